@@ -11,14 +11,17 @@ import (
 	"time"
 
 	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/events"
+	"github.com/dev-parvej/offline_kanban/service"
 )
 
 var frontend embed.FS
 
 type App struct {
-	server *http.Server
-	ctx    context.Context
-	db     *database.Database
+	server        *http.Server
+	ctx           context.Context
+	db            *database.Database
+	stopScheduler context.CancelFunc
 }
 
 func NewApp() *App {
@@ -40,6 +43,20 @@ func (app *App) startup(ctx context.Context) {
 		ip = "localhost"
 	}
 
+	// Generate recurring tasks, including the runs missed while the app was closed
+	schedulerCtx, stopScheduler := context.WithCancel(ctx)
+	app.stopScheduler = stopScheduler
+	service.NewRecurrenceService(app.db).Start(schedulerCtx, time.Minute)
+
+	// Empty the trash of tasks past the retention, checked hourly
+	service.NewTrashService(app.db).Start(schedulerCtx, time.Hour)
+
+	// Archive tasks done for longer than the auto-archive days, checked hourly
+	service.NewArchiveService(app.db).Start(schedulerCtx, time.Hour)
+
+	// Back up the database and uploads on the interval from the settings, checked every 10 minutes
+	service.NewAutoBackupService(app.db).Start(schedulerCtx, 10*time.Minute)
+
 	router := SetUpGorilaMuxServer(app.db)
 
 	port := 8989
@@ -61,8 +78,14 @@ func (app *App) startup(ctx context.Context) {
 }
 
 func (app *App) shutdown(ctx context.Context) {
+	if app.stopScheduler != nil {
+		app.stopScheduler()
+	}
+
 	if app.server != nil {
 		fmt.Println("Shutting down HTTP server...")
+		// Close open event streams, otherwise Shutdown waits on them forever
+		events.Default().Close()
 		_ = app.server.Shutdown(ctx)
 	}
 }
@@ -9,12 +9,16 @@ import (
 	"github.com/dev-parvej/offline_kanban/pkg/dto"
 	"github.com/dev-parvej/offline_kanban/pkg/util"
 	"github.com/dev-parvej/offline_kanban/repository"
+	"github.com/dev-parvej/offline_kanban/service"
 	"github.com/gorilla/mux"
 )
 
 type Activities struct {
 	router             *mux.Router
 	activityRepository *repository.ActivityRepository
+	taskRepository     *repository.TaskRepository
+	userRepository     *repository.UserRepository
+	taskService        *service.TaskService
 	db                 *database.Database
 }
 
@@ -22,6 +26,9 @@ func ActivityController(router *mux.Router, db *database.Database) *Activities {
 	return &Activities{
 		router:             router,
 		activityRepository: repository.NewActivityRepository(db),
+		taskRepository:     repository.NewTaskRepository(db),
+		userRepository:     repository.NewUserRepository(db),
+		taskService:        service.NewTaskService(db),
 		db:                 db,
 	}
 }
@@ -35,6 +42,10 @@ func (activities *Activities) Router() {
 	activityRouter.HandleFunc("", activities.getActivities).Methods("GET")
 	activityRouter.HandleFunc("/task/{task_id:[0-9]+}", activities.getTaskActivities).Methods("GET")
 	activityRouter.HandleFunc("/{id:[0-9]+}", activities.getActivity).Methods("GET")
+
+	// Undo task changes (own changes, any change for root users)
+	activityRouter.HandleFunc("/undo", activities.undoLastChange).Methods("POST")
+	activityRouter.HandleFunc("/{id:[0-9]+}/revert", activities.revertActivity).Methods("POST")
 }
 
 // Get activities with optional filters
@@ -55,8 +66,16 @@ func (activities *Activities) getActivities(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
+	// Optionally limit activities to one board
+	var boardID *int
+	if boardParam := r.URL.Query().Get("board_id"); boardParam != "" {
+		if parsedBoardID, err := strconv.Atoi(boardParam); err == nil && parsedBoardID > 0 {
+			boardID = &parsedBoardID
+		}
+	}
+
 	// Get activities
-	activityList, err := activities.activityRepository.GetRecent(limit, offset)
+	activityList, err := activities.activityRepository.GetRecent(limit, offset, boardID)
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
 		return
@@ -129,6 +148,100 @@ func (activities *Activities) getActivity(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// Revert a single task change
+func (activities *Activities) revertActivity(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid activity ID")
+		return
+	}
+
+	activity, err := activities.activityRepository.FindByID(id)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data("activity not found")
+		return
+	}
+
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	// Users undo their own changes, root users anyone's
+	if activity.UserID != userIdInt {
+		currentUser, err := activities.userRepository.FindByID(userIdInt)
+		if err != nil {
+			util.Res.Writer(w).Status(500).Data("Failed to get user info")
+			return
+		}
+
+		if !currentUser.IsRoot {
+			util.Res.Writer(w).Status(403).Data("You can only revert your own changes")
+			return
+		}
+	}
+
+	if activity.EntityType == "task" {
+		if _, err := activities.taskRepository.FindByID(activity.EntityID); err != nil {
+			util.Res.Writer(w).Status(404).Data(err.Error())
+			return
+		}
+	}
+
+	revert, err := activities.taskService.RevertActivity(id, userIdInt)
+	if err != nil {
+		writeRevertError(w, err)
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(&dto.RevertActivityResponseDto{
+		Reverted: activities.convertToResponseDto(activity),
+		Activity: activities.convertToResponseDto(revert),
+	})
+}
+
+// Undo the latest task change of the current user that is still in place
+func (activities *Activities) undoLastChange(w http.ResponseWriter, r *http.Request) {
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	reverted, revert, err := activities.taskService.UndoLastChange(userIdInt)
+	if err == service.ErrNothingToUndo {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	if err != nil {
+		writeRevertError(w, err)
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(&dto.RevertActivityResponseDto{
+		Reverted: activities.convertToResponseDto(reverted),
+		Activity: activities.convertToResponseDto(revert),
+	})
+}
+
+// writeRevertError writes a 409 when the change conflicts with the task as it is now, and a 400 otherwise
+func writeRevertError(w http.ResponseWriter, err error) {
+	if conflict, ok := err.(*service.RevertConflictError); ok {
+		util.Res.Writer(w).Status(409).Data(conflict)
+		return
+	}
+
+	if writeConflictError(w, err) {
+		return
+	}
+
+	util.Res.Writer(w).Status(400).Data(err.Error())
+}
+
 // Helper method to convert repository Activity to ActivityResponseDto
 func (activities *Activities) convertToResponseDto(activity *repository.Activity) *dto.ActivityResponseDto {
 	userName := ""
@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/repository"
+	"github.com/dev-parvej/offline_kanban/service"
+	"github.com/gorilla/mux"
+)
+
+type Backups struct {
+	router             *mux.Router
+	backupService      *service.BackupService
+	autoBackupService  *service.AutoBackupService
+	settingsRepository *repository.SettingsRepository
+	db                 *database.Database
+}
+
+func BackupController(router *mux.Router, db *database.Database) *Backups {
+	return &Backups{
+		router:             router,
+		backupService:      service.NewBackupService(db),
+		autoBackupService:  service.NewAutoBackupService(db),
+		settingsRepository: repository.NewSettingsRepository(db),
+		db:                 db,
+	}
+}
+
+func (backups *Backups) Router() {
+	// Backup routes (root users only)
+	adminRouter := backups.router.PathPrefix("/admin/backups").Subrouter()
+	adminRouter.Use(middleware.Authenticate)
+	adminRouter.Use(middleware.RequireRoot(backups.db))
+
+	adminRouter.HandleFunc("", backups.listBackups).Methods("GET")
+	adminRouter.HandleFunc("", backups.createBackup).Methods("POST")
+	adminRouter.HandleFunc("/restore", backups.restoreUpload).Methods("POST")
+	adminRouter.HandleFunc("/schedule", backups.getSchedule).Methods("GET")
+	adminRouter.HandleFunc("/schedule", backups.updateSchedule).Methods("PUT")
+	adminRouter.HandleFunc("/schedule/run", backups.runScheduledBackup).Methods("POST")
+	adminRouter.HandleFunc("/{name}", backups.downloadBackup).Methods("GET")
+	adminRouter.HandleFunc("/{name}/restore", backups.restoreBackup).Methods("POST")
+}
+
+func (backups *Backups) listBackups(w http.ResponseWriter, r *http.Request) {
+	list, err := backups.backupService.List()
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(list)
+}
+
+// Take a hot backup of the database bundled with the uploads
+func (backups *Backups) createBackup(w http.ResponseWriter, r *http.Request) {
+	backup, err := backups.backupService.Create("backup")
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]interface{}{
+		"message": "Backup created successfully",
+		"backup":  backup,
+	})
+}
+
+// Scheduled backup settings, recent runs with their errors and the backups kept by the rotation
+func (backups *Backups) getSchedule(w http.ResponseWriter, r *http.Request) {
+	status, err := backups.autoBackupService.Status()
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(status)
+}
+
+func (backups *Backups) updateSchedule(w http.ResponseWriter, r *http.Request) {
+	updateDto, errors := util.ValidateRequest(r, dto.UpdateBackupSettingsDto{})
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	if updateDto.Directory != nil && *updateDto.Directory != "" {
+		if err := service.CheckDirectory(*updateDto.Directory); err != nil {
+			util.Res.Writer(w).Status(400).Data("Backup directory is not writable: " + err.Error())
+			return
+		}
+	}
+
+	_, err := backups.settingsRepository.UpdateBackupSettings(
+		updateDto.Enabled,
+		updateDto.IntervalHours,
+		updateDto.Directory,
+		updateDto.KeepDaily,
+		updateDto.KeepWeekly,
+	)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	status, err := backups.autoBackupService.Status()
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(status)
+}
+
+// Run the scheduled backup now, with verification and rotation, the run is part of the history
+func (backups *Backups) runScheduledBackup(w http.ResponseWriter, r *http.Request) {
+	run, err := backups.autoBackupService.Run()
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	if run.Status == repository.BackupRunFailed {
+		util.Res.Writer(w).Status(500).Data(run)
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(run)
+}
+
+func (backups *Backups) downloadBackup(w http.ResponseWriter, r *http.Request) {
+	file, backup, err := backups.backupService.Open(mux.Vars(r)["name"])
+	if err != nil {
+		if errors.Is(err, service.ErrBackupNotFound) {
+			util.Res.Writer(w).Status(404).Data(err.Error())
+			return
+		}
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+backup.Name+"\"")
+	http.ServeContent(w, r, backup.Name, backup.CreatedAt, file)
+}
+
+// Restore from an uploaded archive, sent as the raw body or as the "file" field of a form
+func (backups *Backups) restoreUpload(w http.ResponseWriter, r *http.Request) {
+	// Archives hold the whole database and every uploaded file
+	const maxBackupSize = 2 << 30 // 2GB
+	r.Body = http.MaxBytesReader(w, r.Body, maxBackupSize)
+
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			util.Res.Writer(w).Status(400).Data("File too large or invalid form data")
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			util.Res.Writer(w).Status(400).Data("No backup file provided")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	result, err := backups.backupService.Restore(body)
+	backups.respondRestore(w, result, err)
+}
+
+// Restore one of the backups in the backup directory
+func (backups *Backups) restoreBackup(w http.ResponseWriter, r *http.Request) {
+	result, err := backups.backupService.RestoreBackup(mux.Vars(r)["name"])
+	backups.respondRestore(w, result, err)
+}
+
+func (backups *Backups) respondRestore(w http.ResponseWriter, result *service.RestoreResult, err error) {
+	if err != nil {
+		var backupErr *service.BackupError
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &backupErr):
+			util.Res.Writer(w).Status(400).Data(backupErr.Message)
+		case errors.As(err, &maxBytesErr):
+			util.Res.Writer(w).Status(413).Data("Backup archive is larger than " + strconv.FormatInt(maxBytesErr.Limit>>20, 10) + "MB")
+		case errors.Is(err, service.ErrBackupNotFound):
+			util.Res.Writer(w).Status(404).Data(err.Error())
+		default:
+			util.Res.Writer(w).Status(500).Data(err.Error())
+		}
+		return
+	}
+
+	util.Res.Writer(w).Status(200).Data(map[string]interface{}{
+		"message": "Backup restored successfully",
+		"result":  result,
+	})
+}
@@ -0,0 +1,247 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/repository"
+	"github.com/gorilla/mux"
+)
+
+type Boards struct {
+	router           *mux.Router
+	boardRepository  *repository.BoardRepository
+	columnRepository *repository.ColumnRepository
+	userRepository   *repository.UserRepository
+	db               *database.Database
+}
+
+func BoardController(router *mux.Router, db *database.Database) *Boards {
+	return &Boards{
+		router:           router,
+		boardRepository:  repository.NewBoardRepository(db),
+		columnRepository: repository.NewColumnRepository(db),
+		userRepository:   repository.NewUserRepository(db),
+		db:               db,
+	}
+}
+
+func (boards *Boards) Router() {
+	// All board routes require authentication
+	boardRouter := boards.router.PathPrefix("/features/boards").Subrouter()
+	boardRouter.Use(middleware.Authenticate)
+
+	// Board read operations (all authenticated users)
+	boardRouter.HandleFunc("", boards.getAllBoards).Methods("GET")
+	boardRouter.HandleFunc("/{id:[0-9]+}", boards.getBoard).Methods("GET")
+	boardRouter.HandleFunc("/{id:[0-9]+}/columns", boards.getBoardColumns).Methods("GET")
+
+	// Board write operations (root users only, checked per handler)
+	boardRouter.HandleFunc("", boards.createBoard).Methods("POST")
+	boardRouter.HandleFunc("/{id:[0-9]+}", boards.updateBoard).Methods("PUT")
+	boardRouter.HandleFunc("/{id:[0-9]+}", boards.deleteBoard).Methods("DELETE")
+}
+
+func (boards *Boards) getAllBoards(w http.ResponseWriter, r *http.Request) {
+	allBoards, err := boards.boardRepository.GetAll()
+
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string][]*repository.Board{
+		"boards": allBoards,
+	})
+}
+
+func (boards *Boards) getBoard(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid board ID")
+		return
+	}
+
+	board, err := boards.boardRepository.FindByID(id)
+
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*repository.Board{
+		"board": board,
+	})
+}
+
+func (boards *Boards) getBoardColumns(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid board ID")
+		return
+	}
+
+	if _, err := boards.boardRepository.FindByID(id); err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	cols, err := boards.columnRepository.GetAllWithTaskCounts(id, false)
+
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string][]*repository.Column{
+		"columns": cols,
+	})
+}
+
+func (boards *Boards) createBoard(w http.ResponseWriter, r *http.Request) {
+	userIdInt, ok := boards.requireRoot(w, r)
+	if !ok {
+		return
+	}
+
+	createBoardDto, errors := util.ValidateRequest(r, dto.CreateBoardDto{})
+
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	titleExists, err := boards.boardRepository.TitleExists(createBoardDto.Title, nil)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	if titleExists {
+		util.Res.Writer(w).Status(400).Data("Board with this title already exists")
+		return
+	}
+
+	board, err := boards.boardRepository.Create(createBoardDto.Title, createBoardDto.Description, userIdInt)
+
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*repository.Board{
+		"board": board,
+	})
+}
+
+func (boards *Boards) updateBoard(w http.ResponseWriter, r *http.Request) {
+	if _, ok := boards.requireRoot(w, r); !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid board ID")
+		return
+	}
+
+	updateBoardDto, errors := util.ValidateRequest(r, dto.UpdateBoardDto{})
+
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	if updateBoardDto.Title != nil {
+		titleExists, err := boards.boardRepository.TitleExists(*updateBoardDto.Title, &id)
+		if err != nil {
+			util.Res.Writer(w).Status(500).Data(err.Error())
+			return
+		}
+
+		if titleExists {
+			util.Res.Writer(w).Status(400).Data("Board with this title already exists")
+			return
+		}
+	}
+
+	board, err := boards.boardRepository.Update(id, updateBoardDto.Title, updateBoardDto.Description)
+
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*repository.Board{
+		"board": board,
+	})
+}
+
+func (boards *Boards) deleteBoard(w http.ResponseWriter, r *http.Request) {
+	if _, ok := boards.requireRoot(w, r); !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid board ID")
+		return
+	}
+
+	// Check if this is the last board
+	count, err := boards.boardRepository.Count()
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	if count <= 1 {
+		util.Res.Writer(w).Status(400).Data("Cannot delete the last board")
+		return
+	}
+
+	err = boards.boardRepository.Delete(id)
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]string{
+		"message": "Board deleted successfully",
+	})
+}
+
+// requireRoot writes a 403 unless the current user is root, and returns the user ID
+func (boards *Boards) requireRoot(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return 0, false
+	}
+
+	currentUser, err := boards.userRepository.FindByID(userIdInt)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data("Failed to get user info")
+		return 0, false
+	}
+
+	if !currentUser.IsRoot {
+		util.Res.Writer(w).Status(403).Data("Only root users can manage boards")
+		return 0, false
+	}
+
+	return userIdInt, true
+}
@@ -10,12 +10,15 @@ import (
 	"github.com/dev-parvej/offline_kanban/pkg/dto"
 	"github.com/dev-parvej/offline_kanban/pkg/util"
 	"github.com/dev-parvej/offline_kanban/repository"
+	"github.com/dev-parvej/offline_kanban/service"
 	"github.com/gorilla/mux"
 )
 
 type Columns struct {
 	router           *mux.Router
 	columnRepository *repository.ColumnRepository
+	boardRepository  *repository.BoardRepository
+	taskService      *service.TaskService
 	db               *database.Database
 }
 
@@ -23,6 +26,8 @@ func ColumnsController(router *mux.Router, db *database.Database) *Columns {
 	return &Columns{
 		router:           router,
 		columnRepository: repository.NewColumnRepository(db),
+		boardRepository:  repository.NewBoardRepository(db),
+		taskService:      service.NewTaskService(db),
 		db:               db,
 	}
 }
@@ -49,7 +54,13 @@ func (columns *Columns) Router() {
 }
 
 func (columns *Columns) getAllColumns(w http.ResponseWriter, r *http.Request) {
-	cols, err := columns.columnRepository.GetAll()
+	boardID, err := columns.resolveBoardID(columns.boardIDFromQuery(r))
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	cols, err := columns.columnRepository.GetAll(boardID)
 
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
@@ -103,8 +114,14 @@ func (columns *Columns) createColumn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if column title already exists
-	titleExists, err := columns.columnRepository.TitleExists(createColumnDto.Title, nil)
+	boardID, err := columns.resolveBoardID(createColumnDto.BoardID)
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid board ID")
+		return
+	}
+
+	// Check if column title already exists on the board
+	titleExists, err := columns.columnRepository.TitleExists(boardID, createColumnDto.Title, nil)
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
 		return
@@ -115,13 +132,21 @@ func (columns *Columns) createColumn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	column, err := columns.columnRepository.Create(createColumnDto.Title, userIdInt, createColumnDto.Colors)
+	column, err := columns.columnRepository.Create(createColumnDto.Title, boardID, userIdInt, createColumnDto.Colors)
 
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
 		return
 	}
 
+	if createColumnDto.IsDone {
+		column, err = columns.columnRepository.SetDone(column.ID, true)
+		if err != nil {
+			util.Res.Writer(w).Status(500).Data(err.Error())
+			return
+		}
+	}
+
 	util.Res.Writer(w).Status().Data(map[string]*repository.Column{
 		"column": column,
 	})
@@ -143,9 +168,15 @@ func (columns *Columns) updateColumn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if new title already exists (if title is being updated)
+	existingColumn, err := columns.columnRepository.FindByID(id)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	// Check if new title already exists on the board (if title is being updated)
 	if updateColumnDto.Title != nil {
-		titleExists, err := columns.columnRepository.TitleExists(*updateColumnDto.Title, &id)
+		titleExists, err := columns.columnRepository.TitleExists(existingColumn.BoardID, *updateColumnDto.Title, &id)
 		if err != nil {
 			util.Res.Writer(w).Status(500).Data(err.Error())
 			return
@@ -157,11 +188,50 @@ func (columns *Columns) updateColumn(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	column, err := columns.columnRepository.Update(id, updateColumnDto.Title, updateColumnDto.Colors)
+	column := existingColumn
+	if updateColumnDto.Title != nil || updateColumnDto.Colors != nil {
+		column, err = columns.columnRepository.Update(id, updateColumnDto.Title, updateColumnDto.Colors)
 
-	if err != nil {
-		util.Res.Writer(w).Status(404).Data(err.Error())
-		return
+		if err != nil {
+			util.Res.Writer(w).Status(404).Data(err.Error())
+			return
+		}
+	}
+
+	// A WIP limit of 0 removes the limit
+	if updateColumnDto.WipLimit != nil {
+		var wipLimit *int
+		if *updateColumnDto.WipLimit > 0 {
+			wipLimit = updateColumnDto.WipLimit
+		}
+
+		column, err = columns.columnRepository.SetWipLimit(id, wipLimit)
+		if err != nil {
+			util.Res.Writer(w).Status(404).Data(err.Error())
+			return
+		}
+	}
+
+	if updateColumnDto.IsDone != nil {
+		column, err = columns.columnRepository.SetDone(id, *updateColumnDto.IsDone)
+		if err != nil {
+			util.Res.Writer(w).Status(404).Data(err.Error())
+			return
+		}
+	}
+
+	// Auto-archive days of 0 follow the app setting again
+	if updateColumnDto.AutoArchiveDays != nil {
+		var autoArchiveDays *int
+		if *updateColumnDto.AutoArchiveDays > 0 {
+			autoArchiveDays = updateColumnDto.AutoArchiveDays
+		}
+
+		column, err = columns.columnRepository.SetAutoArchiveDays(id, autoArchiveDays)
+		if err != nil {
+			util.Res.Writer(w).Status(404).Data(err.Error())
+			return
+		}
 	}
 
 	util.Res.Writer(w).Status().Data(map[string]*repository.Column{
@@ -178,8 +248,15 @@ func (columns *Columns) deleteColumn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if this is the last column
-	allColumns, err := columns.columnRepository.GetAll()
+	column, _ := columns.columnRepository.FindByID(id)
+
+	if column == nil {
+		util.Res.Writer(w).Status(400).Data("Column not found")
+		return
+	}
+
+	// Check if this is the last column of the board
+	allColumns, err := columns.columnRepository.GetAll(column.BoardID)
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
 		return
@@ -190,13 +267,6 @@ func (columns *Columns) deleteColumn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	column, _ := columns.columnRepository.FindByID(id)
-
-	if column == nil {
-		util.Res.Writer(w).Status(400).Data("Column not found")
-		return
-	}
-
 	if column.DeletedAt.Valid {
 		err = columns.columnRepository.UnArchive(id)
 	} else {
@@ -218,7 +288,13 @@ func (columns *Columns) getColumnsWithTaskCounts(w http.ResponseWriter, r *http.
 	archivedParam := r.URL.Query().Get("archived")
 	showArchived := archivedParam == "true"
 
-	cols, err := columns.columnRepository.GetAllWithTaskCounts(showArchived)
+	boardID, err := columns.resolveBoardID(columns.boardIDFromQuery(r))
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	cols, err := columns.columnRepository.GetAllWithTaskCounts(boardID, showArchived)
 
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
@@ -231,7 +307,13 @@ func (columns *Columns) getColumnsWithTaskCounts(w http.ResponseWriter, r *http.
 }
 
 func (columns *Columns) getColumnsWithCreators(w http.ResponseWriter, r *http.Request) {
-	cols, err := columns.columnRepository.GetAllWithCreators()
+	boardID, err := columns.resolveBoardID(columns.boardIDFromQuery(r))
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	cols, err := columns.columnRepository.GetAllWithCreators(boardID)
 
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
@@ -256,6 +338,12 @@ func (columns *Columns) reorderColumns(w http.ResponseWriter, r *http.Request) {
 		return column.ID
 	})
 
+	boardID, err := columns.resolveBoardID(reorderDto.BoardID)
+	if err != nil {
+		util.Res.Writer(w).Status422().Data("Invalid board ID")
+		return
+	}
+
 	columnEntities, _ := columns.columnRepository.FindByIDs(columnsIds)
 
 	if len(columnEntities) != len(reorderDto.Orders) {
@@ -263,7 +351,14 @@ func (columns *Columns) reorderColumns(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := columns.columnRepository.Reorder(reorderDto.Orders)
+	for _, column := range columnEntities {
+		if column.BoardID != boardID {
+			util.Res.Writer(w).Status422().Data("One or more columns do not belong to this board")
+			return
+		}
+	}
+
+	err = columns.columnRepository.Reorder(boardID, reorderDto.Orders)
 
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
@@ -286,7 +381,8 @@ func (columns *Columns) moveAllTasksFromColumn(w http.ResponseWriter, r *http.Re
 
 	// Get destination column ID from request body
 	type MoveTasksRequest struct {
-		ToColumnID int `json:"to_column_id" validate:"required,gt=0"`
+		ToColumnID       int  `json:"to_column_id" validate:"required,gt=0"`
+		OverrideWipLimit bool `json:"override_wip_limit"`
 	}
 
 	requestBody, errors := util.ValidateRequest(r, MoveTasksRequest{})
@@ -297,23 +393,58 @@ func (columns *Columns) moveAllTasksFromColumn(w http.ResponseWriter, r *http.Re
 	}
 
 	// Validate both columns exist
-	_, err = columns.columnRepository.FindByID(fromColumnID)
+	fromColumn, err := columns.columnRepository.FindByID(fromColumnID)
 	if err != nil {
 		util.Res.Writer(w).Status(404).Data("Source column not found")
 		return
 	}
 
-	_, err = columns.columnRepository.FindByID(requestBody.ToColumnID)
+	toColumn, err := columns.columnRepository.FindByID(requestBody.ToColumnID)
 	if err != nil {
 		util.Res.Writer(w).Status(404).Data("Destination column not found")
 		return
 	}
 
+	if fromColumn.BoardID != toColumn.BoardID {
+		util.Res.Writer(w).Status(400).Data("Tasks can only be moved between columns of the same board")
+		return
+	}
+
 	if fromColumnID == requestBody.ToColumnID {
 		util.Res.Writer(w).Status(400).Data("Source and destination columns cannot be the same")
 		return
 	}
 
+	// The destination must have room for every task of the source column
+	if !requestBody.OverrideWipLimit {
+		taskCount, err := columns.columnRepository.GetTaskCount(fromColumnID)
+		if err != nil {
+			util.Res.Writer(w).Status(500).Data(err.Error())
+			return
+		}
+
+		err = columns.columnRepository.CheckWipLimit(requestBody.ToColumnID, taskCount)
+		if writeConflictError(w, err) {
+			return
+		}
+
+		if err != nil {
+			util.Res.Writer(w).Status(500).Data(err.Error())
+			return
+		}
+	}
+
+	// A done column only takes tasks whose blockers are finished or moved along with them
+	err = columns.taskService.CheckColumnMoveBlockers(fromColumnID, toColumn)
+	if writeConflictError(w, err) {
+		return
+	}
+
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
 	err = columns.columnRepository.MoveAllTasks(fromColumnID, requestBody.ToColumnID)
 
 	if err != nil {
@@ -325,3 +456,28 @@ func (columns *Columns) moveAllTasksFromColumn(w http.ResponseWriter, r *http.Re
 		"message": "All tasks moved successfully",
 	})
 }
+
+// Helper to read the optional board_id query parameter
+func (columns *Columns) boardIDFromQuery(r *http.Request) *int {
+	if boardID, err := strconv.Atoi(r.URL.Query().Get("board_id")); err == nil && boardID > 0 {
+		return &boardID
+	}
+	return nil
+}
+
+// Helper to validate the requested board, falling back to the default board
+func (columns *Columns) resolveBoardID(boardID *int) (int, error) {
+	if boardID == nil {
+		board, err := columns.boardRepository.GetDefault()
+		if err != nil {
+			return 0, err
+		}
+		return board.ID, nil
+	}
+
+	board, err := columns.boardRepository.FindByID(*boardID)
+	if err != nil {
+		return 0, err
+	}
+	return board.ID, nil
+}
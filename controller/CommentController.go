@@ -35,6 +35,7 @@ func (comments *Comments) Router() {
 	// Comment routes (authenticated users only)
 	commentRouter := comments.router.PathPrefix("/comments").Subrouter()
 	commentRouter.Use(middleware.Authenticate)
+	commentRouter.Use(middleware.Idempotency(comments.db))
 
 	// Comment CRUD operations
 	commentRouter.HandleFunc("", comments.createComment).Methods("POST")
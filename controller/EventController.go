@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/events"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/gorilla/mux"
+)
+
+type Events struct {
+	router *mux.Router
+	broker *events.Broker
+	db     *database.Database
+}
+
+func EventController(router *mux.Router, db *database.Database) *Events {
+	return &Events{
+		router: router,
+		broker: events.Default(),
+		db:     db,
+	}
+}
+
+func (ev *Events) Router() {
+	// Event stream (authenticated users only, token may be passed as a query parameter)
+	eventRouter := ev.router.PathPrefix("/events").Subrouter()
+	eventRouter.Use(middleware.TokenFromQuery)
+	eventRouter.Use(middleware.Authenticate)
+
+	eventRouter.HandleFunc("", ev.stream).Methods("GET")
+}
+
+// Stream board changes as Server-Sent Events, optionally limited with ?board_id=
+func (ev *Events) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		util.Res.Writer(w).Status(500).Data("Streaming not supported")
+		return
+	}
+
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	var boardID *int
+	if parsedBoardID, err := strconv.Atoi(r.URL.Query().Get("board_id")); err == nil && parsedBoardID > 0 {
+		boardID = &parsedBoardID
+	}
+
+	subscriber := ev.broker.Subscribe(userIdInt, boardID)
+	defer ev.broker.Unsubscribe(subscriber)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	// Keep idle connections from being dropped by proxies
+	keepAlive := time.NewTicker(25 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case event, open := <-subscriber.Events():
+			if !open {
+				return
+			}
+
+			payload, err := json.Marshal(event)
+			if err != nil {
+				fmt.Printf("Failed to encode %s event: %v\n", event.Type, err)
+				continue
+			}
+
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, payload)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/repository"
+	"github.com/gorilla/mux"
+)
+
+const defaultLabelColor = "#6b7280"
+
+type Labels struct {
+	router          *mux.Router
+	labelRepository *repository.LabelRepository
+	userRepository  *repository.UserRepository
+	db              *database.Database
+}
+
+func LabelController(router *mux.Router, db *database.Database) *Labels {
+	return &Labels{
+		router:          router,
+		labelRepository: repository.NewLabelRepository(db),
+		userRepository:  repository.NewUserRepository(db),
+		db:              db,
+	}
+}
+
+func (labels *Labels) Router() {
+	// All label routes require authentication
+	labelRouter := labels.router.PathPrefix("/settings/labels").Subrouter()
+	labelRouter.Use(middleware.Authenticate)
+
+	// Label read operations (all authenticated users, needed to tag tasks)
+	labelRouter.HandleFunc("", labels.getAllLabels).Methods("GET")
+	labelRouter.HandleFunc("/{id:[0-9]+}", labels.getLabel).Methods("GET")
+
+	// Label write operations (root users only, checked per handler)
+	labelRouter.HandleFunc("", labels.createLabel).Methods("POST")
+	labelRouter.HandleFunc("/{id:[0-9]+}", labels.updateLabel).Methods("PUT")
+	labelRouter.HandleFunc("/{id:[0-9]+}", labels.deleteLabel).Methods("DELETE")
+}
+
+func (labels *Labels) getAllLabels(w http.ResponseWriter, r *http.Request) {
+	allLabels, err := labels.labelRepository.GetAll()
+
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string][]*repository.Label{
+		"labels": allLabels,
+	})
+}
+
+func (labels *Labels) getLabel(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid label ID")
+		return
+	}
+
+	label, err := labels.labelRepository.FindByID(id)
+
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*repository.Label{
+		"label": label,
+	})
+}
+
+func (labels *Labels) createLabel(w http.ResponseWriter, r *http.Request) {
+	userIdInt, ok := labels.requireRoot(w, r)
+	if !ok {
+		return
+	}
+
+	createLabelDto, errors := util.ValidateRequest(r, dto.CreateLabelDto{})
+
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	name := strings.TrimSpace(createLabelDto.Name)
+	if name == "" {
+		util.Res.Writer(w).Status422().Data("Label name is required")
+		return
+	}
+
+	nameExists, err := labels.labelRepository.NameExists(name, nil)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	if nameExists {
+		util.Res.Writer(w).Status(400).Data("Label with this name already exists")
+		return
+	}
+
+	color := defaultLabelColor
+	if createLabelDto.Color != nil && *createLabelDto.Color != "" {
+		color = *createLabelDto.Color
+	}
+
+	label, err := labels.labelRepository.Create(name, color, userIdInt)
+
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*repository.Label{
+		"label": label,
+	})
+}
+
+func (labels *Labels) updateLabel(w http.ResponseWriter, r *http.Request) {
+	if _, ok := labels.requireRoot(w, r); !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid label ID")
+		return
+	}
+
+	updateLabelDto, errors := util.ValidateRequest(r, dto.UpdateLabelDto{})
+
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	if updateLabelDto.Name != nil {
+		name := strings.TrimSpace(*updateLabelDto.Name)
+		if name == "" {
+			util.Res.Writer(w).Status422().Data("Label name is required")
+			return
+		}
+		updateLabelDto.Name = &name
+
+		nameExists, err := labels.labelRepository.NameExists(name, &id)
+		if err != nil {
+			util.Res.Writer(w).Status(500).Data(err.Error())
+			return
+		}
+
+		if nameExists {
+			util.Res.Writer(w).Status(400).Data("Label with this name already exists")
+			return
+		}
+	}
+
+	label, err := labels.labelRepository.Update(id, updateLabelDto.Name, updateLabelDto.Color)
+
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*repository.Label{
+		"label": label,
+	})
+}
+
+func (labels *Labels) deleteLabel(w http.ResponseWriter, r *http.Request) {
+	if _, ok := labels.requireRoot(w, r); !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid label ID")
+		return
+	}
+
+	err = labels.labelRepository.Delete(id)
+
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]string{
+		"message": "Label deleted successfully",
+	})
+}
+
+// requireRoot writes a 403 unless the current user is root, and returns the user ID
+func (labels *Labels) requireRoot(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return 0, false
+	}
+
+	currentUser, err := labels.userRepository.FindByID(userIdInt)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data("Failed to get user info")
+		return 0, false
+	}
+
+	if !currentUser.IsRoot {
+		util.Res.Writer(w).Status(403).Data("Only root users can manage labels")
+		return 0, false
+	}
+
+	return userIdInt, true
+}
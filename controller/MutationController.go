@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/repository"
+	"github.com/gorilla/mux"
+)
+
+// Endpoints a queued mutation can call, all of them handle Idempotency-Key
+var mutationPrefixes = []string{"/features/tasks", "/comments", "/admin/tasks"}
+
+// {{<idempotency key>:<field>}}, the key runs up to the last colon
+var mutationReference = regexp.MustCompile(`\{\{([^{}]+):([A-Za-z0-9_.]+)\}\}`)
+
+type Mutations struct {
+	router                *mux.Router
+	idempotencyRepository *repository.IdempotencyRepository
+	db                    *database.Database
+}
+
+func MutationController(router *mux.Router, db *database.Database) *Mutations {
+	return &Mutations{
+		router:                router,
+		idempotencyRepository: repository.NewIdempotencyRepository(db),
+		db:                    db,
+	}
+}
+
+// MutationResult is the outcome of one queued mutation
+type MutationResult struct {
+	IdempotencyKey string          `json:"idempotency_key"`
+	Status         int             `json:"status"`
+	Replayed       bool            `json:"replayed"`          // Already applied by an earlier attempt
+	Skipped        bool            `json:"skipped,omitempty"` // Not tried because an earlier mutation failed
+	Body           json.RawMessage `json:"body,omitempty"`
+}
+
+func (mutations *Mutations) Router() {
+	// Queued client mutations (authenticated users only)
+	mutationRouter := mutations.router.PathPrefix("/sync/mutations").Subrouter()
+	mutationRouter.Use(middleware.Authenticate)
+
+	mutationRouter.HandleFunc("", mutations.applyMutations).Methods("POST")
+}
+
+// Apply mutations queued by a client while it was offline, in order, each one as the request it
+// describes sent with its idempotency key, so sending the queue again never applies one twice
+func (mutations *Mutations) applyMutations(w http.ResponseWriter, r *http.Request) {
+	mutationsDto, errors := util.ValidateRequest(r, dto.SyncMutationsDto{})
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	results := make([]*MutationResult, len(mutationsDto.Mutations))
+	applied, failed, skipped := 0, 0, 0
+
+	for i, mutation := range mutationsDto.Mutations {
+		if failed > 0 && !mutationsDto.ContinueOnError {
+			results[i] = &MutationResult{IdempotencyKey: mutation.IdempotencyKey, Skipped: true}
+			skipped++
+			continue
+		}
+
+		results[i] = mutations.apply(r, userIdInt, mutation)
+		if results[i].Status >= 400 {
+			failed++
+		} else {
+			applied++
+		}
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]interface{}{
+		"results": results,
+		"applied": applied,
+		"failed":  failed,
+		"skipped": skipped,
+	})
+}
+
+func (mutations *Mutations) apply(r *http.Request, userId int, mutation dto.SyncMutationDto) *MutationResult {
+	result := &MutationResult{IdempotencyKey: mutation.IdempotencyKey}
+	fail := func(status int, message string) *MutationResult {
+		result.Status = status
+		result.Body, _ = json.Marshal(message)
+		return result
+	}
+
+	target, err := mutations.resolveReferences(userId, mutation.Path, true)
+	if err != nil {
+		return fail(422, err.Error())
+	}
+
+	targetURL, err := url.Parse(target)
+	if err != nil || targetURL.IsAbs() || path.Clean(targetURL.Path) != targetURL.Path || !mutationAllowed(targetURL.Path) {
+		return fail(400, "Mutations can only change tasks, comments and checklists")
+	}
+
+	body := []byte(mutation.Body)
+	if len(body) > 0 {
+		resolved, err := mutations.resolveBody(userId, body)
+		if err != nil {
+			return fail(422, err.Error())
+		}
+		body = resolved
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), mutation.Method, targetURL.RequestURI(), bytes.NewReader(body))
+	if err != nil {
+		return fail(400, err.Error())
+	}
+	req.Header.Set("Authorization", r.Header.Get("Authorization"))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.IdempotencyKeyHeader, mutation.IdempotencyKey)
+
+	recorder := httptest.NewRecorder()
+	mutations.router.ServeHTTP(recorder, req)
+
+	result.Status = recorder.Code
+	result.Replayed = recorder.Header().Get(middleware.IdempotentReplayedHeader) == "true"
+
+	response := bytes.TrimSpace(recorder.Body.Bytes())
+	if json.Valid(response) {
+		result.Body = response
+	} else if len(response) > 0 {
+		result.Body, _ = json.Marshal(string(response))
+	}
+	return result
+}
+
+func mutationAllowed(target string) bool {
+	for _, prefix := range mutationPrefixes {
+		if target == prefix || strings.HasPrefix(target, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveBody replaces references in the strings of a JSON body, a string that is only a
+// reference takes the referenced value as is, so IDs stay numbers
+func (mutations *Mutations) resolveBody(userId int, body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("Invalid mutation body: %w", err)
+	}
+
+	resolved, err := mutations.resolveValue(userId, value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resolved)
+}
+
+func (mutations *Mutations) resolveValue(userId int, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if match := mutationReference.FindStringSubmatch(v); match != nil && match[0] == v {
+			return mutations.lookupReference(userId, match[1], match[2])
+		}
+		return mutations.resolveReferences(userId, v, false)
+	case []interface{}:
+		for i := range v {
+			resolved, err := mutations.resolveValue(userId, v[i])
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+	case map[string]interface{}:
+		for key := range v {
+			resolved, err := mutations.resolveValue(userId, v[key])
+			if err != nil {
+				return nil, err
+			}
+			v[key] = resolved
+		}
+	}
+	return value, nil
+}
+
+// resolveReferences replaces the references within a string by the text of their values, escaped
+// when the string is a path
+func (mutations *Mutations) resolveReferences(userId int, text string, inPath bool) (string, error) {
+	var lookupErr error
+	resolved := mutationReference.ReplaceAllStringFunc(text, func(reference string) string {
+		match := mutationReference.FindStringSubmatch(reference)
+		value, err := mutations.lookupReference(userId, match[1], match[2])
+		if err != nil {
+			lookupErr = err
+			return reference
+		}
+		if inPath {
+			return url.PathEscape(fmt.Sprint(value))
+		}
+		return fmt.Sprint(value)
+	})
+	return resolved, lookupErr
+}
+
+// lookupReference reads a field of the stored response to the user's request with the given key
+func (mutations *Mutations) lookupReference(userId int, key, field string) (interface{}, error) {
+	record, err := mutations.idempotencyRepository.FindByKey(userId, key)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || record.StatusCode == nil || *record.StatusCode >= 400 {
+		return nil, fmt.Errorf("No successful mutation with idempotency key %q to take %s from", key, field)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(record.ResponseBody))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("The response to %q is not JSON", key)
+	}
+
+	for _, name := range strings.Split(field, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("The response to %q has no %s", key, field)
+		}
+		if value, ok = object[name]; !ok {
+			return nil, fmt.Errorf("The response to %q has no %s", key, field)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/repository"
+	"github.com/gorilla/mux"
+)
+
+type Notifications struct {
+	router                 *mux.Router
+	notificationRepository *repository.NotificationRepository
+	db                     *database.Database
+}
+
+func NotificationController(router *mux.Router, db *database.Database) *Notifications {
+	return &Notifications{
+		router:                 router,
+		notificationRepository: repository.NewNotificationRepository(db),
+		db:                     db,
+	}
+}
+
+func (notifications *Notifications) Router() {
+	// Notification routes (authenticated users only, always scoped to the current user)
+	notificationRouter := notifications.router.PathPrefix("/notifications").Subrouter()
+	notificationRouter.Use(middleware.Authenticate)
+
+	notificationRouter.HandleFunc("", notifications.getNotifications).Methods("GET")
+	notificationRouter.HandleFunc("/unread-count", notifications.getUnreadCount).Methods("GET")
+	notificationRouter.HandleFunc("/read-all", notifications.markAllRead).Methods("POST")
+	notificationRouter.HandleFunc("/{id:[0-9]+}/read", notifications.markRead).Methods("POST")
+}
+
+// Get notifications of the current user
+func (notifications *Notifications) getNotifications(w http.ResponseWriter, r *http.Request) {
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	// Parse query parameters
+	limit := 50 // default limit
+	offset := 0 // default offset
+
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := r.URL.Query().Get("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	unreadOnly := r.URL.Query().Get("unread_only") == "true"
+
+	notificationList, err := notifications.notificationRepository.GetByRecipient(userIdInt, limit, offset, unreadOnly)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	total, err := notifications.notificationRepository.CountByRecipient(userIdInt, unreadOnly)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	unreadCount, err := notifications.notificationRepository.CountByRecipient(userIdInt, true)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	// Convert to response DTOs
+	responseDtos := []*dto.NotificationResponseDto{}
+	for _, notification := range notificationList {
+		responseDtos = append(responseDtos, notifications.convertToResponseDto(notification))
+	}
+
+	util.Res.Writer(w).Status().Data(&dto.NotificationListResponseDto{
+		Notifications: responseDtos,
+		Total:         total,
+		UnreadCount:   unreadCount,
+	})
+}
+
+// Get the number of unread notifications of the current user
+func (notifications *Notifications) getUnreadCount(w http.ResponseWriter, r *http.Request) {
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	unreadCount, err := notifications.notificationRepository.CountByRecipient(userIdInt, true)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]int{
+		"unread_count": unreadCount,
+	})
+}
+
+// Mark a single notification as read
+func (notifications *Notifications) markRead(w http.ResponseWriter, r *http.Request) {
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid notification ID")
+		return
+	}
+
+	notification, err := notifications.notificationRepository.MarkRead(id, userIdInt)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*dto.NotificationResponseDto{
+		"notification": notifications.convertToResponseDto(notification),
+	})
+}
+
+// Mark every notification of the current user as read
+func (notifications *Notifications) markAllRead(w http.ResponseWriter, r *http.Request) {
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	updated, err := notifications.notificationRepository.MarkAllRead(userIdInt)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]int{
+		"updated": updated,
+	})
+}
+
+// Helper method to convert repository Notification to NotificationResponseDto
+func (notifications *Notifications) convertToResponseDto(notification *repository.Notification) *dto.NotificationResponseDto {
+	var readAt *string
+	if notification.ReadAt != nil {
+		formatted := notification.ReadAt.Format("2006-01-02T15:04:05Z07:00")
+		readAt = &formatted
+	}
+
+	return &dto.NotificationResponseDto{
+		ID:             notification.ID,
+		Type:           notification.Type,
+		Title:          notification.Title,
+		Message:        notification.Message,
+		SenderID:       notification.SenderID,
+		SenderName:     notification.SenderName,
+		SenderUsername: notification.SenderUsername,
+		TaskID:         notification.TaskID,
+		CommentID:      notification.CommentID,
+		Data:           notification.Data,
+		IsRead:         notification.IsRead,
+		IsSystem:       notification.IsSystem,
+		ReadAt:         readAt,
+		CreatedAt:      notification.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
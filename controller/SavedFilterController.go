@@ -0,0 +1,266 @@
+package controller
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/repository"
+	"github.com/gorilla/mux"
+)
+
+// Task list query parameters a saved filter may hold, page is left to the request
+var savedFilterParameters = map[string]bool{
+	"search": true, "q": true, "board_id": true, "column_id": true, "assigned_to": true, "created_by": true,
+	"priority": true, "label_id": true, "labels": true, "label_mode": true, "parent_id": true,
+	"top_level_only": true, "include_archived": true, "mentioned_me": true, "due_date_from": true, "due_date_to": true,
+	"created_from": true, "created_to": true, "page_size": true, "order_by": true, "order_dir": true,
+}
+
+type SavedFilters struct {
+	router                *mux.Router
+	savedFilterRepository *repository.SavedFilterRepository
+	userRepository        *repository.UserRepository
+	db                    *database.Database
+}
+
+func SavedFilterController(router *mux.Router, db *database.Database) *SavedFilters {
+	return &SavedFilters{
+		router:                router,
+		savedFilterRepository: repository.NewSavedFilterRepository(db),
+		userRepository:        repository.NewUserRepository(db),
+		db:                    db,
+	}
+}
+
+func (savedFilters *SavedFilters) Router() {
+	// Saved filter operations (all authenticated users, changes limited to the owner)
+	savedFilterRouter := savedFilters.router.PathPrefix("/features/saved-filters").Subrouter()
+	savedFilterRouter.Use(middleware.Authenticate)
+
+	savedFilterRouter.HandleFunc("", savedFilters.getSavedFilters).Methods("GET")
+	savedFilterRouter.HandleFunc("", savedFilters.createSavedFilter).Methods("POST")
+	savedFilterRouter.HandleFunc("/{id:[0-9]+}", savedFilters.getSavedFilter).Methods("GET")
+	savedFilterRouter.HandleFunc("/{id:[0-9]+}", savedFilters.updateSavedFilter).Methods("PUT")
+	savedFilterRouter.HandleFunc("/{id:[0-9]+}", savedFilters.deleteSavedFilter).Methods("DELETE")
+}
+
+func (savedFilters *SavedFilters) getSavedFilters(w http.ResponseWriter, r *http.Request) {
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	visibleFilters, err := savedFilters.savedFilterRepository.GetVisible(userIdInt)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string][]*repository.SavedFilter{
+		"saved_filters": visibleFilters,
+	})
+}
+
+func (savedFilters *SavedFilters) getSavedFilter(w http.ResponseWriter, r *http.Request) {
+	savedFilter, _, ok := savedFilters.findVisible(w, r)
+	if !ok {
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*repository.SavedFilter{
+		"saved_filter": savedFilter,
+	})
+}
+
+func (savedFilters *SavedFilters) createSavedFilter(w http.ResponseWriter, r *http.Request) {
+	createSavedFilterDto, errors := util.ValidateRequest(r, dto.CreateSavedFilterDto{})
+
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	name := strings.TrimSpace(createSavedFilterDto.Name)
+	if !savedFilters.isNameAvailable(w, userIdInt, name, nil) {
+		return
+	}
+
+	if !savedFilters.isValidFilter(w, r, createSavedFilterDto.Filter) {
+		return
+	}
+
+	savedFilter, err := savedFilters.savedFilterRepository.Create(name, createSavedFilterDto.Filter,
+		createSavedFilterDto.IsShared, userIdInt)
+
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*repository.SavedFilter{
+		"saved_filter": savedFilter,
+	})
+}
+
+func (savedFilters *SavedFilters) updateSavedFilter(w http.ResponseWriter, r *http.Request) {
+	savedFilter, userIdInt, ok := savedFilters.findVisible(w, r)
+	if !ok || !savedFilters.canEdit(w, savedFilter, userIdInt) {
+		return
+	}
+
+	updateSavedFilterDto, errors := util.ValidateRequest(r, dto.UpdateSavedFilterDto{})
+
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	if updateSavedFilterDto.Name != nil {
+		name := strings.TrimSpace(*updateSavedFilterDto.Name)
+		if !savedFilters.isNameAvailable(w, savedFilter.CreatedBy, name, &savedFilter.ID) {
+			return
+		}
+		updateSavedFilterDto.Name = &name
+	}
+
+	if updateSavedFilterDto.Filter != nil && !savedFilters.isValidFilter(w, r, updateSavedFilterDto.Filter) {
+		return
+	}
+
+	savedFilter, err := savedFilters.savedFilterRepository.Update(savedFilter.ID, updateSavedFilterDto.Name,
+		updateSavedFilterDto.Filter, updateSavedFilterDto.IsShared)
+
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*repository.SavedFilter{
+		"saved_filter": savedFilter,
+	})
+}
+
+func (savedFilters *SavedFilters) deleteSavedFilter(w http.ResponseWriter, r *http.Request) {
+	savedFilter, userIdInt, ok := savedFilters.findVisible(w, r)
+	if !ok || !savedFilters.canEdit(w, savedFilter, userIdInt) {
+		return
+	}
+
+	err := savedFilters.savedFilterRepository.Delete(savedFilter.ID)
+
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]string{
+		"message": "Saved filter deleted successfully",
+	})
+}
+
+// findVisible loads the filter in the URL, other users' filters only exist for the caller when shared
+func (savedFilters *SavedFilters) findVisible(w http.ResponseWriter, r *http.Request) (*repository.SavedFilter, int, bool) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid saved filter ID")
+		return nil, 0, false
+	}
+
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return nil, 0, false
+	}
+
+	savedFilter, err := savedFilters.savedFilterRepository.FindByID(id)
+	if err != nil || (!savedFilter.IsShared && savedFilter.CreatedBy != userIdInt) {
+		util.Res.Writer(w).Status(404).Data("saved filter not found")
+		return nil, 0, false
+	}
+
+	return savedFilter, userIdInt, true
+}
+
+// canEdit allows the owner and root users to change a saved filter
+func (savedFilters *SavedFilters) canEdit(w http.ResponseWriter, savedFilter *repository.SavedFilter, userID int) bool {
+	if savedFilter.CreatedBy == userID {
+		return true
+	}
+
+	currentUser, err := savedFilters.userRepository.FindByID(userID)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data("Failed to get user info")
+		return false
+	}
+
+	if !currentUser.IsRoot {
+		util.Res.Writer(w).Status(403).Data("You can only change your own saved filters")
+		return false
+	}
+
+	return true
+}
+
+// isNameAvailable writes a 422 for an empty name and a 400 when the owner already uses it
+func (savedFilters *SavedFilters) isNameAvailable(w http.ResponseWriter, userID int, name string, excludeID *int) bool {
+	if name == "" {
+		util.Res.Writer(w).Status422().Data("Saved filter name is required")
+		return false
+	}
+
+	nameExists, err := savedFilters.savedFilterRepository.NameExists(userID, name, excludeID)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return false
+	}
+
+	if nameExists {
+		util.Res.Writer(w).Status(400).Data("You already have a saved filter with this name")
+		return false
+	}
+
+	return true
+}
+
+// isValidFilter checks a filter the same way the task list would read it
+func (savedFilters *SavedFilters) isValidFilter(w http.ResponseWriter, r *http.Request, filter map[string]string) bool {
+	query := url.Values{}
+	for key, value := range filter {
+		if !savedFilterParameters[key] {
+			util.Res.Writer(w).Status422().Data("Unknown filter parameter " + key)
+			return false
+		}
+		query.Set(key, value)
+	}
+
+	userIdInt, _ := strconv.Atoi(r.Header.Get("user_id"))
+	taskFilter := parseTaskFilter(query, userIdInt)
+
+	if err := util.ValidateStruct(taskFilter); err != nil {
+		util.Res.Writer(w).Status422().Data(err.Error())
+		return false
+	}
+
+	if taskFilter.Query != nil {
+		if _, ok := parseTaskQuery(w, r, *taskFilter.Query); !ok {
+			return false
+		}
+	}
+
+	return true
+}
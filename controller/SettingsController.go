@@ -69,6 +69,9 @@ func (s *Settings) updateSettings(w http.ResponseWriter, r *http.Request) {
 		updateSettingsDto.AppDescription,
 		updateSettingsDto.DefaultTheme,
 		updateSettingsDto.EnableNotifications,
+		updateSettingsDto.EnforceDependencies,
+		updateSettingsDto.TrashRetentionDays,
+		updateSettingsDto.AutoArchiveDays,
 	)
 
 	if err != nil {
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/service"
+	"github.com/gorilla/mux"
+)
+
+type Snapshots struct {
+	router              *mux.Router
+	snapshotService     *service.SnapshotService
+	trelloImportService *service.TrelloImportService
+	db                  *database.Database
+}
+
+func SnapshotController(router *mux.Router, db *database.Database) *Snapshots {
+	return &Snapshots{
+		router:              router,
+		snapshotService:     service.NewSnapshotService(db),
+		trelloImportService: service.NewTrelloImportService(db),
+		db:                  db,
+	}
+}
+
+func (snapshots *Snapshots) Router() {
+	// Export and import routes (root users only)
+	adminRouter := snapshots.router.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(middleware.Authenticate)
+	adminRouter.Use(middleware.RequireRoot(snapshots.db))
+
+	adminRouter.HandleFunc("/export", snapshots.exportSnapshot).Methods("GET")
+	adminRouter.HandleFunc("/import", snapshots.importSnapshot).Methods("POST")
+	adminRouter.HandleFunc("/import/trello", snapshots.importTrello).Methods("POST")
+}
+
+// Export everything as a versioned JSON snapshot, ?include_password_hashes=true adds the password hashes
+func (snapshots *Snapshots) exportSnapshot(w http.ResponseWriter, r *http.Request) {
+	includePasswords, _ := strconv.ParseBool(r.URL.Query().Get("include_password_hashes"))
+
+	snapshot, err := snapshots.snapshotService.Export(includePasswords)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("offline-kanban-%s.json", snapshot.ExportedAt.Format("20060102-150405"))
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+
+	util.Res.Writer(w).Status(200).Data(snapshot)
+}
+
+// Import a snapshot, ?mode=restore (default) fills an empty database keeping every ID,
+// ?mode=merge adds the snapshot next to the existing data under new IDs
+func (snapshots *Snapshots) importSnapshot(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = service.ImportModeRestore
+	}
+	if mode != service.ImportModeRestore && mode != service.ImportModeMerge {
+		util.Res.Writer(w).Status(400).Data("Invalid mode. Must be 'restore' or 'merge'")
+		return
+	}
+
+	// Snapshots hold the whole database, allow far more than a regular request
+	const maxSnapshotSize = 256 << 20 // 256MB
+	r.Body = http.MaxBytesReader(w, r.Body, maxSnapshotSize)
+
+	snapshot := &service.Snapshot{}
+	if err := json.NewDecoder(r.Body).Decode(snapshot); err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid snapshot document: " + err.Error())
+		return
+	}
+
+	result, err := snapshots.snapshotService.Import(snapshot, mode, userID)
+	if err != nil {
+		var snapshotErr *service.SnapshotError
+		switch {
+		case errors.As(err, &snapshotErr):
+			util.Res.Writer(w).Status(400).Data(snapshotErr.Message)
+		case errors.Is(err, service.ErrDatabaseNotEmpty):
+			util.Res.Writer(w).Status(409).Data(err.Error())
+		default:
+			util.Res.Writer(w).Status(500).Data(err.Error())
+		}
+		return
+	}
+
+	util.Res.Writer(w).Status(200).Data(map[string]interface{}{
+		"message": "Snapshot imported successfully",
+		"result":  result,
+	})
+}
+
+// Import a Trello board JSON export as a new board, the result lists what was skipped
+func (snapshots *Snapshots) importTrello(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	// Trello exports carry the board's whole action history
+	const maxTrelloExportSize = 64 << 20 // 64MB
+	r.Body = http.MaxBytesReader(w, r.Body, maxTrelloExportSize)
+
+	board := &service.TrelloBoard{}
+	if err := json.NewDecoder(r.Body).Decode(board); err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid Trello export: " + err.Error())
+		return
+	}
+
+	result, err := snapshots.trelloImportService.Import(board, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotTrelloExport) {
+			util.Res.Writer(w).Status(400).Data(err.Error())
+			return
+		}
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status(200).Data(map[string]interface{}{
+		"message": "Trello board imported successfully",
+		"result":  result,
+	})
+}
@@ -0,0 +1,285 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/repository"
+	"github.com/dev-parvej/offline_kanban/service"
+	"github.com/gorilla/mux"
+)
+
+type Sync struct {
+	router             *mux.Router
+	syncService        *service.SyncService
+	syncPeerRepository *repository.SyncPeerRepository
+	db                 *database.Database
+}
+
+func SyncController(router *mux.Router, db *database.Database) *Sync {
+	return &Sync{
+		router:             router,
+		syncService:        service.NewSyncService(db),
+		syncPeerRepository: repository.NewSyncPeerRepository(db),
+		db:                 db,
+	}
+}
+
+func (sync *Sync) Router() {
+	// Routes other instances sync through, with this instance's sync token
+	peerRouter := sync.router.PathPrefix("/sync").Subrouter()
+	peerRouter.Use(middleware.RequireSyncToken(sync.db))
+
+	peerRouter.HandleFunc("/changes", sync.getChanges).Methods("GET")
+	peerRouter.HandleFunc("/changes", sync.applyChanges).Methods("POST")
+
+	// Sync settings, peers and conflicts (root users only)
+	adminRouter := sync.router.PathPrefix("/admin/sync").Subrouter()
+	adminRouter.Use(middleware.Authenticate)
+	adminRouter.Use(middleware.RequireRoot(sync.db))
+
+	adminRouter.HandleFunc("", sync.getStatus).Methods("GET")
+	adminRouter.HandleFunc("/token", sync.generateToken).Methods("POST")
+	adminRouter.HandleFunc("/token", sync.disableToken).Methods("DELETE")
+	adminRouter.HandleFunc("/peers", sync.listPeers).Methods("GET")
+	adminRouter.HandleFunc("/peers", sync.createPeer).Methods("POST")
+	adminRouter.HandleFunc("/peers/{id}", sync.updatePeer).Methods("PUT")
+	adminRouter.HandleFunc("/peers/{id}", sync.deletePeer).Methods("DELETE")
+	adminRouter.HandleFunc("/peers/{id}/sync", sync.syncPeer).Methods("POST")
+	adminRouter.HandleFunc("/conflicts", sync.listConflicts).Methods("GET")
+	adminRouter.HandleFunc("/conflicts/{id}/resolve", sync.resolveConflict).Methods("PUT")
+}
+
+// Changes made on this instance after the "since" sequence number
+func (sync *Sync) getChanges(w http.ResponseWriter, r *http.Request) {
+	since, err := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+	if err != nil || since < 0 {
+		since = 0
+	}
+
+	limit := service.SyncBatchSize
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 1000 {
+			limit = parsedLimit
+		}
+	}
+
+	batch, err := sync.syncService.Changes(since, limit)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status(200).Data(batch)
+}
+
+// Changes pushed by another instance
+func (sync *Sync) applyChanges(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<20)
+
+	push := &service.SyncPush{}
+	if err := json.NewDecoder(r.Body).Decode(push); err != nil || push.InstanceID == "" {
+		util.Res.Writer(w).Status(400).Data("Invalid sync request")
+		return
+	}
+
+	result, err := sync.syncService.Apply(push.InstanceID, push.Seen, push.Since, push.Changes)
+	if err != nil {
+		if errors.Is(err, service.ErrSyncSameInstance) {
+			util.Res.Writer(w).Status(400).Data(err.Error())
+			return
+		}
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status(200).Data(result)
+}
+
+func (sync *Sync) getStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := sync.syncService.Status()
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(status)
+}
+
+// Generate a new sync token, it is only shown in this response
+func (sync *Sync) generateToken(w http.ResponseWriter, r *http.Request) {
+	status, err := sync.syncService.GenerateToken()
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(status)
+}
+
+func (sync *Sync) disableToken(w http.ResponseWriter, r *http.Request) {
+	status, err := sync.syncService.DisableToken()
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(status)
+}
+
+func (sync *Sync) listPeers(w http.ResponseWriter, r *http.Request) {
+	peers, err := sync.syncPeerRepository.GetAll()
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(peers)
+}
+
+func (sync *Sync) createPeer(w http.ResponseWriter, r *http.Request) {
+	createDto, errors := util.ValidateRequest(r, dto.CreateSyncPeerDto{})
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	peer, err := sync.syncPeerRepository.Create(createDto.Name, createDto.URL, createDto.Token)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(peer)
+}
+
+func (sync *Sync) updatePeer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid peer ID")
+		return
+	}
+
+	updateDto, errors := util.ValidateRequest(r, dto.UpdateSyncPeerDto{})
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	peer, err := sync.syncPeerRepository.Update(id, updateDto.Name, updateDto.URL, updateDto.Token)
+	if err != nil {
+		if err == repository.ErrSyncPeerNotFound {
+			util.Res.Writer(w).Status(404).Data(err.Error())
+			return
+		}
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(peer)
+}
+
+func (sync *Sync) deletePeer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid peer ID")
+		return
+	}
+
+	if err := sync.syncPeerRepository.Delete(id); err != nil {
+		if err == repository.ErrSyncPeerNotFound {
+			util.Res.Writer(w).Status(404).Data(err.Error())
+			return
+		}
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]string{
+		"message": "Peer deleted successfully",
+	})
+}
+
+// Pull the peer's changes, then push this instance's changes to it
+func (sync *Sync) syncPeer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid peer ID")
+		return
+	}
+
+	result, err := sync.syncService.SyncPeer(id)
+	if err != nil {
+		var syncErr *service.SyncError
+		switch {
+		case errors.Is(err, repository.ErrSyncPeerNotFound):
+			util.Res.Writer(w).Status(404).Data(err.Error())
+		case errors.As(err, &syncErr):
+			util.Res.Writer(w).Status(502).Data(syncErr.Message)
+		default:
+			util.Res.Writer(w).Status(500).Data(err.Error())
+		}
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(result)
+}
+
+// Changes from peers that could not be merged cleanly, only unresolved ones unless all=true
+func (sync *Sync) listConflicts(w http.ResponseWriter, r *http.Request) {
+	limit := 50 // default limit
+	offset := 0 // default offset
+
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetParam := r.URL.Query().Get("offset"); offsetParam != "" {
+		if parsedOffset, err := strconv.Atoi(offsetParam); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	conflicts, total, err := sync.syncPeerRepository.GetConflicts(r.URL.Query().Get("all") == "true", limit, offset)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]interface{}{
+		"conflicts": conflicts,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+func (sync *Sync) resolveConflict(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid conflict ID")
+		return
+	}
+
+	found, err := sync.syncPeerRepository.ResolveConflict(id)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+	if !found {
+		util.Res.Writer(w).Status(404).Data("Conflict not found")
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]string{
+		"message": "Conflict marked as resolved",
+	})
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/gorilla/mux"
+)
+
+// TaskArchive serves archiving and unarchiving below the task routes
+type TaskArchive struct {
+	*Tasks
+}
+
+func TaskArchiveController(router *mux.Router, db *database.Database) *TaskArchive {
+	return &TaskArchive{
+		Tasks: TaskController(router, db),
+	}
+}
+
+func (archive *TaskArchive) Router() {
+	taskRouter := archive.router.PathPrefix("/features/tasks").Subrouter()
+	taskRouter.Use(middleware.Authenticate)
+	taskRouter.Use(middleware.Idempotency(archive.db))
+
+	// Archive operations (all authenticated users, changes limited to the task's creator)
+	taskRouter.HandleFunc("/{id:[0-9]+}/archive", archive.archiveTask).Methods("POST")
+	taskRouter.HandleFunc("/{id:[0-9]+}/unarchive", archive.unarchiveTask).Methods("POST")
+}
+
+func (archive *TaskArchive) archiveTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid task ID")
+		return
+	}
+
+	existingTask, err := archive.taskRepository.FindByID(id)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	userIdInt, ok := archive.canEditTask(w, r, existingTask)
+	if !ok {
+		return
+	}
+
+	if existingTask.ArchivedAt != nil {
+		util.Res.Writer(w).Status(400).Data("Task is already archived")
+		return
+	}
+
+	task, err := archive.taskService.ArchiveTask(id, userIdInt)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]dto.TaskResponseDto{
+		"task": archive.convertToResponseDto(task),
+	})
+}
+
+func (archive *TaskArchive) unarchiveTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid task ID")
+		return
+	}
+
+	unarchiveTaskDto, errors := util.ValidateRequest(r, dto.UnarchiveTaskDto{})
+
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	existingTask, err := archive.taskRepository.FindByID(id)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	userIdInt, ok := archive.canEditTask(w, r, existingTask)
+	if !ok {
+		return
+	}
+
+	if existingTask.ArchivedAt == nil {
+		util.Res.Writer(w).Status(400).Data("Task is not archived")
+		return
+	}
+
+	if !archive.canOverrideWipLimit(w, userIdInt, unarchiveTaskDto.OverrideWipLimit) {
+		return
+	}
+
+	task, err := archive.taskService.UnarchiveTask(id, userIdInt, unarchiveTaskDto.OverrideWipLimit)
+
+	if writeConflictError(w, err) {
+		return
+	}
+
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]dto.TaskResponseDto{
+		"task": archive.convertToResponseDto(task),
+	})
+}
@@ -0,0 +1,218 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/repository"
+	"github.com/dev-parvej/offline_kanban/service"
+	"github.com/gorilla/mux"
+)
+
+// Most tasks one bulk request may change
+const maxBulkTasks = 500
+
+// TaskBulk serves changing many tasks at once below the task routes
+type TaskBulk struct {
+	*Tasks
+}
+
+func TaskBulkController(router *mux.Router, db *database.Database) *TaskBulk {
+	return &TaskBulk{
+		Tasks: TaskController(router, db),
+	}
+}
+
+func (bulk *TaskBulk) Router() {
+	taskRouter := bulk.router.PathPrefix("/features/tasks").Subrouter()
+	taskRouter.Use(middleware.Authenticate)
+	taskRouter.Use(middleware.Idempotency(bulk.db))
+
+	// Bulk operations (all authenticated users, changes limited to the tasks' creator)
+	taskRouter.HandleFunc("/bulk", bulk.bulkUpdateTasks).Methods("POST")
+}
+
+func (bulk *TaskBulk) bulkUpdateTasks(w http.ResponseWriter, r *http.Request) {
+	bulkTaskDto, errors := util.ValidateRequest(r, dto.BulkTaskDto{})
+
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	currentUser, err := bulk.userRepository.FindByID(userIdInt)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data("Failed to get user info")
+		return
+	}
+
+	operation := service.BulkOperation{
+		Type:             bulkTaskDto.Operation,
+		AssignedTo:       bulkTaskDto.AssignedTo,
+		OverrideWipLimit: bulkTaskDto.OverrideWipLimit,
+	}
+
+	switch bulkTaskDto.Operation {
+	case service.BulkMove:
+		if bulkTaskDto.ColumnID == nil {
+			util.Res.Writer(w).Status422().Data("column_id is required for move")
+			return
+		}
+		if _, err := bulk.columnRepository.FindByID(*bulkTaskDto.ColumnID); err != nil {
+			util.Res.Writer(w).Status(400).Data("Invalid column ID")
+			return
+		}
+		operation.ColumnID = *bulkTaskDto.ColumnID
+
+	case service.BulkAssign:
+		if bulkTaskDto.AssignedTo != nil {
+			if _, err := bulk.userRepository.FindByID(*bulkTaskDto.AssignedTo); err != nil {
+				util.Res.Writer(w).Status(400).Data("Invalid assigned user ID")
+				return
+			}
+		}
+
+	case service.BulkPriority:
+		if bulkTaskDto.Priority == nil {
+			util.Res.Writer(w).Status422().Data("priority is required for priority")
+			return
+		}
+		operation.Priority = *bulkTaskDto.Priority
+
+	case service.BulkAddLabel, service.BulkRemoveLabel:
+		if bulkTaskDto.LabelID == nil {
+			util.Res.Writer(w).Status422().Data("label_id is required for " + bulkTaskDto.Operation)
+			return
+		}
+		if !bulk.labelsExist(w, []int{*bulkTaskDto.LabelID}) {
+			return
+		}
+		operation.LabelID = *bulkTaskDto.LabelID
+
+	case service.BulkDelete:
+		if !currentUser.IsRoot {
+			util.Res.Writer(w).Status(403).Data("Only root users can delete tasks")
+			return
+		}
+	}
+
+	if !bulk.canOverrideWipLimit(w, userIdInt, bulkTaskDto.OverrideWipLimit) {
+		return
+	}
+
+	taskIDs, ok := bulk.bulkTaskIDs(w, r, bulkTaskDto, userIdInt)
+	if !ok {
+		return
+	}
+
+	failures, err := bulk.taskService.BulkUpdate(taskIDs, operation, userIdInt, currentUser.IsRoot)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	response := dto.BulkTaskResponseDto{
+		Results: make([]dto.BulkTaskResultDto, len(taskIDs)),
+	}
+
+	for i, taskID := range taskIDs {
+		result := dto.BulkTaskResultDto{TaskID: taskID, Success: true}
+
+		if err, failed := failures[taskID]; failed {
+			message := err.Error()
+			result.Success = false
+			result.Error = &message
+
+			switch conflict := err.(type) {
+			case *repository.WipLimitError:
+				result.Details = conflict
+			case *repository.BlockedTaskError:
+				result.Details = conflict
+			}
+
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+
+		response.Results[i] = result
+	}
+
+	util.Res.Writer(w).Status().Data(response)
+}
+
+// bulkTaskIDs resolves the tasks of a bulk request, the listed IDs without duplicates or every task
+// matching its filter. A filter matching more than maxBulkTasks tasks is refused
+func (bulk *TaskBulk) bulkTaskIDs(w http.ResponseWriter, r *http.Request, bulkTaskDto dto.BulkTaskDto, userID int) ([]int, bool) {
+	if len(bulkTaskDto.TaskIDs) > 0 {
+		seen := make(map[int]bool, len(bulkTaskDto.TaskIDs))
+		taskIDs := make([]int, 0, len(bulkTaskDto.TaskIDs))
+		for _, taskID := range bulkTaskDto.TaskIDs {
+			if !seen[taskID] {
+				seen[taskID] = true
+				taskIDs = append(taskIDs, taskID)
+			}
+		}
+		return taskIDs, true
+	}
+
+	query := url.Values{}
+	for key, value := range bulkTaskDto.Filter {
+		if !savedFilterParameters[key] {
+			util.Res.Writer(w).Status422().Data("Unknown filter parameter " + key)
+			return nil, false
+		}
+		query.Set(key, value)
+	}
+
+	filter := parseTaskFilter(query, userID)
+	if err := util.ValidateStruct(filter); err != nil {
+		util.Res.Writer(w).Status422().Data(err.Error())
+		return nil, false
+	}
+
+	repoFilters := bulk.convertToRepoFilters(filter)
+
+	if filter.Query != nil {
+		node, ok := parseTaskQuery(w, r, *filter.Query)
+		if !ok {
+			return nil, false
+		}
+		repoFilters.Query = node
+	}
+
+	// One more than allowed tells an oversized filter apart
+	limit := maxBulkTasks + 1
+	repoFilters.Limit = &limit
+	repoFilters.Offset = nil
+
+	matchingTasks, err := bulk.taskRepository.GetWithFilters(repoFilters)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return nil, false
+	}
+
+	if len(matchingTasks) > maxBulkTasks {
+		util.Res.Writer(w).Status422().Data(fmt.Sprintf("Filter matches more than %d tasks", maxBulkTasks))
+		return nil, false
+	}
+
+	taskIDs := make([]int, len(matchingTasks))
+	for i, task := range matchingTasks {
+		taskIDs[i] = task.ID
+	}
+
+	return taskIDs, true
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/dev-parvej/offline_kanban/middleware"
 	"github.com/dev-parvej/offline_kanban/pkg/database"
 	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/taskquery"
 	"github.com/dev-parvej/offline_kanban/pkg/util"
 	"github.com/dev-parvej/offline_kanban/repository"
 	"github.com/dev-parvej/offline_kanban/service"
@@ -17,24 +19,28 @@ import (
 )
 
 type Tasks struct {
-	router              *mux.Router
-	taskRepository      *repository.TaskRepository
-	userRepository      *repository.UserRepository
-	columnRepository    *repository.ColumnRepository
-	checklistRepository *repository.ChecklistRepository
-	taskService         *service.TaskService
-	db                  *database.Database
+	router                *mux.Router
+	taskRepository        *repository.TaskRepository
+	userRepository        *repository.UserRepository
+	columnRepository      *repository.ColumnRepository
+	checklistRepository   *repository.ChecklistRepository
+	labelRepository       *repository.LabelRepository
+	savedFilterRepository *repository.SavedFilterRepository
+	taskService           *service.TaskService
+	db                    *database.Database
 }
 
 func TaskController(router *mux.Router, db *database.Database) *Tasks {
 	return &Tasks{
-		router:              router,
-		taskRepository:      repository.NewTaskRepository(db),
-		userRepository:      repository.NewUserRepository(db),
-		columnRepository:    repository.NewColumnRepository(db),
-		checklistRepository: repository.NewChecklistRepository(db),
-		taskService:         service.NewTaskService(db),
-		db:                  db,
+		router:                router,
+		taskRepository:        repository.NewTaskRepository(db),
+		userRepository:        repository.NewUserRepository(db),
+		columnRepository:      repository.NewColumnRepository(db),
+		checklistRepository:   repository.NewChecklistRepository(db),
+		labelRepository:       repository.NewLabelRepository(db),
+		savedFilterRepository: repository.NewSavedFilterRepository(db),
+		taskService:           service.NewTaskService(db),
+		db:                    db,
 	}
 }
 
@@ -42,6 +48,7 @@ func (tasks *Tasks) Router() {
 	// All task routes require authentication
 	taskRouter := tasks.router.PathPrefix("/features/tasks").Subrouter()
 	taskRouter.Use(middleware.Authenticate)
+	taskRouter.Use(middleware.Idempotency(tasks.db))
 
 	// Public task operations (all authenticated users)
 	taskRouter.HandleFunc("", tasks.getAllTasks).Methods("GET")
@@ -62,24 +69,18 @@ func (tasks *Tasks) Router() {
 	adminTaskRouter := tasks.router.PathPrefix("/admin/tasks").Subrouter()
 	adminTaskRouter.Use(middleware.Authenticate)
 	adminTaskRouter.Use(middleware.RequireRoot(tasks.db))
+	adminTaskRouter.Use(middleware.Idempotency(tasks.db))
 
 	adminTaskRouter.HandleFunc("/{id:[0-9]+}", tasks.deleteTask).Methods("DELETE")
 	adminTaskRouter.HandleFunc("/{id:[0-9]+}/force-update", tasks.forceUpdateTask).Methods("PUT")
 }
 
 func (tasks *Tasks) getAllTasks(w http.ResponseWriter, r *http.Request) {
-	// Parse and validate query parameters
-	filter := tasks.parseTaskFilter(r)
-
-	// Validate the filter struct
-	if err := util.ValidateStruct(filter); err != nil {
-		util.Res.Writer(w).Status(400).Data(err.Error())
+	filter, repoFilters, ok := tasks.taskListFilters(w, r)
+	if !ok {
 		return
 	}
 
-	// Convert DTO to repository filters
-	repoFilters := tasks.convertToRepoFilters(filter)
-
 	// Get tasks with relations (includes user and column data)
 	allTasks, err := tasks.taskRepository.GetWithRelations(repoFilters)
 	if err != nil {
@@ -123,6 +124,43 @@ func (tasks *Tasks) getAllTasks(w http.ResponseWriter, r *http.Request) {
 	util.Res.Writer(w).Status().Data(response)
 }
 
+// taskListFilters reads the filters of the task list from the request, a saved view provides
+// the defaults and parameters on the request override them
+func (tasks *Tasks) taskListFilters(w http.ResponseWriter, r *http.Request) (dto.TaskFilterDto, repository.TaskFilters, bool) {
+	query := r.URL.Query()
+	userID, _ := strconv.Atoi(r.Header.Get("user_id"))
+
+	if viewID := query.Get("view"); viewID != "" {
+		viewQuery, ok := tasks.applySavedFilter(w, viewID, userID, query)
+		if !ok {
+			return dto.TaskFilterDto{}, repository.TaskFilters{}, false
+		}
+		query = viewQuery
+	}
+
+	// Parse and validate query parameters
+	filter := parseTaskFilter(query, userID)
+
+	// Validate the filter struct
+	if err := util.ValidateStruct(filter); err != nil {
+		util.Res.Writer(w).Status(400).Data(err.Error())
+		return filter, repository.TaskFilters{}, false
+	}
+
+	// Convert DTO to repository filters
+	repoFilters := tasks.convertToRepoFilters(filter)
+
+	if filter.Query != nil {
+		node, ok := parseTaskQuery(w, r, *filter.Query)
+		if !ok {
+			return filter, repoFilters, false
+		}
+		repoFilters.Query = node
+	}
+
+	return filter, repoFilters, true
+}
+
 func (tasks *Tasks) getTask(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -177,6 +215,20 @@ func (tasks *Tasks) createTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Validate labels exist (if provided)
+	if !tasks.labelsExist(w, createTaskDto.LabelIDs) {
+		return
+	}
+
+	// Validate parent task is on the same board (if provided)
+	if createTaskDto.ParentID != nil && !tasks.canBeParent(w, *createTaskDto.ParentID, createTaskDto.ColumnID) {
+		return
+	}
+
+	if !tasks.canOverrideWipLimit(w, userIdInt, createTaskDto.OverrideWipLimit) {
+		return
+	}
+
 	// Parse due date if provided
 	var dueDate *time.Time
 	if createTaskDto.DueDate != nil {
@@ -197,13 +249,34 @@ func (tasks *Tasks) createTask(w http.ResponseWriter, r *http.Request) {
 		createTaskDto.AssignedTo,
 		dueDate,
 		*createTaskDto.Priority,
+		createTaskDto.OverrideWipLimit,
 	)
 
+	if writeConflictError(w, err) {
+		return
+	}
+
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
 		return
 	}
 
+	if len(createTaskDto.LabelIDs) > 0 {
+		task, err = tasks.taskService.SetTaskLabels(task.ID, createTaskDto.LabelIDs, userIdInt)
+		if err != nil {
+			util.Res.Writer(w).Status(500).Data(err.Error())
+			return
+		}
+	}
+
+	if createTaskDto.ParentID != nil {
+		task, err = tasks.taskService.SetParent(task.ID, createTaskDto.ParentID, userIdInt)
+		if err != nil {
+			util.Res.Writer(w).Status(500).Data(err.Error())
+			return
+		}
+	}
+
 	response := tasks.convertToResponseDto(task)
 	util.Res.Writer(w).Status().Data(map[string]dto.TaskResponseDto{
 		"task": response,
@@ -262,6 +335,15 @@ func (tasks *Tasks) updateTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Validate labels exist (if being updated)
+	if updateTaskDto.LabelIDs != nil && !tasks.labelsExist(w, *updateTaskDto.LabelIDs) {
+		return
+	}
+
+	if !tasks.canOverrideWipLimit(w, userIdInt, updateTaskDto.OverrideWipLimit) {
+		return
+	}
+
 	// Parse due date if provided
 	var dueDate *time.Time
 	if updateTaskDto.DueDate != nil {
@@ -283,13 +365,26 @@ func (tasks *Tasks) updateTask(w http.ResponseWriter, r *http.Request) {
 		dueDate,
 		updateTaskDto.Priority,
 		updateTaskDto.ColumnID,
+		updateTaskDto.OverrideWipLimit,
 	)
 
+	if writeConflictError(w, err) {
+		return
+	}
+
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
 		return
 	}
 
+	if updateTaskDto.LabelIDs != nil {
+		task, err = tasks.taskService.SetTaskLabels(id, *updateTaskDto.LabelIDs, userIdInt)
+		if err != nil {
+			util.Res.Writer(w).Status(500).Data(err.Error())
+			return
+		}
+	}
+
 	response := tasks.convertToResponseDto(task)
 	util.Res.Writer(w).Status().Data(map[string]dto.TaskResponseDto{
 		"task": response,
@@ -313,21 +408,35 @@ func (tasks *Tasks) moveTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if task exists
-	_, err = tasks.taskRepository.FindByID(id)
+	existingTask, err := tasks.taskRepository.FindByID(id)
 	if err != nil {
 		util.Res.Writer(w).Status(404).Data(err.Error())
 		return
 	}
 
-	// Validate destination column exists
-	_, err = tasks.columnRepository.FindByID(moveTaskDto.ColumnID)
-	if err != nil {
+	// Validate destination column exists on the task's board
+	if !tasks.isSameBoard(existingTask.ColumnID, moveTaskDto.ColumnID) {
 		util.Res.Writer(w).Status(400).Data("Invalid column ID")
 		return
 	}
 
-	// Move task
-	err = tasks.taskRepository.MoveToColumn(id, moveTaskDto.ColumnID, moveTaskDto.NewPosition)
+	// Get current user ID
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	if !tasks.canOverrideWipLimit(w, userIdInt, moveTaskDto.OverrideWipLimit) {
+		return
+	}
+
+	// Move task using service (handles activity tracking)
+	err = tasks.taskService.MoveTask(id, moveTaskDto.ColumnID, &moveTaskDto.NewPosition, userIdInt, moveTaskDto.OverrideWipLimit)
+	if writeConflictError(w, err) {
+		return
+	}
+
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
 		return
@@ -355,14 +464,22 @@ func (tasks *Tasks) deleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = tasks.taskRepository.Delete(id)
+	// Get current user ID
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	// Move the task to the trash using service (handles activity tracking)
+	err = tasks.taskService.DeleteTask(id, userIdInt)
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
 		return
 	}
 
 	util.Res.Writer(w).Status().Data(map[string]string{
-		"message": "Task deleted successfully",
+		"message": "Task moved to trash successfully",
 	})
 }
 
@@ -391,6 +508,13 @@ func (tasks *Tasks) forceUpdateTask(w http.ResponseWriter, r *http.Request) {
 
 	// Admin can force update any task (no ownership check)
 
+	// Get current user ID
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
 	// Validate assigned user exists (if being updated)
 	if updateTaskDto.AssignedTo != nil {
 		_, err = tasks.userRepository.FindByID(*updateTaskDto.AssignedTo)
@@ -411,14 +535,17 @@ func (tasks *Tasks) forceUpdateTask(w http.ResponseWriter, r *http.Request) {
 		dueDate = &parsedDate
 	}
 
-	// Update task
-	task, err := tasks.taskRepository.Update(
+	// Update task using service (handles activity tracking)
+	task, err := tasks.taskService.UpdateTask(
 		id,
+		userIdInt,
 		updateTaskDto.Title,
 		updateTaskDto.Description,
 		updateTaskDto.AssignedTo,
 		dueDate,
 		updateTaskDto.Priority,
+		nil,
+		false,
 	)
 
 	if err != nil {
@@ -448,14 +575,34 @@ func (tasks *Tasks) updateColumnId(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	col, _ := tasks.columnRepository.GetTaskCount(*updateTaskDto.ColumnID)
+	existingTask, err := tasks.taskRepository.FindByID(id)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
 
-	// Update task
-	err = tasks.taskRepository.MoveToColumn(
-		id,
-		*updateTaskDto.ColumnID,
-		col,
-	)
+	if !tasks.isSameBoard(existingTask.ColumnID, *updateTaskDto.ColumnID) {
+		util.Res.Writer(w).Status(400).Data("Invalid column ID")
+		return
+	}
+
+	// Get current user ID
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	if !tasks.canOverrideWipLimit(w, userIdInt, updateTaskDto.OverrideWipLimit) {
+		return
+	}
+
+	// Move task to the end of the column using service (handles activity tracking)
+	err = tasks.taskService.MoveTask(id, *updateTaskDto.ColumnID, nil, userIdInt, updateTaskDto.OverrideWipLimit)
+
+	if writeConflictError(w, err) {
+		return
+	}
 
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
@@ -468,15 +615,114 @@ func (tasks *Tasks) updateColumnId(w http.ResponseWriter, r *http.Request) {
 }
 
 // Helper methods
-func (tasks *Tasks) parseTaskFilter(r *http.Request) dto.TaskFilterDto {
-	query := r.URL.Query()
 
+// canOverrideWipLimit writes a 403 when a non-root user asks to ignore WIP limits
+func (tasks *Tasks) canOverrideWipLimit(w http.ResponseWriter, userID int, override bool) bool {
+	if !override {
+		return true
+	}
+
+	currentUser, err := tasks.userRepository.FindByID(userID)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data("Failed to get user info")
+		return false
+	}
+
+	if !currentUser.IsRoot {
+		util.Res.Writer(w).Status(403).Data("Only root users can override WIP limits")
+		return false
+	}
+
+	return true
+}
+
+// writeConflictError responds with a 409 and the details when err is a WIP limit or
+// blocked task violation
+func writeConflictError(w http.ResponseWriter, err error) bool {
+	switch conflict := err.(type) {
+	case *repository.WipLimitError:
+		util.Res.Writer(w).Status(409).Data(conflict)
+	case *repository.BlockedTaskError:
+		util.Res.Writer(w).Status(409).Data(conflict)
+	default:
+		return false
+	}
+	return true
+}
+
+// applySavedFilter merges a saved filter the user can see with the request's own parameters
+func (tasks *Tasks) applySavedFilter(w http.ResponseWriter, viewID string, userID int, overrides url.Values) (url.Values, bool) {
+	id, err := strconv.Atoi(viewID)
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid view ID")
+		return nil, false
+	}
+
+	savedFilter, err := tasks.savedFilterRepository.FindByID(id)
+	if err != nil || (!savedFilter.IsShared && savedFilter.CreatedBy != userID) {
+		util.Res.Writer(w).Status(404).Data("saved filter not found")
+		return nil, false
+	}
+
+	query := url.Values{}
+	for key, value := range savedFilter.Filter {
+		query.Set(key, value)
+	}
+	for key, values := range overrides {
+		if key != "view" {
+			query[key] = values
+		}
+	}
+
+	return query, true
+}
+
+// parseTaskQuery parses a q= style task query for the current user, writing a 400 that points
+// at the bad token when it is invalid
+func parseTaskQuery(w http.ResponseWriter, r *http.Request, query string) (taskquery.Node, bool) {
+	userID, _ := strconv.Atoi(r.Header.Get("user_id"))
+
+	node, err := taskquery.Parse(query, taskquery.Context{UserID: userID, Now: time.Now()})
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data(err)
+		return nil, false
+	}
+
+	return node, true
+}
+
+func (tasks *Tasks) isSameBoard(fromColumnID, toColumnID int) bool {
+	toColumn, err := tasks.columnRepository.FindByID(toColumnID)
+	if err != nil {
+		return false
+	}
+
+	fromColumn, err := tasks.columnRepository.FindByID(fromColumnID)
+	if err != nil {
+		// The task's column is gone, any existing column is a valid destination
+		return true
+	}
+
+	return fromColumn.BoardID == toColumn.BoardID
+}
+
+// parseTaskFilter reads the task list query parameters, userID resolves mentioned_me
+func parseTaskFilter(query url.Values, userID int) dto.TaskFilterDto {
 	filter := dto.TaskFilterDto{}
 
 	if search := strings.TrimSpace(query.Get("search")); search != "" {
 		filter.Search = &search
 	}
 
+	// Structured query, e.g. q=assignee:me priority:>=high -column:Done
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		filter.Query = &q
+	}
+
+	if boardID, err := strconv.Atoi(query.Get("board_id")); err == nil && boardID > 0 {
+		filter.BoardID = &boardID
+	}
+
 	if columnID, err := strconv.Atoi(query.Get("column_id")); err == nil && columnID > 0 {
 		filter.ColumnID = &columnID
 	}
@@ -493,6 +739,36 @@ func (tasks *Tasks) parseTaskFilter(r *http.Request) dto.TaskFilterDto {
 		filter.Priority = &priority
 	}
 
+	// Labels, either one label_id or a comma separated list in labels
+	if labelID, err := strconv.Atoi(query.Get("label_id")); err == nil && labelID > 0 {
+		filter.LabelIDs = append(filter.LabelIDs, labelID)
+	}
+
+	if labels := query.Get("labels"); labels != "" {
+		for _, part := range strings.Split(labels, ",") {
+			if labelID, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && labelID > 0 {
+				filter.LabelIDs = append(filter.LabelIDs, labelID)
+			}
+		}
+	}
+
+	if labelMode := query.Get("label_mode"); labelMode != "" {
+		filter.LabelMode = &labelMode
+	}
+
+	// Children of one task, or only tasks without a parent
+	if parentID, err := strconv.Atoi(query.Get("parent_id")); err == nil && parentID > 0 {
+		filter.ParentID = &parentID
+	}
+
+	filter.TopLevelOnly = query.Get("top_level_only") == "true"
+	filter.IncludeArchived = query.Get("include_archived") == "true"
+
+	// Tasks where the current user is mentioned
+	if query.Get("mentioned_me") == "true" && userID > 0 {
+		filter.MentionedUserID = &userID
+	}
+
 	if dueDateFrom := query.Get("due_date_from"); dueDateFrom != "" {
 		filter.DueDateFrom = &dueDateFrom
 	}
@@ -537,12 +813,23 @@ func (tasks *Tasks) parseTaskFilter(r *http.Request) dto.TaskFilterDto {
 func (tasks *Tasks) convertToRepoFilters(filter dto.TaskFilterDto) repository.TaskFilters {
 	repoFilter := repository.TaskFilters{
 		Search:     filter.Search,
+		BoardID:    filter.BoardID,
 		ColumnID:   filter.ColumnID,
 		AssignedTo: filter.AssignedTo,
 		CreatedBy:  filter.CreatedBy,
 		Priority:   filter.Priority,
 	}
 
+	repoFilter.MentionedUserID = filter.MentionedUserID
+	repoFilter.ParentID = filter.ParentID
+	repoFilter.TopLevelOnly = filter.TopLevelOnly
+	repoFilter.IncludeArchived = filter.IncludeArchived
+	repoFilter.LabelIDs = filter.LabelIDs
+
+	if filter.LabelMode != nil {
+		repoFilter.LabelMode = *filter.LabelMode
+	}
+
 	// Parse date strings to time.Time
 	if filter.DueDateFrom != nil {
 		if parsed, err := time.Parse(time.RFC3339, *filter.DueDateFrom); err == nil {
@@ -576,6 +863,9 @@ func (tasks *Tasks) convertToRepoFilters(filter dto.TaskFilterDto) repository.Ta
 	// Set ordering
 	if filter.OrderBy != nil {
 		repoFilter.OrderBy = *filter.OrderBy
+	} else if filter.Search != nil {
+		// Search results come best match first
+		repoFilter.OrderBy = "relevance"
 	} else {
 		repoFilter.OrderBy = "position"
 	}
@@ -600,6 +890,7 @@ func (tasks *Tasks) convertToResponseDto(task *repository.Task) dto.TaskResponse
 		Priority:     task.Priority,
 		Position:     task.Position,
 		Weight:       task.Weight,
+		ParentID:     task.ParentID,
 		CreatedAt:    task.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:    task.UpdatedAt.Format(time.RFC3339),
 		CommentCount: task.CommentCount,
@@ -611,6 +902,21 @@ func (tasks *Tasks) convertToResponseDto(task *repository.Task) dto.TaskResponse
 		response.DueDate = &dueDateStr
 	}
 
+	// Highlighted match, only set when listing search results
+	response.SearchSnippet = task.SearchSnippet
+
+	// Set for tasks in the trash
+	if task.DeletedAt != nil {
+		deletedAtStr := task.DeletedAt.Format(time.RFC3339)
+		response.DeletedAt = &deletedAtStr
+	}
+	response.DeletedBy = task.DeletedBy
+
+	if task.ArchivedAt != nil {
+		archivedAtStr := task.ArchivedAt.Format(time.RFC3339)
+		response.ArchivedAt = &archivedAtStr
+	}
+
 	// Handle related data
 	if task.AssignedUser != nil {
 		name := ""
@@ -640,9 +946,54 @@ func (tasks *Tasks) convertToResponseDto(task *repository.Task) dto.TaskResponse
 		response.ColumnTitle = task.ColumnTitle
 	}
 
+	// Labels are loaded with the task; fall back to a query for tasks fetched without them
+	labels := task.Labels
+	if labels == nil {
+		labels, _ = tasks.labelRepository.GetByTask(task.ID)
+	}
+	response.Labels = make([]dto.LabelDto, 0, len(labels))
+	for _, label := range labels {
+		response.Labels = append(response.Labels, dto.LabelDto{
+			ID:    label.ID,
+			Name:  label.Name,
+			Color: label.Color,
+		})
+	}
+
+	// Same for dependencies
+	if task.BlockedBy == nil || task.Blocks == nil {
+		tasks.taskRepository.LoadDependencies(task)
+	}
+	dependencies := convertDependenciesToResponseDto(task.BlockedBy, task.Blocks)
+	response.BlockedBy = dependencies.BlockedBy
+	response.Blocks = dependencies.Blocks
+	response.IsBlocked = dependencies.IsBlocked
+
+	// And for the roll-up of child tasks
+	if task.Subtasks == nil {
+		tasks.taskRepository.LoadSubtaskProgress(task)
+	}
+	response.Subtasks = convertSubtaskProgressToDto(task.Subtasks)
+
 	return response
 }
 
+// labelsExist writes a 400 unless every label ID refers to an existing label
+func (tasks *Tasks) labelsExist(w http.ResponseWriter, labelIDs []int) bool {
+	exists, err := tasks.labelRepository.AllExist(labelIDs)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return false
+	}
+
+	if !exists {
+		util.Res.Writer(w).Status(400).Data("Invalid label ID")
+		return false
+	}
+
+	return true
+}
+
 // Checklist handlers
 func (tasks *Tasks) getTaskChecklists(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -725,7 +1076,7 @@ func (tasks *Tasks) createChecklist(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create checklist
-	checklist, err := tasks.checklistRepository.Create(createChecklistDto.Title, taskID, userIdInt)
+	checklist, err := tasks.taskService.CreateChecklist(createChecklistDto.Title, taskID, userIdInt)
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
 		return
@@ -790,7 +1141,7 @@ func (tasks *Tasks) updateChecklist(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update checklist
-	checklist, err := tasks.checklistRepository.Update(checklistID, updateChecklistDto.Title)
+	checklist, err := tasks.taskService.UpdateChecklist(checklistID, updateChecklistDto.Title, userIdInt)
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
 		return
@@ -843,7 +1194,7 @@ func (tasks *Tasks) toggleChecklist(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Toggle checklist completion
-	checklist, err := tasks.checklistRepository.ToggleComplete(checklistID, userIdInt, toggleChecklistDto.Completed)
+	checklist, err := tasks.taskService.ToggleChecklist(checklistID, userIdInt, toggleChecklistDto.Completed)
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
 		return
@@ -901,7 +1252,7 @@ func (tasks *Tasks) deleteChecklist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = tasks.checklistRepository.Delete(checklistID)
+	err = tasks.taskService.DeleteChecklist(checklistID, taskID, userIdInt)
 	if err != nil {
 		util.Res.Writer(w).Status(500).Data(err.Error())
 		return
@@ -952,3 +1303,41 @@ func (tasks *Tasks) convertChecklistToResponseDto(checklist *repository.Checklis
 
 	return response
 }
+
+// canEditTask writes a 403 unless the current user is root or created the task, and returns the user ID
+func (tasks *Tasks) canEditTask(w http.ResponseWriter, r *http.Request, task *repository.Task) (int, bool) {
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return 0, false
+	}
+
+	currentUser, err := tasks.userRepository.FindByID(userIdInt)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data("Failed to get user info")
+		return 0, false
+	}
+
+	if !currentUser.IsRoot && task.CreatedBy != userIdInt {
+		util.Res.Writer(w).Status(403).Data("You can only edit your own tasks")
+		return 0, false
+	}
+
+	return userIdInt, true
+}
+
+// canBeParent writes a 400 unless the parent task exists on the same board as the child's column
+func (tasks *Tasks) canBeParent(w http.ResponseWriter, parentID, childColumnID int) bool {
+	parent, err := tasks.taskRepository.FindByID(parentID)
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid parent task ID")
+		return false
+	}
+
+	if !tasks.isSameBoard(parent.ColumnID, childColumnID) {
+		util.Res.Writer(w).Status(400).Data("Subtasks must be on the same board as their parent")
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/service"
+	"github.com/gorilla/mux"
+)
+
+// TaskCsv serves the CSV export and import of tasks below the task routes
+type TaskCsv struct {
+	*Tasks
+	taskCsvService *service.TaskCsvService
+}
+
+func TaskCsvController(router *mux.Router, db *database.Database) *TaskCsv {
+	return &TaskCsv{
+		Tasks:          TaskController(router, db),
+		taskCsvService: service.NewTaskCsvService(db),
+	}
+}
+
+func (taskCsv *TaskCsv) Router() {
+	// Export (all authenticated users)
+	taskRouter := taskCsv.router.PathPrefix("/features/tasks").Subrouter()
+	taskRouter.Use(middleware.Authenticate)
+	taskRouter.Use(middleware.Idempotency(taskCsv.db))
+
+	taskRouter.HandleFunc("/export.csv", taskCsv.exportTasksCsv).Methods("GET")
+
+	// Import (root users only)
+	adminTaskRouter := taskCsv.router.PathPrefix("/admin/tasks").Subrouter()
+	adminTaskRouter.Use(middleware.Authenticate)
+	adminTaskRouter.Use(middleware.RequireRoot(taskCsv.db))
+	adminTaskRouter.Use(middleware.Idempotency(taskCsv.db))
+
+	adminTaskRouter.HandleFunc("/import.csv", taskCsv.importTasksCsv).Methods("POST")
+}
+
+// Export the tasks matching the filters of the task list as CSV, every page at once. Rows are
+// written as they are read from the database
+func (taskCsv *TaskCsv) exportTasksCsv(w http.ResponseWriter, r *http.Request) {
+	_, repoFilters, ok := taskCsv.taskListFilters(w, r)
+	if !ok {
+		return
+	}
+
+	repoFilters.Limit = nil
+	repoFilters.Offset = nil
+
+	filename := fmt.Sprintf("tasks-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if err := taskCsv.taskCsvService.Export(w, repoFilters, flush); err != nil {
+		// The status is sent already, the client is left with a cut off file
+		fmt.Printf("Failed to export tasks as CSV: %v\n", err)
+	}
+}
+
+// Import tasks from a CSV file, sent as the request body or as the file field of a form. Columns are mapped by
+// header name, ?dry_run=true only reports the row errors, ?column_id= is the column of rows without one and
+// ?board_id= limits the lookup of column titles to one board
+func (taskCsv *TaskCsv) importTasksCsv(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	query := r.URL.Query()
+	options := service.CsvImportOptions{
+		DryRun:           query.Get("dry_run") == "true",
+		OverrideWipLimit: query.Get("override_wip_limit") == "true",
+		UserID:           userID,
+	}
+
+	if value := query.Get("column_id"); value != "" {
+		columnID, err := strconv.Atoi(value)
+		if err != nil || columnID <= 0 {
+			util.Res.Writer(w).Status(400).Data("Invalid column ID")
+			return
+		}
+		column, err := taskCsv.columnRepository.FindByID(columnID)
+		if err != nil || column.DeletedAt.Valid {
+			util.Res.Writer(w).Status(404).Data("Column not found")
+			return
+		}
+		options.ColumnID = &columnID
+	}
+
+	if value := query.Get("board_id"); value != "" {
+		boardID, err := strconv.Atoi(value)
+		if err != nil || boardID <= 0 {
+			util.Res.Writer(w).Status(400).Data("Invalid board ID")
+			return
+		}
+		options.BoardID = &boardID
+	}
+
+	// Limit file size to 10MB
+	const maxCsvSize = 10 << 20
+	r.Body = http.MaxBytesReader(w, r.Body, maxCsvSize)
+
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxCsvSize); err != nil {
+			util.Res.Writer(w).Status(400).Data("File too large or invalid form data")
+			return
+		}
+
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			util.Res.Writer(w).Status(400).Data("No CSV file provided")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	result, err := taskCsv.taskCsvService.Import(body, options)
+	if err != nil {
+		var csvErr *service.CsvImportError
+		if errors.As(err, &csvErr) {
+			util.Res.Writer(w).Status(400).Data(csvErr.Message)
+			return
+		}
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	// Nothing was created when a row failed
+	if len(result.Errors) > 0 && !result.DryRun {
+		util.Res.Writer(w).Status422().Data(result)
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(result)
+}
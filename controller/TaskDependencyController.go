@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/repository"
+	"github.com/gorilla/mux"
+)
+
+// TaskDependencies serves the tasks blocking each other below the task routes
+type TaskDependencies struct {
+	*Tasks
+	dependencyRepository *repository.TaskDependencyRepository
+}
+
+func TaskDependencyController(router *mux.Router, db *database.Database) *TaskDependencies {
+	return &TaskDependencies{
+		Tasks:                TaskController(router, db),
+		dependencyRepository: repository.NewTaskDependencyRepository(db),
+	}
+}
+
+func (dependencies *TaskDependencies) Router() {
+	taskRouter := dependencies.router.PathPrefix("/features/tasks").Subrouter()
+	taskRouter.Use(middleware.Authenticate)
+	taskRouter.Use(middleware.Idempotency(dependencies.db))
+
+	// Dependency operations (all authenticated users, changes limited to the task's creator)
+	taskRouter.HandleFunc("/{id:[0-9]+}/dependencies", dependencies.getTaskDependencies).Methods("GET")
+	taskRouter.HandleFunc("/{id:[0-9]+}/dependencies", dependencies.addTaskDependency).Methods("POST")
+	taskRouter.HandleFunc("/{id:[0-9]+}/dependencies/{blockedById:[0-9]+}", dependencies.removeTaskDependency).Methods("DELETE")
+}
+
+func (dependencies *TaskDependencies) getTaskDependencies(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid task ID")
+		return
+	}
+
+	task, err := dependencies.taskRepository.FindByID(taskID)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	if err := dependencies.taskRepository.LoadDependencies(task); err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(convertDependenciesToResponseDto(task.BlockedBy, task.Blocks))
+}
+
+func (dependencies *TaskDependencies) addTaskDependency(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid task ID")
+		return
+	}
+
+	createDependencyDto, errors := util.ValidateRequest(r, dto.CreateTaskDependencyDto{})
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	task, err := dependencies.taskRepository.FindByID(taskID)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	userIdInt, ok := dependencies.canEditTask(w, r, task)
+	if !ok {
+		return
+	}
+
+	blockedByTaskID := createDependencyDto.BlockedByTaskID
+	if blockedByTaskID == taskID {
+		util.Res.Writer(w).Status(400).Data("A task cannot depend on itself")
+		return
+	}
+
+	if _, err := dependencies.taskRepository.FindByID(blockedByTaskID); err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid blocking task ID")
+		return
+	}
+
+	exists, err := dependencies.dependencyRepository.Exists(taskID, blockedByTaskID)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	if exists {
+		util.Res.Writer(w).Status(400).Data("Dependency already exists")
+		return
+	}
+
+	createsCycle, err := dependencies.dependencyRepository.CreatesCycle(taskID, blockedByTaskID)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	if createsCycle {
+		util.Res.Writer(w).Status(409).Data("Dependency would create a cycle")
+		return
+	}
+
+	task, err = dependencies.taskService.AddDependency(taskID, blockedByTaskID, userIdInt)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]dto.TaskResponseDto{
+		"task": dependencies.convertToResponseDto(task),
+	})
+}
+
+func (dependencies *TaskDependencies) removeTaskDependency(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid task ID")
+		return
+	}
+
+	blockedByTaskID, err := strconv.Atoi(vars["blockedById"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid blocking task ID")
+		return
+	}
+
+	task, err := dependencies.taskRepository.FindByID(taskID)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	userIdInt, ok := dependencies.canEditTask(w, r, task)
+	if !ok {
+		return
+	}
+
+	task, err = dependencies.taskService.RemoveDependency(taskID, blockedByTaskID, userIdInt)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]dto.TaskResponseDto{
+		"task": dependencies.convertToResponseDto(task),
+	})
+}
+
+// Helper to convert both sides of a task's dependencies to response DTOs
+func convertDependenciesToResponseDto(blockedBy, blocks []*repository.TaskDependency) dto.TaskDependenciesResponseDto {
+	response := dto.TaskDependenciesResponseDto{
+		BlockedBy: make([]dto.TaskDependencyDto, 0, len(blockedBy)),
+		Blocks:    make([]dto.TaskDependencyDto, 0, len(blocks)),
+	}
+
+	for _, dependency := range blockedBy {
+		response.BlockedBy = append(response.BlockedBy, convertDependencyToDto(dependency))
+		if !dependency.IsDone {
+			response.IsBlocked = true
+		}
+	}
+
+	for _, dependency := range blocks {
+		response.Blocks = append(response.Blocks, convertDependencyToDto(dependency))
+	}
+
+	return response
+}
+
+func convertDependencyToDto(dependency *repository.TaskDependency) dto.TaskDependencyDto {
+	return dto.TaskDependencyDto{
+		TaskID:      dependency.TaskID,
+		Title:       dependency.Title,
+		ColumnID:    dependency.ColumnID,
+		ColumnTitle: dependency.ColumnTitle,
+		IsDone:      dependency.IsDone,
+	}
+}
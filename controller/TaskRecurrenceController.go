@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/recurrence"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/repository"
+	"github.com/dev-parvej/offline_kanban/service"
+	"github.com/gorilla/mux"
+)
+
+// TaskRecurrences serves the recurrence rules of template tasks below the task routes
+type TaskRecurrences struct {
+	*Tasks
+	recurrenceRepository *repository.TaskRecurrenceRepository
+	recurrenceService    *service.RecurrenceService
+}
+
+func TaskRecurrenceController(router *mux.Router, db *database.Database) *TaskRecurrences {
+	return &TaskRecurrences{
+		Tasks:                TaskController(router, db),
+		recurrenceRepository: repository.NewTaskRecurrenceRepository(db),
+		recurrenceService:    service.NewRecurrenceService(db),
+	}
+}
+
+func (recurrences *TaskRecurrences) Router() {
+	taskRouter := recurrences.router.PathPrefix("/features/tasks").Subrouter()
+	taskRouter.Use(middleware.Authenticate)
+	taskRouter.Use(middleware.Idempotency(recurrences.db))
+
+	// Recurrence operations (all authenticated users, changes limited to the template's creator)
+	taskRouter.HandleFunc("/{id:[0-9]+}/recurrence", recurrences.getTaskRecurrence).Methods("GET")
+	taskRouter.HandleFunc("/{id:[0-9]+}/recurrence", recurrences.setTaskRecurrence).Methods("PUT")
+	taskRouter.HandleFunc("/{id:[0-9]+}/recurrence", recurrences.deleteTaskRecurrence).Methods("DELETE")
+}
+
+func (recurrences *TaskRecurrences) getTaskRecurrence(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid task ID")
+		return
+	}
+
+	recurrence, err := recurrences.recurrenceRepository.FindByTask(taskID)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*repository.TaskRecurrence{
+		"recurrence": recurrence,
+	})
+}
+
+func (recurrences *TaskRecurrences) setTaskRecurrence(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid task ID")
+		return
+	}
+
+	setRecurrenceDto, errors := util.ValidateRequest(r, dto.SetTaskRecurrenceDto{})
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	task, err := recurrences.taskRepository.FindByID(taskID)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	userIdInt, ok := recurrences.canEditTask(w, r, task)
+	if !ok {
+		return
+	}
+
+	// Instances go to the template's column unless another column of the board is given
+	columnID := task.ColumnID
+	if setRecurrenceDto.ColumnID != nil {
+		if !recurrences.isSameBoard(task.ColumnID, *setRecurrenceDto.ColumnID) {
+			util.Res.Writer(w).Status(400).Data("Invalid column ID")
+			return
+		}
+		columnID = *setRecurrenceDto.ColumnID
+	}
+
+	if setRecurrenceDto.Frequency == "cron" {
+		if setRecurrenceDto.CronExpr == nil {
+			util.Res.Writer(w).Status(400).Data("A cron expression is required for the cron frequency")
+			return
+		}
+		if err := recurrence.ValidateCron(*setRecurrenceDto.CronExpr); err != nil {
+			util.Res.Writer(w).Status(400).Data("Invalid cron expression: " + err.Error())
+			return
+		}
+	}
+
+	var startsAt *time.Time
+	if setRecurrenceDto.StartsAt != nil {
+		parsedDate, err := time.Parse(time.RFC3339, *setRecurrenceDto.StartsAt)
+		if err != nil {
+			util.Res.Writer(w).Status(400).Data("Invalid start date format. Use ISO 8601 format")
+			return
+		}
+		startsAt = &parsedDate
+	}
+
+	catchUp := true
+	if setRecurrenceDto.CatchUp != nil {
+		catchUp = *setRecurrenceDto.CatchUp
+	}
+
+	taskRecurrence, err := recurrences.recurrenceService.SetRecurrence(
+		taskID,
+		columnID,
+		setRecurrenceDto.Frequency,
+		setRecurrenceDto.Interval,
+		setRecurrenceDto.CronExpr,
+		catchUp,
+		startsAt,
+		userIdInt,
+	)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*repository.TaskRecurrence{
+		"recurrence": taskRecurrence,
+	})
+}
+
+func (recurrences *TaskRecurrences) deleteTaskRecurrence(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid task ID")
+		return
+	}
+
+	task, err := recurrences.taskRepository.FindByID(taskID)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	if _, ok := recurrences.canEditTask(w, r, task); !ok {
+		return
+	}
+
+	err = recurrences.recurrenceRepository.DeleteByTask(taskID)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]string{
+		"message": "Recurrence removed successfully",
+	})
+}
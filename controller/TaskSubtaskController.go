@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/repository"
+	"github.com/gorilla/mux"
+)
+
+// TaskSubtasks serves the subtasks of a task below the task routes
+type TaskSubtasks struct {
+	*Tasks
+}
+
+func TaskSubtaskController(router *mux.Router, db *database.Database) *TaskSubtasks {
+	return &TaskSubtasks{
+		Tasks: TaskController(router, db),
+	}
+}
+
+func (subtasks *TaskSubtasks) Router() {
+	taskRouter := subtasks.router.PathPrefix("/features/tasks").Subrouter()
+	taskRouter.Use(middleware.Authenticate)
+	taskRouter.Use(middleware.Idempotency(subtasks.db))
+
+	// Subtask operations (all authenticated users, changes limited to the creators of both tasks)
+	taskRouter.HandleFunc("/{id:[0-9]+}/subtasks", subtasks.getSubtasks).Methods("GET")
+	taskRouter.HandleFunc("/{id:[0-9]+}/subtasks", subtasks.attachSubtask).Methods("POST")
+	taskRouter.HandleFunc("/{id:[0-9]+}/subtasks/{childId:[0-9]+}", subtasks.detachSubtask).Methods("DELETE")
+}
+
+func (subtasks *TaskSubtasks) getSubtasks(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	parentID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid task ID")
+		return
+	}
+
+	parent, err := subtasks.taskRepository.FindByID(parentID)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	children, err := subtasks.taskRepository.GetWithRelations(repository.TaskFilters{
+		ParentID: &parentID,
+		OrderBy:  "position",
+	})
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	if err := subtasks.taskRepository.LoadSubtaskProgress(parent); err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	subtaskResponses := make([]dto.TaskResponseDto, len(children))
+	for i, child := range children {
+		subtaskResponses[i] = subtasks.convertToResponseDto(child)
+	}
+
+	util.Res.Writer(w).Status().Data(dto.SubtaskListResponseDto{
+		Subtasks: subtaskResponses,
+		ParentID: parentID,
+		Progress: convertSubtaskProgressToDto(parent.Subtasks),
+	})
+}
+
+func (subtasks *TaskSubtasks) attachSubtask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	parentID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid task ID")
+		return
+	}
+
+	attachSubtaskDto, errors := util.ValidateRequest(r, dto.AttachSubtaskDto{})
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	parent, err := subtasks.taskRepository.FindByID(parentID)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	userIdInt, ok := subtasks.canEditTask(w, r, parent)
+	if !ok {
+		return
+	}
+
+	child, err := subtasks.taskRepository.FindByID(attachSubtaskDto.TaskID)
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid subtask ID")
+		return
+	}
+
+	if child.ParentID != nil && *child.ParentID == parentID {
+		util.Res.Writer(w).Status(400).Data("Task is already a subtask of this task")
+		return
+	}
+
+	// Moving a subtask off another parent takes an explicit detach first
+	if child.ParentID != nil {
+		util.Res.Writer(w).Status(409).Data("Task is a subtask of another task, detach it first")
+		return
+	}
+
+	if _, ok := subtasks.canEditTask(w, r, child); !ok {
+		return
+	}
+
+	if !subtasks.canBeParent(w, parentID, child.ColumnID) {
+		return
+	}
+
+	createsCycle, err := subtasks.taskRepository.CreatesParentCycle(child.ID, parentID)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	if createsCycle {
+		util.Res.Writer(w).Status(409).Data("A task cannot be a subtask of itself or of its own subtasks")
+		return
+	}
+
+	child, err = subtasks.taskService.SetParent(child.ID, &parentID, userIdInt)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]dto.TaskResponseDto{
+		"task": subtasks.convertToResponseDto(child),
+	})
+}
+
+func (subtasks *TaskSubtasks) detachSubtask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	parentID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid task ID")
+		return
+	}
+
+	childID, err := strconv.Atoi(vars["childId"])
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid subtask ID")
+		return
+	}
+
+	parent, err := subtasks.taskRepository.FindByID(parentID)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	userIdInt, ok := subtasks.canEditTask(w, r, parent)
+	if !ok {
+		return
+	}
+
+	child, err := subtasks.taskRepository.FindByID(childID)
+	if err != nil || child.ParentID == nil || *child.ParentID != parentID {
+		util.Res.Writer(w).Status(404).Data("Subtask not found")
+		return
+	}
+
+	child, err = subtasks.taskService.SetParent(childID, nil, userIdInt)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]dto.TaskResponseDto{
+		"task": subtasks.convertToResponseDto(child),
+	})
+}
+
+// Helper to convert a subtask roll-up to a response DTO
+func convertSubtaskProgressToDto(progress *repository.SubtaskProgress) dto.SubtaskProgressDto {
+	response := dto.SubtaskProgressDto{}
+	if progress == nil {
+		return response
+	}
+
+	response.Total = progress.Total
+	response.Done = progress.Done
+	if progress.Total > 0 {
+		response.Percent = progress.Done * 100 / progress.Total
+	}
+
+	return response
+}
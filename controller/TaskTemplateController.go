@@ -0,0 +1,383 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/repository"
+	"github.com/gorilla/mux"
+)
+
+type TaskTemplates struct {
+	router             *mux.Router
+	templateRepository *repository.TaskTemplateRepository
+	columnRepository   *repository.ColumnRepository
+	userRepository     *repository.UserRepository
+	labelRepository    *repository.LabelRepository
+	tasks              *Tasks
+	db                 *database.Database
+}
+
+func TaskTemplateController(router *mux.Router, db *database.Database) *TaskTemplates {
+	return &TaskTemplates{
+		router:             router,
+		templateRepository: repository.NewTaskTemplateRepository(db),
+		columnRepository:   repository.NewColumnRepository(db),
+		userRepository:     repository.NewUserRepository(db),
+		labelRepository:    repository.NewLabelRepository(db),
+		tasks:              TaskController(router, db),
+		db:                 db,
+	}
+}
+
+func (templates *TaskTemplates) Router() {
+	// Template read operations (all authenticated users, needed to create tasks from them)
+	templateRouter := templates.router.PathPrefix("/features/task-templates").Subrouter()
+	templateRouter.Use(middleware.Authenticate)
+
+	templateRouter.HandleFunc("", templates.getAllTemplates).Methods("GET")
+	templateRouter.HandleFunc("/{id:[0-9]+}", templates.getTemplate).Methods("GET")
+
+	// Creating a task from a template sits with the task routes (all authenticated users)
+	taskRouter := templates.router.PathPrefix("/features/tasks").Subrouter()
+	taskRouter.Use(middleware.Authenticate)
+	taskRouter.Use(middleware.Idempotency(templates.db))
+
+	taskRouter.HandleFunc("/from-template/{id:[0-9]+}", templates.createTaskFromTemplate).Methods("POST")
+
+	// Template management (root users only)
+	adminTemplateRouter := templates.router.PathPrefix("/admin/task-templates").Subrouter()
+	adminTemplateRouter.Use(middleware.Authenticate)
+	adminTemplateRouter.Use(middleware.RequireRoot(templates.db))
+
+	adminTemplateRouter.HandleFunc("", templates.getAllTemplates).Methods("GET")
+	adminTemplateRouter.HandleFunc("", templates.createTemplate).Methods("POST")
+	adminTemplateRouter.HandleFunc("/{id:[0-9]+}", templates.getTemplate).Methods("GET")
+	adminTemplateRouter.HandleFunc("/{id:[0-9]+}", templates.updateTemplate).Methods("PUT")
+	adminTemplateRouter.HandleFunc("/{id:[0-9]+}", templates.deleteTemplate).Methods("DELETE")
+}
+
+func (templates *TaskTemplates) getAllTemplates(w http.ResponseWriter, r *http.Request) {
+	allTemplates, err := templates.templateRepository.GetAll()
+
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string][]*repository.TaskTemplate{
+		"templates": allTemplates,
+	})
+}
+
+func (templates *TaskTemplates) getTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid template ID")
+		return
+	}
+
+	template, err := templates.templateRepository.FindByID(id)
+
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*repository.TaskTemplate{
+		"template": template,
+	})
+}
+
+func (templates *TaskTemplates) createTemplate(w http.ResponseWriter, r *http.Request) {
+	createTemplateDto, errors := util.ValidateRequest(r, dto.CreateTaskTemplateDto{})
+
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	name := strings.TrimSpace(createTemplateDto.Name)
+	if !templates.isNameAvailable(w, name, nil) {
+		return
+	}
+
+	if !templates.referencesExist(w, createTemplateDto.ColumnID, createTemplateDto.AssignedTo, createTemplateDto.LabelIDs) {
+		return
+	}
+
+	template, err := templates.templateRepository.Create(
+		name,
+		createTemplateDto.TitlePattern,
+		createTemplateDto.Description,
+		createTemplateDto.Priority,
+		createTemplateDto.ColumnID,
+		createTemplateDto.AssignedTo,
+		trimChecklistItems(createTemplateDto.ChecklistItems),
+		createTemplateDto.LabelIDs,
+		userIdInt,
+	)
+
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*repository.TaskTemplate{
+		"template": template,
+	})
+}
+
+func (templates *TaskTemplates) updateTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid template ID")
+		return
+	}
+
+	updateTemplateDto, errors := util.ValidateRequest(r, dto.UpdateTaskTemplateDto{})
+
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	if updateTemplateDto.Name != nil {
+		name := strings.TrimSpace(*updateTemplateDto.Name)
+		if !templates.isNameAvailable(w, name, &id) {
+			return
+		}
+		updateTemplateDto.Name = &name
+	}
+
+	var labelIDs []int
+	if updateTemplateDto.LabelIDs != nil {
+		labelIDs = *updateTemplateDto.LabelIDs
+	}
+
+	if !templates.referencesExist(w, updateTemplateDto.ColumnID, updateTemplateDto.AssignedTo, labelIDs) {
+		return
+	}
+
+	if updateTemplateDto.ChecklistItems != nil {
+		items := trimChecklistItems(*updateTemplateDto.ChecklistItems)
+		updateTemplateDto.ChecklistItems = &items
+	}
+
+	template, err := templates.templateRepository.Update(
+		id,
+		updateTemplateDto.Name,
+		updateTemplateDto.TitlePattern,
+		updateTemplateDto.Description,
+		updateTemplateDto.Priority,
+		updateTemplateDto.ColumnID,
+		updateTemplateDto.AssignedTo,
+		updateTemplateDto.ChecklistItems,
+		updateTemplateDto.LabelIDs,
+	)
+
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]*repository.TaskTemplate{
+		"template": template,
+	})
+}
+
+func (templates *TaskTemplates) deleteTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid template ID")
+		return
+	}
+
+	err = templates.templateRepository.Delete(id)
+
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]string{
+		"message": "Task template deleted successfully",
+	})
+}
+
+// isNameAvailable writes a 422 for an empty name and a 400 when another template uses it
+func (templates *TaskTemplates) isNameAvailable(w http.ResponseWriter, name string, excludeID *int) bool {
+	if name == "" {
+		util.Res.Writer(w).Status422().Data("Template name is required")
+		return false
+	}
+
+	nameExists, err := templates.templateRepository.NameExists(name, excludeID)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return false
+	}
+
+	if nameExists {
+		util.Res.Writer(w).Status(400).Data("Template with this name already exists")
+		return false
+	}
+
+	return true
+}
+
+// referencesExist writes a 400 unless the column, assignee and labels of a template exist
+func (templates *TaskTemplates) referencesExist(w http.ResponseWriter, columnID, assignedTo *int, labelIDs []int) bool {
+	if columnID != nil {
+		if _, err := templates.columnRepository.FindByID(*columnID); err != nil {
+			util.Res.Writer(w).Status(400).Data("Invalid column ID")
+			return false
+		}
+	}
+
+	if assignedTo != nil {
+		if _, err := templates.userRepository.FindByID(*assignedTo); err != nil {
+			util.Res.Writer(w).Status(400).Data("Invalid assigned user ID")
+			return false
+		}
+	}
+
+	exists, err := templates.labelRepository.AllExist(labelIDs)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return false
+	}
+
+	if !exists {
+		util.Res.Writer(w).Status(400).Data("Invalid label ID")
+		return false
+	}
+
+	return true
+}
+
+// trimChecklistItems drops surrounding whitespace and blank items
+func trimChecklistItems(items []string) []string {
+	trimmed := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			trimmed = append(trimmed, item)
+		}
+	}
+	return trimmed
+}
+
+func (templates *TaskTemplates) createTaskFromTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	templateID, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid template ID")
+		return
+	}
+
+	createFromTemplateDto, errors := util.ValidateRequest(r, dto.CreateTaskFromTemplateDto{})
+
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	template, err := templates.templateRepository.FindByID(templateID)
+	if err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	// Values given on use win over the template's defaults
+	columnID := template.ColumnID
+	if createFromTemplateDto.ColumnID != nil {
+		columnID = createFromTemplateDto.ColumnID
+	}
+
+	if columnID == nil {
+		util.Res.Writer(w).Status(400).Data("Template has no default column, column_id is required")
+		return
+	}
+
+	_, err = templates.columnRepository.FindByID(*columnID)
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid column ID")
+		return
+	}
+
+	assignedTo := template.AssignedTo
+	if createFromTemplateDto.AssignedTo != nil {
+		assignedTo = createFromTemplateDto.AssignedTo
+	}
+
+	if assignedTo != nil {
+		_, err = templates.userRepository.FindByID(*assignedTo)
+		if err != nil {
+			util.Res.Writer(w).Status(400).Data("Invalid assigned user ID")
+			return
+		}
+	}
+
+	if !templates.tasks.canOverrideWipLimit(w, userIdInt, createFromTemplateDto.OverrideWipLimit) {
+		return
+	}
+
+	var dueDate *time.Time
+	if createFromTemplateDto.DueDate != nil {
+		parsedDate, err := time.Parse(time.RFC3339, *createFromTemplateDto.DueDate)
+		if err != nil {
+			util.Res.Writer(w).Status(400).Data("Invalid due date format. Use ISO 8601 format")
+			return
+		}
+		dueDate = &parsedDate
+	}
+
+	task, err := templates.tasks.taskService.CreateTaskFromTemplate(
+		template,
+		createFromTemplateDto.Values,
+		*columnID,
+		userIdInt,
+		assignedTo,
+		dueDate,
+		createFromTemplateDto.OverrideWipLimit,
+	)
+
+	if writeConflictError(w, err) {
+		return
+	}
+
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	response := templates.tasks.convertToResponseDto(task)
+	util.Res.Writer(w).Status().Data(map[string]dto.TaskResponseDto{
+		"task": response,
+	})
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dev-parvej/offline_kanban/middleware"
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/service"
+	"github.com/gorilla/mux"
+)
+
+// TaskTrash serves the deleted tasks below the admin task routes
+type TaskTrash struct {
+	*Tasks
+}
+
+func TaskTrashController(router *mux.Router, db *database.Database) *TaskTrash {
+	return &TaskTrash{
+		Tasks: TaskController(router, db),
+	}
+}
+
+func (trash *TaskTrash) Router() {
+	// Trash operations (root users only)
+	adminTaskRouter := trash.router.PathPrefix("/admin/tasks").Subrouter()
+	adminTaskRouter.Use(middleware.Authenticate)
+	adminTaskRouter.Use(middleware.RequireRoot(trash.db))
+	adminTaskRouter.Use(middleware.Idempotency(trash.db))
+
+	adminTaskRouter.HandleFunc("/trash", trash.getTrash).Methods("GET")
+	adminTaskRouter.HandleFunc("/trash", trash.emptyTrash).Methods("DELETE")
+	adminTaskRouter.HandleFunc("/{id:[0-9]+}/restore", trash.restoreTask).Methods("POST")
+	adminTaskRouter.HandleFunc("/{id:[0-9]+}/purge", trash.purgeTask).Methods("DELETE")
+}
+
+// getTrash lists the tasks in the trash, most recently deleted first unless ordered otherwise
+func (trash *TaskTrash) getTrash(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userID, _ := strconv.Atoi(r.Header.Get("user_id"))
+
+	filter := parseTaskFilter(query, userID)
+
+	if err := util.ValidateStruct(filter); err != nil {
+		util.Res.Writer(w).Status(400).Data(err.Error())
+		return
+	}
+
+	repoFilters := trash.convertToRepoFilters(filter)
+	repoFilters.Trashed = true
+
+	if filter.Query != nil {
+		node, ok := parseTaskQuery(w, r, *filter.Query)
+		if !ok {
+			return
+		}
+		repoFilters.Query = node
+	}
+
+	if filter.OrderBy == nil && filter.Search == nil {
+		repoFilters.OrderBy = "deleted_at"
+		if filter.OrderDir == nil {
+			repoFilters.OrderDir = "desc"
+		}
+	}
+
+	trashedTasks, err := trash.taskRepository.GetWithRelations(repoFilters)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	totalCount, err := trash.taskRepository.CountWithFilters(repoFilters)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	taskResponses := make([]dto.TaskResponseDto, len(trashedTasks))
+	for i, task := range trashedTasks {
+		taskResponses[i] = trash.convertToResponseDto(task)
+	}
+
+	page := 1
+	pageSize := 20
+	if filter.Page != nil && *filter.Page > 0 {
+		page = *filter.Page
+	}
+	if filter.PageSize != nil && *filter.PageSize > 0 {
+		pageSize = *filter.PageSize
+	}
+
+	util.Res.Writer(w).Status().Data(dto.TaskListResponseDto{
+		Tasks:      taskResponses,
+		Total:      totalCount,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: (totalCount + pageSize - 1) / pageSize,
+	})
+}
+
+func (trash *TaskTrash) restoreTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid task ID")
+		return
+	}
+
+	restoreTaskDto, errors := util.ValidateRequest(r, dto.RestoreTaskDto{})
+	if errors != nil {
+		util.Res.Writer(w).Status422().Data(errors.Error())
+		return
+	}
+
+	if _, err := trash.taskRepository.FindTrashedByID(id); err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	task, err := trash.taskService.RestoreTask(id, restoreTaskDto.ColumnID, userIdInt)
+	if err == service.ErrRestoreColumnGone {
+		util.Res.Writer(w).Status(409).Data(err.Error())
+		return
+	}
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]dto.TaskResponseDto{
+		"task": trash.convertToResponseDto(task),
+	})
+}
+
+func (trash *TaskTrash) purgeTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid task ID")
+		return
+	}
+
+	if _, err := trash.taskRepository.FindTrashedByID(id); err != nil {
+		util.Res.Writer(w).Status(404).Data(err.Error())
+		return
+	}
+
+	userIdInt, err := strconv.Atoi(r.Header.Get("user_id"))
+	if err != nil {
+		util.Res.Writer(w).Status(400).Data("Invalid user ID")
+		return
+	}
+
+	if err := trash.taskService.PurgeTask(id, userIdInt); err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]string{
+		"message": "Task permanently deleted",
+	})
+}
+
+func (trash *TaskTrash) emptyTrash(w http.ResponseWriter, r *http.Request) {
+	purged, err := trash.taskService.PurgeTrash(0)
+	if err != nil {
+		util.Res.Writer(w).Status(500).Data(err.Error())
+		return
+	}
+
+	util.Res.Writer(w).Status().Data(map[string]int{
+		"purged": purged,
+	})
+}
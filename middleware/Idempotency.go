@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/repository"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	idempotencyKeyTTL         = 7 * 24 * time.Hour // Clients can stay offline for days before retrying
+	idempotencyPendingTimeout = 5 * time.Minute    // A request still unanswered by then has failed
+	idempotencyMaxBodySize    = 10 << 20           // 10MB, the body is read into memory to be hashed
+)
+
+// Idempotency middleware handles a mutating request sent with an Idempotency-Key header only once
+// per user. Retries get the stored response of the first request. Only successes and validation
+// errors are stored, any other response depends on state that can change, so the request can be
+// retried. Must run after Authenticate
+func Idempotency(db *database.Database) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))
+			if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > 255 {
+				util.Res.Writer(w).Status(400).Data(map[string]string{
+					"message": "Idempotency-Key must be at most 255 characters",
+				})
+				return
+			}
+
+			userId, err := strconv.Atoi(r.Header.Get("user_id"))
+			if err != nil {
+				util.Res.Writer(w).Status(400).Data(map[string]string{
+					"message": "Invalid user ID",
+				})
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, idempotencyMaxBodySize))
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					util.Res.Writer(w).Status(413).Data(map[string]string{
+						"message": "Request body is larger than " + strconv.FormatInt(maxBytesErr.Limit>>20, 10) + "MB",
+					})
+					return
+				}
+				util.Res.Writer(w).Status(400).Data(map[string]string{
+					"message": "Could not read request body",
+				})
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := sha256.New()
+			hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+			hash.Write(body)
+			requestHash := hex.EncodeToString(hash.Sum(nil))
+
+			idempotencyRepo := repository.NewIdempotencyRepository(db)
+			if err := idempotencyRepo.DeleteExpired(time.Now().Add(-idempotencyKeyTTL)); err != nil {
+				util.Res.Writer(w).Status(500).Data(err.Error())
+				return
+			}
+
+			record, reserved, err := idempotencyRepo.Reserve(userId, key, r.Method, r.URL.RequestURI(), requestHash)
+			if err != nil {
+				util.Res.Writer(w).Status(500).Data(err.Error())
+				return
+			}
+
+			// The first request was abandoned half way, let this one take over
+			if !reserved && record.StatusCode == nil && time.Since(record.CreatedAt) > idempotencyPendingTimeout && record.RequestHash == requestHash {
+				if err := idempotencyRepo.Release(record.ID); err != nil {
+					util.Res.Writer(w).Status(500).Data(err.Error())
+					return
+				}
+				record, reserved, err = idempotencyRepo.Reserve(userId, key, r.Method, r.URL.RequestURI(), requestHash)
+				if err != nil {
+					util.Res.Writer(w).Status(500).Data(err.Error())
+					return
+				}
+			}
+
+			if !reserved {
+				switch {
+				case record.RequestHash != requestHash:
+					util.Res.Writer(w).Status422().Data(map[string]string{
+						"message": "Idempotency-Key was already used for a different request",
+					})
+				case record.StatusCode == nil:
+					util.Res.Writer(w).Status(409).Data(map[string]string{
+						"message": "A request with this Idempotency-Key is still being processed",
+					})
+				default:
+					if record.ContentType != nil {
+						w.Header().Set("Content-Type", *record.ContentType)
+					}
+					w.Header().Set(IdempotentReplayedHeader, "true")
+					w.WriteHeader(*record.StatusCode)
+					w.Write(record.ResponseBody)
+				}
+				return
+			}
+
+			// A handler that panicked gave no response worth replaying
+			defer func() {
+				if p := recover(); p != nil {
+					idempotencyRepo.Release(record.ID)
+					panic(p)
+				}
+			}()
+
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			h.ServeHTTP(recorder, r)
+
+			if storesResponse(recorder.status) {
+				err = idempotencyRepo.Complete(record.ID, recorder.status, w.Header().Get("Content-Type"), recorder.body.Bytes())
+			} else {
+				err = idempotencyRepo.Release(record.ID)
+			}
+			if err != nil {
+				// The response is already sent, a retry will be handled again
+				idempotencyRepo.Release(record.ID)
+			}
+		})
+	}
+}
+
+// storesResponse reports whether a response is final, a 409, 404 or 403 can be different on a retry
+func storesResponse(status int) bool {
+	return (status >= 200 && status < 300) || status == http.StatusBadRequest || status == http.StatusUnprocessableEntity
+}
+
+// responseRecorder passes a response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if !rr.wroteHeader {
+		rr.status = status
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(data []byte) (int, error) {
+	rr.wroteHeader = true
+	rr.body.Write(data)
+	return rr.ResponseWriter.Write(data)
+}
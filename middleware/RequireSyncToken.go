@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+	"github.com/dev-parvej/offline_kanban/repository"
+)
+
+// RequireSyncToken middleware checks the token another instance sends to read and write changes
+func RequireSyncToken(db *database.Database) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			instance, err := repository.NewSyncRepository(db).GetInstance()
+			if err != nil {
+				util.Res.Writer(w).Status(500).Data(map[string]string{
+					"message": err.Error(),
+				})
+				return
+			}
+
+			// Sync is off until a root user sets a token
+			if instance.Token == nil {
+				util.Res.Writer(w).Status(403).Data(map[string]string{
+					"message": "Sync is not enabled on this instance",
+				})
+				return
+			}
+
+			token := r.Header.Get("X-Sync-Token")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(*instance.Token)) != 1 {
+				util.Res.Writer(w).Status(403).Data(map[string]string{
+					"message": "Invalid sync token",
+				})
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// TokenFromQuery lets clients that cannot set headers (such as EventSource)
+// pass the access token as a "token" query parameter
+func TokenFromQuery(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			if token := r.URL.Query().Get("token"); token != "" {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrInvalidBackup is returned when a file is not a usable copy of the app's database
+var ErrInvalidBackup = errors.New("not a valid backup of the database")
+
+// BackupDir is where backups of this database are kept
+func (d *Database) BackupDir() string {
+	return backupDir(d.path)
+}
+
+// SchemaVersion returns the latest migration applied to the database
+func (d *Database) SchemaVersion() (int, error) {
+	var version int
+	err := d.Instance().QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// BackupTo writes a consistent copy of the live database to path, which must not exist yet.
+// Reads and writes carry on while the copy is made
+func (d *Database) BackupTo(path string) error {
+	_, err := d.Instance().Exec(`VACUUM INTO ?`, path)
+	return err
+}
+
+// CheckBackup verifies that the database file at path is intact and can be migrated by this
+// version of the app, returning its schema version
+func CheckBackup(path string) (int, error) {
+	db, err := sql.Open(driverName, "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow(`PRAGMA integrity_check`).Scan(&result); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("%w: integrity check failed: %s", ErrInvalidBackup, result)
+	}
+
+	for _, table := range []string{"users", "columns", "tasks"} {
+		exists, err := tableExists(db, table)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			return 0, fmt.Errorf("%w: the %s table is missing", ErrInvalidBackup, table)
+		}
+	}
+
+	// Backups taken before migrations were versioned have no schema_migrations table
+	versioned, err := tableExists(db, "schema_migrations")
+	if err != nil {
+		return 0, err
+	}
+	if !versioned {
+		return 0, nil
+	}
+
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, err
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if latest := migrations[len(migrations)-1].version; version > latest {
+		return 0, fmt.Errorf("%w: the backup is at version %d but this build only knows up to version %d", ErrSchemaTooNew, version, latest)
+	}
+
+	return version, nil
+}
+
+// Restore replaces the database with the file at path and reopens it, migrating it when it is
+// older than this version of the app. It waits for running statements, rows being read and open
+// transactions to finish, new ones wait while the file is swapped and then run against the
+// restored database. When the restored file cannot be opened the previous database is put back.
+// The caller must not have rows or a transaction of its own open
+func (d *Database) Restore(path string) error {
+	if _, err := CheckBackup(path); err != nil {
+		return err
+	}
+
+	if d.gate == nil {
+		return errors.New("the database cannot be restored inside a transaction")
+	}
+	d.gate.close()
+	defer d.gate.open()
+
+	current := d.handle.(pool)
+	if err := current.Close(); err != nil {
+		return err
+	}
+
+	previous := d.path + ".before-restore"
+	if err := os.Rename(d.path, previous); err != nil {
+		return d.reopen(err)
+	}
+	// Journal files belong to the database being replaced
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		os.Remove(d.path + suffix)
+	}
+
+	if err := os.Rename(path, d.path); err != nil {
+		os.Rename(previous, d.path)
+		return d.reopen(err)
+	}
+
+	db, fullTextSearch, err := open(d.path)
+	if err != nil {
+		os.Rename(d.path, path)
+		os.Rename(previous, d.path)
+		return d.reopen(err)
+	}
+
+	d.handle = pool{db}
+	d.fullTextSearch = fullTextSearch
+	return os.Remove(previous)
+}
+
+// reopen connects to the current database file again after a failed restore and returns cause
+func (d *Database) reopen(cause error) error {
+	db, fullTextSearch, err := open(d.path)
+	if err != nil {
+		return fmt.Errorf("restore failed: %v, reopening the database failed: %w", cause, err)
+	}
+
+	d.handle = pool{db}
+	d.fullTextSearch = fullTextSearch
+	return fmt.Errorf("restore failed: %w", cause)
+}
@@ -6,11 +6,17 @@ import (
 	"path/filepath"
 
 	"github.com/dev-parvej/offline_kanban/config"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 type Database struct {
-	db *sql.DB
+	// gate holds Restore off while the database is used and statements off while it swaps the
+	// database file, nil for a Database inside a transaction
+	gate           *gate
+	handle         Handle
+	fullTextSearch bool
+	path           string
+	// afterCommit collects the functions to run once the transaction commits, nil outside one
+	afterCommit *[]func()
 }
 
 func InitDatabase() (*Database, error) {
@@ -22,100 +28,62 @@ func InitDatabase() (*Database, error) {
 
 	// Open database
 	dbPath := filepath.Join(dbDir, config.Get("DB_NAME"))
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return nil, err
-	}
 
-	// Create tables
-	if err := createTables(db); err != nil {
+	db, fullTextSearch, err := open(dbPath)
+	if err != nil {
 		return nil, err
 	}
 
-	return &Database{db: db}, nil
+	return &Database{gate: newGate(), handle: pool{db}, fullTextSearch: fullTextSearch, path: dbPath}, nil
 }
 
-func createTables(db *sql.DB) error {
-	// Users table and trigger
-	if _, err := db.Exec(createUsersTable); err != nil {
-		return err
-	}
-	if _, err := db.Exec(createUsersUpdateTrigger); err != nil {
-		return err
-	}
-
-	// Setup status table and trigger
-	if _, err := db.Exec(createSetupStatusTable); err != nil {
-		return err
-	}
-	if _, err := db.Exec(createSetupStatusUpdateTrigger); err != nil {
-		return err
-	}
-
-	// Columns table and trigger
-	if _, err := db.Exec(createColumnsTable); err != nil {
-		return err
-	}
-	if _, err := db.Exec(createColumnsUpdateTrigger); err != nil {
-		return err
-	}
-
-	// Tasks table and trigger
-	if _, err := db.Exec(createTasksTable); err != nil {
-		return err
-	}
-	if _, err := db.Exec(createTasksUpdateTrigger); err != nil {
-		return err
+// open connects to the database file at path and brings its schema up to date
+func open(path string) (*sql.DB, bool, error) {
+	// Transactions take the write lock up front so two of them never deadlock upgrading a read lock
+	db, err := sql.Open(driverName, path+"?_txlock=immediate")
+	if err != nil {
+		return nil, false, err
 	}
 
-	// Comments table and trigger
-	if _, err := db.Exec(createCommentsTable); err != nil {
-		return err
-	}
-	if _, err := db.Exec(createCommentsUpdateTrigger); err != nil {
-		return err
+	// Bring the schema up to date, backing the database up first when it has to change
+	if err := migrate(db, backupDir(path)); err != nil {
+		db.Close()
+		return nil, false, err
 	}
 
-	// Checklists table and trigger
-	if _, err := db.Exec(createChecklistsTable); err != nil {
-		return err
-	}
-	if _, err := db.Exec(createChecklistsUpdateTrigger); err != nil {
-		return err
+	// Record local changes for syncing with other instances
+	if err := setupSyncTriggers(db); err != nil {
+		db.Close()
+		return nil, false, err
 	}
 
-	// Refresh tokens table and trigger
-	if _, err := db.Exec(createRefreshTokensTable); err != nil {
-		return err
-	}
-	if _, err := db.Exec(createRefreshTokensUpdateTrigger); err != nil {
-		return err
+	// Full-text search index, unavailable when SQLite was built without FTS5
+	fullTextSearch, err := setupTaskSearch(db)
+	if err != nil {
+		db.Close()
+		return nil, false, err
 	}
 
-	// App settings table and trigger
-	if _, err := db.Exec(createAppSettingsTable); err != nil {
-		return err
-	}
-	if _, err := db.Exec(createAppSettingsUpdateTrigger); err != nil {
-		return err
-	}
+	return db, fullTextSearch, nil
+}
 
-	// Insert default app settings
-	if _, err := db.Exec(insertDefaultAppSettings); err != nil {
-		return err
-	}
+// backupDir is where backups of the database at path are kept
+func backupDir(path string) string {
+	return filepath.Join(filepath.Dir(path), "backups")
+}
 
-	// Activities table and trigger
-	if _, err := db.Exec(createActivitiesTable); err != nil {
-		return err
+func (d *Database) Instance() Handle {
+	if d.gate == nil {
+		return d.handle
 	}
-	if _, err := db.Exec(createActivitiesUpdateTrigger); err != nil {
-		return err
-	}
-
-	return nil
+	return guarded{d}
 }
 
-func (d *Database) Instance() *sql.DB {
-	return d.db
+// FullTextSearch reports whether the tasks_search FTS5 index is available
+func (d *Database) FullTextSearch() bool {
+	if d.gate != nil {
+		release := d.gate.enter()
+		defer release()
+	}
+	return d.fullTextSearch
 }
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// gate counts the statements, unread rows and transactions using the database. Restore closes it
+// once none are left and new ones wait until it is open again. A waiting Restore doesn't hold up
+// new statements, so a query run while the rows of another are read never deadlocks
+type gate struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active int
+	closed bool
+}
+
+func newGate() *gate {
+	g := &gate{}
+	g.cond = sync.NewCond(&g.mu)
+	return g
+}
+
+// enter waits while the gate is closed and returns the function that leaves it again, safe to call twice
+func (g *gate) enter() func() {
+	g.mu.Lock()
+	for g.closed {
+		g.cond.Wait()
+	}
+	g.active++
+	g.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			g.mu.Lock()
+			g.active--
+			if g.active == 0 {
+				g.cond.Broadcast()
+			}
+			g.mu.Unlock()
+		})
+	}
+}
+
+// close waits until nothing uses the database and keeps it that way until open
+func (g *gate) close() {
+	g.mu.Lock()
+	for g.closed || g.active > 0 {
+		g.cond.Wait()
+	}
+	g.closed = true
+	g.mu.Unlock()
+}
+
+func (g *gate) open() {
+	g.mu.Lock()
+	g.closed = false
+	g.cond.Broadcast()
+	g.mu.Unlock()
+}
+
+// guarded runs statements on the pool of a Database, keeping the gate entered until each
+// statement, the rows it returned or the transaction it began is done
+type guarded struct {
+	d *Database
+}
+
+func (g guarded) Exec(query string, args ...interface{}) (sql.Result, error) {
+	release := g.d.gate.enter()
+	defer release()
+	return g.d.handle.Exec(query, args...)
+}
+
+func (g guarded) Query(query string, args ...interface{}) (*Rows, error) {
+	release := g.d.gate.enter()
+	rows, err := g.d.handle.Query(query, args...)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	rows.release = release
+	return rows, nil
+}
+
+func (g guarded) QueryRow(query string, args ...interface{}) *Row {
+	rows, err := g.Query(query, args...)
+	return &Row{rows: rows, err: err}
+}
+
+func (g guarded) Begin() (Tx, error) {
+	release := g.d.gate.enter()
+	tx, err := g.d.handle.Begin()
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &guardedTx{Tx: tx, release: release}, nil
+}
+
+type guardedTx struct {
+	Tx
+	release func()
+}
+
+func (t *guardedTx) Commit() error {
+	defer t.release()
+	return t.Tx.Commit()
+}
+
+func (t *guardedTx) Rollback() error {
+	defer t.release()
+	return t.Tx.Rollback()
+}
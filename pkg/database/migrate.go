@@ -0,0 +1,236 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations are numbered SQL files, NNNN_description.sql, applied in order. A migration that has
+// been released must never change, schema changes always go into a new file
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer version of the app
+var ErrSchemaTooNew = errors.New("database schema is newer than this version of the app")
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+const createSchemaMigrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+
+// Columns added to existing tables before migrations were versioned. A database created back then
+// may lack any of them, so they are added before the initial schema runs over it
+var legacyColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"columns", "board_id", "INTEGER NULL REFERENCES boards(id) ON DELETE CASCADE"},
+	{"columns", "wip_limit", "INTEGER NULL"},
+	{"columns", "is_done", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"columns", "auto_archive_days", "INTEGER NULL"},
+	{"tasks", "parent_id", "INTEGER NULL REFERENCES tasks(id) ON DELETE SET NULL"},
+	{"tasks", "deleted_at", "DATETIME NULL"},
+	{"tasks", "deleted_by", "INTEGER NULL REFERENCES users(id) ON DELETE SET NULL"},
+	{"tasks", "archived_at", "DATETIME NULL"},
+	{"tasks", "moved_at", "DATETIME NULL"},
+	{"app_settings", "enforce_dependencies", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"app_settings", "trash_retention_days", "INTEGER NOT NULL DEFAULT 30"},
+	{"app_settings", "auto_archive_days", "INTEGER NOT NULL DEFAULT 0"},
+	{"activities", "old_ref", "INTEGER NULL"},
+	{"activities", "new_ref", "INTEGER NULL"},
+}
+
+// loadMigrations reads the embedded migrations sorted by version
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		number, _, _ := strings.Cut(name, "_")
+
+		version, err := strconv.Atoi(number)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %s has no version number", entry.Name())
+		}
+
+		content, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{version: version, name: name, sql: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version == migrations[i-1].version {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", migrations[i-1].name, migrations[i].name, migrations[i].version)
+		}
+	}
+
+	return migrations, nil
+}
+
+// migrate brings the schema up to the latest migration. Each migration runs in its own transaction
+// together with its schema_migrations row, so a failed migration leaves the database at the previous
+// version. An existing database is copied to backupDir before anything is applied
+func migrate(db *sql.DB, backupDir string) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	latest := migrations[len(migrations)-1].version
+
+	// Tables created before schema_migrations existed mean an unversioned database
+	legacy, err := tableExists(db, "users")
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(createSchemaMigrationsTable); err != nil {
+		return err
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	if current > latest {
+		return fmt.Errorf("%w: the database is at version %d but this build only knows up to version %d, update the app", ErrSchemaTooNew, current, latest)
+	}
+	if current == latest {
+		return nil
+	}
+
+	if legacy || current > 0 {
+		if err := backupBeforeMigration(db, backupDir, current); err != nil {
+			return fmt.Errorf("backup before migrating: %w", err)
+		}
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		err := applyMigration(db, m, func(tx *sql.Tx) error {
+			if m.version == 1 && legacy {
+				return addLegacyColumns(tx)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(db *sql.DB, m migration, before func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := before(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(m.sql); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// backupBeforeMigration writes a consistent copy of the database next to it, named after the
+// version it was at, so a failed or unwanted upgrade can be rolled back by hand
+func backupBeforeMigration(db *sql.DB, backupDir string, version int) error {
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return err
+	}
+
+	path := filepath.Join(backupDir, fmt.Sprintf("pre-migration-v%d-%s.db", version, time.Now().UTC().Format("20060102-150405")))
+	_, err := db.Exec(`VACUUM INTO ?`, path)
+	return err
+}
+
+// addLegacyColumns adds the columns an unversioned database is missing, skipping tables it never had
+func addLegacyColumns(tx *sql.Tx) error {
+	for _, c := range legacyColumns {
+		columns, err := tableColumns(tx, c.table)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 || columns[c.column] {
+			continue
+		}
+
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tableExists(db *sql.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&count)
+	return count > 0, err
+}
+
+// tableColumns returns the column names of a table, empty when the table does not exist
+func tableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]bool{}
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,457 @@
+-- Schema as it stood when versioned migrations were introduced. Databases created before then
+-- already hold part of it, so every statement here must be safe to run over an existing schema
+
+-- Users table with all columns included
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT UNIQUE NOT NULL,
+	password TEXT NOT NULL,
+	name TEXT,
+	designation TEXT,
+	is_root BOOLEAN NOT NULL DEFAULT 0,
+	is_active BOOLEAN NOT NULL DEFAULT 1,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS update_users_updated_at;
+CREATE TRIGGER update_users_updated_at
+AFTER UPDATE ON users
+FOR EACH ROW
+BEGIN
+	UPDATE users
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- Setup status table
+CREATE TABLE IF NOT EXISTS setup_status (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	is_complete BOOLEAN NOT NULL DEFAULT 0,
+	completed_at DATETIME,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS update_setup_status_updated_at;
+CREATE TRIGGER update_setup_status_updated_at
+AFTER UPDATE ON setup_status
+FOR EACH ROW
+BEGIN
+	UPDATE setup_status
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- Boards table
+CREATE TABLE IF NOT EXISTS boards (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title VARCHAR NOT NULL,
+	description TEXT,
+	created_by INTEGER NULL,
+	position INTEGER DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+DROP TRIGGER IF EXISTS update_boards_updated_at;
+CREATE TRIGGER update_boards_updated_at
+AFTER UPDATE ON boards
+FOR EACH ROW
+BEGIN
+	UPDATE boards
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- Columns table with all columns included
+CREATE TABLE IF NOT EXISTS columns (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title VARCHAR NOT NULL,
+	board_id INTEGER NULL,
+	created_by INTEGER NOT NULL,
+	colors VARCHAR NULL,
+	wip_limit INTEGER NULL,
+	is_done BOOLEAN NOT NULL DEFAULT 0,
+	auto_archive_days INTEGER NULL,
+	position INTEGER DEFAULT 0,
+	deleted_at DATETIME NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (board_id) REFERENCES boards(id) ON DELETE CASCADE,
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+DROP TRIGGER IF EXISTS update_columns_updated_at;
+CREATE TRIGGER update_columns_updated_at
+AFTER UPDATE ON columns
+FOR EACH ROW
+BEGIN
+	UPDATE columns
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- Moves columns created before boards existed into a "Default" board
+INSERT INTO boards (title, description, created_by, position)
+SELECT 'Default', 'Default board', (SELECT id FROM users WHERE is_root = 1 ORDER BY id LIMIT 1), 1
+WHERE NOT EXISTS (SELECT 1 FROM boards);
+
+UPDATE columns
+SET board_id = (SELECT id FROM boards ORDER BY id LIMIT 1)
+WHERE board_id IS NULL;
+
+-- Tasks table
+CREATE TABLE IF NOT EXISTS tasks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title TEXT NOT NULL,
+	description TEXT,
+	column_id INTEGER NOT NULL,
+	assigned_to INTEGER,
+	created_by INTEGER NOT NULL,
+	due_date DATETIME,
+	priority VARCHAR NULL,
+	position INTEGER NOT NULL,
+	weight INTEGER DEFAULT 0,
+	parent_id INTEGER NULL,
+	deleted_at DATETIME NULL,
+	deleted_by INTEGER NULL,
+	archived_at DATETIME NULL,
+	moved_at DATETIME NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (column_id) REFERENCES columns(id) ON DELETE SET NULL,
+	FOREIGN KEY (parent_id) REFERENCES tasks(id) ON DELETE SET NULL,
+	FOREIGN KEY (assigned_to) REFERENCES users(id) ON DELETE SET NULL,
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+	FOREIGN KEY (deleted_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Subtasks are looked up by their parent
+CREATE INDEX IF NOT EXISTS idx_tasks_parent ON tasks(parent_id);
+
+-- Trashed tasks have a deleted_at and are purged once it is older than the retention
+CREATE INDEX IF NOT EXISTS idx_tasks_deleted ON tasks(deleted_at);
+
+DROP TRIGGER IF EXISTS update_tasks_updated_at;
+CREATE TRIGGER update_tasks_updated_at
+AFTER UPDATE ON tasks
+FOR EACH ROW
+BEGIN
+	UPDATE tasks
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- Comments table
+CREATE TABLE IF NOT EXISTS comments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	content TEXT NOT NULL,
+	task_id INTEGER NOT NULL,
+	created_by INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+DROP TRIGGER IF EXISTS update_comments_updated_at;
+CREATE TRIGGER update_comments_updated_at
+AFTER UPDATE ON comments
+FOR EACH ROW
+BEGIN
+	UPDATE comments
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- Checklists table
+CREATE TABLE IF NOT EXISTS checklists (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title TEXT NOT NULL,
+	created_by INTEGER NOT NULL,
+	completed_by INTEGER,
+	task_id INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+	FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE SET NULL,
+	FOREIGN KEY (completed_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+DROP TRIGGER IF EXISTS update_checklist_updated_at;
+CREATE TRIGGER update_checklist_updated_at
+AFTER UPDATE ON checklists
+FOR EACH ROW
+BEGIN
+	UPDATE checklists
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- Refresh tokens table
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	token TEXT UNIQUE NOT NULL,
+	user_id INTEGER NOT NULL,
+	expires_at DATETIME NOT NULL,
+	is_revoked BOOLEAN NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+DROP TRIGGER IF EXISTS update_refresh_tokens_updated_at;
+CREATE TRIGGER update_refresh_tokens_updated_at
+AFTER UPDATE ON refresh_tokens
+FOR EACH ROW
+BEGIN
+	UPDATE refresh_tokens
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- App settings table
+CREATE TABLE IF NOT EXISTS app_settings (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	app_name VARCHAR(50) NOT NULL DEFAULT 'Offline Kanban',
+	app_description TEXT,
+	default_theme VARCHAR(10) NOT NULL DEFAULT 'system' CHECK (default_theme IN ('light', 'dark', 'system')),
+	enable_notifications BOOLEAN NOT NULL DEFAULT 1,
+	enforce_dependencies BOOLEAN NOT NULL DEFAULT 0,
+	trash_retention_days INTEGER NOT NULL DEFAULT 30,
+	auto_archive_days INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS update_app_settings_updated_at;
+CREATE TRIGGER update_app_settings_updated_at
+AFTER UPDATE ON app_settings
+FOR EACH ROW
+BEGIN
+	UPDATE app_settings
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+INSERT OR IGNORE INTO app_settings (id, app_name, app_description, default_theme, enable_notifications)
+VALUES (1, 'Offline Kanban', 'A powerful offline-first Kanban board application', 'system', 1);
+
+-- Activities table
+CREATE TABLE IF NOT EXISTS activities (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	entity_type TEXT NOT NULL,
+	entity_id INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	field_name TEXT,
+	old_value TEXT,
+	new_value TEXT,
+	old_ref INTEGER NULL,
+	new_ref INTEGER NULL,
+	user_id INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+DROP TRIGGER IF EXISTS update_activities_updated_at;
+CREATE TRIGGER update_activities_updated_at
+AFTER UPDATE ON activities
+FOR EACH ROW
+BEGIN
+	UPDATE activities
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- Notifications table
+CREATE TABLE IF NOT EXISTS notifications (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	recipient_id INTEGER NOT NULL,
+	sender_id INTEGER NULL,
+	type TEXT NOT NULL,
+	title TEXT NOT NULL,
+	message TEXT NOT NULL,
+	task_id INTEGER NULL,
+	comment_id INTEGER NULL,
+	data TEXT NULL,
+	is_read BOOLEAN NOT NULL DEFAULT 0,
+	is_system BOOLEAN NOT NULL DEFAULT 0,
+	read_at DATETIME NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (recipient_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE SET NULL,
+	FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+	FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_recipient ON notifications(recipient_id, is_read);
+
+DROP TRIGGER IF EXISTS update_notifications_updated_at;
+CREATE TRIGGER update_notifications_updated_at
+AFTER UPDATE ON notifications
+FOR EACH ROW
+BEGIN
+	UPDATE notifications
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- Mentions table (one row per @username resolved in a description or comment)
+CREATE TABLE IF NOT EXISTS mentions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id INTEGER NOT NULL,
+	comment_id INTEGER NULL,
+	mentioned_user_id INTEGER NOT NULL,
+	mentioned_by INTEGER NOT NULL,
+	source TEXT NOT NULL CHECK (source IN ('description', 'comment')),
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+	FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+	FOREIGN KEY (mentioned_user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (mentioned_by) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_mentions_user ON mentions(mentioned_user_id, task_id);
+
+DROP TRIGGER IF EXISTS update_mentions_updated_at;
+CREATE TRIGGER update_mentions_updated_at
+AFTER UPDATE ON mentions
+FOR EACH ROW
+BEGIN
+	UPDATE mentions
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- Labels table
+CREATE TABLE IF NOT EXISTS labels (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+	color TEXT NOT NULL DEFAULT '#6b7280',
+	created_by INTEGER NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+DROP TRIGGER IF EXISTS update_labels_updated_at;
+CREATE TRIGGER update_labels_updated_at
+AFTER UPDATE ON labels
+FOR EACH ROW
+BEGIN
+	UPDATE labels
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- Task labels join table
+CREATE TABLE IF NOT EXISTS task_labels (
+	task_id INTEGER NOT NULL,
+	label_id INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (task_id, label_id),
+	FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+	FOREIGN KEY (label_id) REFERENCES labels(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_task_labels_label ON task_labels(label_id);
+
+-- Task dependencies, task_id cannot start until blocked_by_task_id is done
+CREATE TABLE IF NOT EXISTS task_dependencies (
+	task_id INTEGER NOT NULL,
+	blocked_by_task_id INTEGER NOT NULL,
+	created_by INTEGER NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (task_id, blocked_by_task_id),
+	CHECK (task_id != blocked_by_task_id),
+	FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+	FOREIGN KEY (blocked_by_task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_task_dependencies_blocked_by ON task_dependencies(blocked_by_task_id);
+
+-- Recurrence rules, the task is the template cloned into column_id on every run
+CREATE TABLE IF NOT EXISTS task_recurrences (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id INTEGER NOT NULL UNIQUE,
+	column_id INTEGER NOT NULL,
+	frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly', 'cron')),
+	interval INTEGER NOT NULL DEFAULT 1,
+	cron_expr VARCHAR(100) NULL,
+	catch_up BOOLEAN NOT NULL DEFAULT 1,
+	is_active BOOLEAN NOT NULL DEFAULT 1,
+	starts_at DATETIME NOT NULL,
+	next_run_at DATETIME NULL,
+	last_run_at DATETIME NULL,
+	created_by INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+	FOREIGN KEY (column_id) REFERENCES columns(id) ON DELETE CASCADE,
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+DROP TRIGGER IF EXISTS update_task_recurrences_updated_at;
+CREATE TRIGGER update_task_recurrences_updated_at
+AFTER UPDATE ON task_recurrences
+FOR EACH ROW
+BEGIN
+	UPDATE task_recurrences
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- Task templates, checklist_items and label_ids are JSON arrays
+CREATE TABLE IF NOT EXISTS task_templates (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(100) NOT NULL UNIQUE COLLATE NOCASE,
+	title_pattern TEXT NOT NULL,
+	description TEXT NULL,
+	priority VARCHAR NULL,
+	column_id INTEGER NULL,
+	assigned_to INTEGER NULL,
+	checklist_items TEXT NOT NULL DEFAULT '[]',
+	label_ids TEXT NOT NULL DEFAULT '[]',
+	use_count INTEGER NOT NULL DEFAULT 0,
+	created_by INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (column_id) REFERENCES columns(id) ON DELETE SET NULL,
+	FOREIGN KEY (assigned_to) REFERENCES users(id) ON DELETE SET NULL,
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+DROP TRIGGER IF EXISTS update_task_templates_updated_at;
+CREATE TRIGGER update_task_templates_updated_at
+AFTER UPDATE ON task_templates
+FOR EACH ROW
+BEGIN
+	UPDATE task_templates
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- Saved task list filters, filter holds the task list query parameters as a JSON object
+CREATE TABLE IF NOT EXISTS saved_filters (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(100) NOT NULL,
+	filter TEXT NOT NULL DEFAULT '{}',
+	is_shared BOOLEAN NOT NULL DEFAULT 0,
+	created_by INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (created_by, name COLLATE NOCASE),
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_saved_filters_shared ON saved_filters(is_shared);
+
+DROP TRIGGER IF EXISTS update_saved_filters_updated_at;
+CREATE TRIGGER update_saved_filters_updated_at
+AFTER UPDATE ON saved_filters
+FOR EACH ROW
+BEGIN
+	UPDATE saved_filters
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
@@ -0,0 +1,20 @@
+-- Scheduled backups, backup_directory NULL writes them next to the database
+ALTER TABLE app_settings ADD COLUMN backup_enabled BOOLEAN NOT NULL DEFAULT 1;
+ALTER TABLE app_settings ADD COLUMN backup_interval_hours INTEGER NOT NULL DEFAULT 24;
+ALTER TABLE app_settings ADD COLUMN backup_directory TEXT NULL;
+ALTER TABLE app_settings ADD COLUMN backup_keep_daily INTEGER NOT NULL DEFAULT 7;
+ALTER TABLE app_settings ADD COLUMN backup_keep_weekly INTEGER NOT NULL DEFAULT 4;
+
+-- One row per scheduled backup, successful or not
+CREATE TABLE IF NOT EXISTS backup_runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	status TEXT NOT NULL CHECK (status IN ('success', 'failed')),
+	directory TEXT NOT NULL,
+	file_name TEXT NULL,
+	size INTEGER NULL,
+	pruned INTEGER NOT NULL DEFAULT 0,
+	error TEXT NULL,
+	started_at DATETIME NOT NULL,
+	finished_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_backup_runs_started ON backup_runs(started_at);
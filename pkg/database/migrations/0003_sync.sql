@@ -0,0 +1,106 @@
+-- This instance, its Lamport clock and the sequence numbering local changes. applying is set while
+-- changes from another instance are written, so the sync triggers don't record them as local edits
+CREATE TABLE IF NOT EXISTS sync_instance (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	instance_id TEXT NOT NULL,
+	clock INTEGER NOT NULL DEFAULT 0,
+	seq INTEGER NOT NULL DEFAULT 0,
+	applying BOOLEAN NOT NULL DEFAULT 0,
+	token TEXT NULL
+);
+
+INSERT OR IGNORE INTO sync_instance (id, instance_id, clock, seq)
+VALUES (1, lower(hex(randomblob(16))), 1, 1);
+
+-- Global ID and version of every synced row, deleted rows stay as tombstones. seq orders the rows
+-- for other instances reading the changes, local_seq is the last edit made on this instance
+-- and 0 for rows only ever changed elsewhere
+CREATE TABLE IF NOT EXISTS sync_rows (
+	entity TEXT NOT NULL,
+	local_id INTEGER NOT NULL,
+	global_id TEXT NOT NULL,
+	clock INTEGER NOT NULL,
+	origin TEXT NOT NULL,
+	seq INTEGER NOT NULL,
+	local_seq INTEGER NOT NULL DEFAULT 0,
+	deleted BOOLEAN NOT NULL DEFAULT 0,
+	PRIMARY KEY (entity, global_id),
+	UNIQUE (entity, local_id)
+);
+CREATE INDEX IF NOT EXISTS idx_sync_rows_seq ON sync_rows(seq);
+
+-- Version of every field of entities merged field by field
+CREATE TABLE IF NOT EXISTS sync_fields (
+	entity TEXT NOT NULL,
+	global_id TEXT NOT NULL,
+	field TEXT NOT NULL,
+	clock INTEGER NOT NULL,
+	origin TEXT NOT NULL,
+	local_seq INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (entity, global_id, field)
+);
+
+-- Instances this one syncs with, pulled_seq and pushed_seq are how far each direction got
+CREATE TABLE IF NOT EXISTS sync_peers (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(100) NOT NULL,
+	url TEXT NOT NULL,
+	token TEXT NOT NULL,
+	remote_instance_id TEXT NULL,
+	pulled_seq INTEGER NOT NULL DEFAULT 0,
+	pushed_seq INTEGER NOT NULL DEFAULT 0,
+	last_synced_at DATETIME NULL,
+	last_error TEXT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS update_sync_peers_updated_at;
+CREATE TRIGGER update_sync_peers_updated_at
+AFTER UPDATE ON sync_peers
+FOR EACH ROW
+BEGIN
+	UPDATE sync_peers
+	SET updated_at = CURRENT_TIMESTAMP
+	WHERE id = OLD.id;
+END;
+
+-- Changes from another instance that could not be merged cleanly, values are JSON
+CREATE TABLE IF NOT EXISTS sync_conflicts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	remote_instance_id TEXT NOT NULL,
+	entity TEXT NOT NULL,
+	global_id TEXT NOT NULL,
+	local_id INTEGER NULL,
+	field TEXT NULL,
+	kind TEXT NOT NULL,
+	message TEXT NOT NULL,
+	local_value TEXT NULL,
+	remote_value TEXT NULL,
+	resolution TEXT NOT NULL,
+	is_resolved BOOLEAN NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_sync_conflicts_resolved ON sync_conflicts(is_resolved);
+
+-- Existing rows get their global IDs, all at the first version of this instance. The board every
+-- instance starts with shares its ID, so two new instances don't end up with two "Default" boards
+INSERT OR IGNORE INTO sync_rows (entity, local_id, global_id, clock, origin, seq, local_seq)
+SELECT 'board', id,
+       CASE WHEN id = (SELECT MIN(id) FROM boards) AND title = 'Default' AND description = 'Default board'
+            THEN 'default-board' ELSE lower(hex(randomblob(16))) END,
+       1, (SELECT instance_id FROM sync_instance), 1, 1
+FROM boards;
+INSERT OR IGNORE INTO sync_rows (entity, local_id, global_id, clock, origin, seq, local_seq)
+SELECT 'column', id, lower(hex(randomblob(16))), 1, (SELECT instance_id FROM sync_instance), 1, 1 FROM columns;
+INSERT OR IGNORE INTO sync_rows (entity, local_id, global_id, clock, origin, seq, local_seq)
+SELECT 'task', id, lower(hex(randomblob(16))), 1, (SELECT instance_id FROM sync_instance), 1, 1 FROM tasks;
+INSERT OR IGNORE INTO sync_rows (entity, local_id, global_id, clock, origin, seq, local_seq)
+SELECT 'comment', id, lower(hex(randomblob(16))), 1, (SELECT instance_id FROM sync_instance), 1, 1 FROM comments;
+INSERT OR IGNORE INTO sync_rows (entity, local_id, global_id, clock, origin, seq, local_seq)
+SELECT 'checklist', id, lower(hex(randomblob(16))), 1, (SELECT instance_id FROM sync_instance), 1, 1 FROM checklists;
+
+INSERT OR IGNORE INTO sync_fields (entity, global_id, field, clock, origin, local_seq)
+SELECT 'task', r.global_id, f.value, r.clock, r.origin, r.local_seq
+FROM sync_rows r, json_each('["title","description","column","assigned_to","created_by","due_date","priority","position","weight","parent","deleted_at","deleted_by","archived_at","moved_at"]') f
+WHERE r.entity = 'task';
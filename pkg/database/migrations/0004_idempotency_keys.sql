@@ -0,0 +1,16 @@
+-- Responses to requests sent with an Idempotency-Key header, replayed when a client retries the
+-- request. status_code is NULL while the first request is still being handled
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	idempotency_key VARCHAR(255) NOT NULL,
+	method VARCHAR(10) NOT NULL,
+	path TEXT NOT NULL,
+	request_hash TEXT NOT NULL,
+	status_code INTEGER NULL,
+	content_type TEXT NULL,
+	response_body BLOB NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, idempotency_key)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(created_at);
@@ -0,0 +1,58 @@
+package database
+
+import "database/sql"
+
+// Rows are the results of a query. A Restore waits until they are read to the end or closed
+type Rows struct {
+	*sql.Rows
+	release func()
+}
+
+func (r *Rows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.done()
+	return false
+}
+
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	r.done()
+	return err
+}
+
+func (r *Rows) done() {
+	if r.release != nil {
+		r.release()
+	}
+}
+
+// Row is the result of a query for a single row, it behaves like sql.Row
+type Row struct {
+	rows *Rows
+	err  error
+}
+
+// Scan copies the first row into dest, sql.ErrNoRows when there is none
+func (r *Row) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	return r.rows.Close()
+}
+
+func (r *Row) Err() error {
+	return r.err
+}
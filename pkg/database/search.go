@@ -0,0 +1,93 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/dev-parvej/offline_kanban/pkg/fulltext"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Connections opened with this driver can run strip_html(), which the search triggers rely on
+const driverName = "sqlite3_kanban"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("strip_html", fulltext.StripHTML, true)
+		},
+	})
+}
+
+// Triggers keeping tasks_search in sync, one per write that changes searchable text
+var taskSearchTriggers = []struct {
+	name   string
+	event  string
+	taskID string
+}{
+	{"tasks_search_task_insert", "AFTER INSERT ON tasks", "NEW.id"},
+	{"tasks_search_task_update", "AFTER UPDATE OF title, description ON tasks", "NEW.id"},
+	{"tasks_search_comment_insert", "AFTER INSERT ON comments", "NEW.task_id"},
+	{"tasks_search_comment_update", "AFTER UPDATE OF content ON comments", "NEW.task_id"},
+	{"tasks_search_comment_delete", "AFTER DELETE ON comments", "OLD.task_id"},
+	{"tasks_search_checklist_insert", "AFTER INSERT ON checklists", "NEW.task_id"},
+	{"tasks_search_checklist_update", "AFTER UPDATE OF title ON checklists", "NEW.task_id"},
+	{"tasks_search_checklist_delete", "AFTER DELETE ON checklists", "OLD.task_id"},
+}
+
+// setupTaskSearch creates the FTS5 index with its triggers and fills it for tasks written before
+// it existed. It reports false when SQLite was built without FTS5 (the sqlite_fts5 build tag),
+// in which case the triggers are removed and search falls back to LIKE
+func setupTaskSearch(db *sql.DB) (bool, error) {
+	var available bool
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&available); err != nil {
+		return false, err
+	}
+
+	names := []string{"tasks_search_task_delete"}
+	for _, trigger := range taskSearchTriggers {
+		names = append(names, trigger.name)
+	}
+
+	if !available {
+		for _, name := range names {
+			if _, err := db.Exec("DROP TRIGGER IF EXISTS " + name); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	if _, err := db.Exec(createTasksSearchTable); err != nil {
+		return false, err
+	}
+	if _, err := db.Exec(createTasksSearchDeleteTrigger); err != nil {
+		return false, err
+	}
+
+	for _, trigger := range taskSearchTriggers {
+		statement := "DROP TRIGGER IF EXISTS " + trigger.name + ";\n" +
+			"CREATE TRIGGER " + trigger.name + " " + trigger.event + " FOR EACH ROW BEGIN" +
+			strings.ReplaceAll(reindexTaskSearch, "{task_id}", trigger.taskID) + " END;"
+		if _, err := db.Exec(statement); err != nil {
+			return false, err
+		}
+	}
+
+	// Index tasks written by an older version or while FTS5 was unavailable
+	var indexed, total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM tasks_search`).Scan(&indexed); err != nil {
+		return false, err
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM tasks`).Scan(&total); err != nil {
+		return false, err
+	}
+
+	if indexed != total {
+		if _, err := db.Exec(reindexAllTaskSearch); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
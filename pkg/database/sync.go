@@ -0,0 +1,168 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// SyncField is a column whose value is sent to other instances
+type SyncField struct {
+	Name   string // Name in change documents and sync_fields
+	Column string
+	Ref    string // Entity the column points to, sent as its global ID, or "user" for users matched by username
+
+	// A row whose required reference can't be found is not written, other unknown references are cleared
+	Required bool
+}
+
+// SyncEntity is a table whose rows are synced between instances. Rows of entities with FieldMerge
+// keep a version per field, so edits to different fields on two instances are both kept, other
+// rows are last-writer-wins as a whole
+type SyncEntity struct {
+	Name       string
+	Table      string
+	FieldMerge bool
+	Fields     []SyncField
+}
+
+// SyncEntities lists the synced tables, an entity only refers to entities listed before it
+var SyncEntities = []SyncEntity{
+	{Name: "board", Table: "boards", Fields: []SyncField{
+		{Name: "title", Column: "title"},
+		{Name: "description", Column: "description"},
+		{Name: "position", Column: "position"},
+		{Name: "created_by", Column: "created_by", Ref: "user", Required: true},
+	}},
+	{Name: "column", Table: "columns", Fields: []SyncField{
+		{Name: "board", Column: "board_id", Ref: "board"},
+		{Name: "title", Column: "title"},
+		{Name: "colors", Column: "colors"},
+		{Name: "wip_limit", Column: "wip_limit"},
+		{Name: "is_done", Column: "is_done"},
+		{Name: "auto_archive_days", Column: "auto_archive_days"},
+		{Name: "position", Column: "position"},
+		{Name: "deleted_at", Column: "deleted_at"},
+		{Name: "created_by", Column: "created_by", Ref: "user", Required: true},
+	}},
+	{Name: "task", Table: "tasks", FieldMerge: true, Fields: []SyncField{
+		{Name: "title", Column: "title"},
+		{Name: "description", Column: "description"},
+		{Name: "column", Column: "column_id", Ref: "column", Required: true},
+		{Name: "assigned_to", Column: "assigned_to", Ref: "user"},
+		{Name: "created_by", Column: "created_by", Ref: "user", Required: true},
+		{Name: "due_date", Column: "due_date"},
+		{Name: "priority", Column: "priority"},
+		{Name: "position", Column: "position"},
+		{Name: "weight", Column: "weight"},
+		{Name: "parent", Column: "parent_id", Ref: "task"},
+		{Name: "deleted_at", Column: "deleted_at"},
+		{Name: "deleted_by", Column: "deleted_by", Ref: "user"},
+		{Name: "archived_at", Column: "archived_at"},
+		{Name: "moved_at", Column: "moved_at"},
+	}},
+	{Name: "comment", Table: "comments", Fields: []SyncField{
+		{Name: "task", Column: "task_id", Ref: "task", Required: true},
+		{Name: "content", Column: "content"},
+		{Name: "created_by", Column: "created_by", Ref: "user", Required: true},
+	}},
+	{Name: "checklist", Table: "checklists", Fields: []SyncField{
+		{Name: "task", Column: "task_id", Ref: "task", Required: true},
+		{Name: "title", Column: "title"},
+		{Name: "created_by", Column: "created_by", Ref: "user", Required: true},
+		{Name: "completed_by", Column: "completed_by", Ref: "user"},
+	}},
+}
+
+// FindSyncEntity returns the synced entity with the given name
+func FindSyncEntity(name string) (*SyncEntity, bool) {
+	for i := range SyncEntities {
+		if SyncEntities[i].Name == name {
+			return &SyncEntities[i], true
+		}
+	}
+	return nil, false
+}
+
+// Statements shared by the sync triggers, they stay quiet while changes from another instance are applied
+const (
+	syncTriggerGuard = `(SELECT applying FROM sync_instance WHERE id = 1) = 0`
+	syncNextVersion  = `UPDATE sync_instance SET clock = clock + 1, seq = seq + 1 WHERE id = 1;`
+	syncClock        = `(SELECT clock FROM sync_instance WHERE id = 1)`
+	syncOrigin       = `(SELECT instance_id FROM sync_instance WHERE id = 1)`
+	syncSeq          = `(SELECT seq FROM sync_instance WHERE id = 1)`
+)
+
+// setupSyncTriggers (re)creates the triggers recording every local insert, update and delete of a
+// synced row in sync_rows, and of a synced field in sync_fields, with a new version and sequence number
+func setupSyncTriggers(db *sql.DB) error {
+	for _, entity := range SyncEntities {
+		for _, statement := range syncTriggers(entity) {
+			if _, err := db.Exec(statement); err != nil {
+				return fmt.Errorf("sync triggers for %s: %w", entity.Table, err)
+			}
+		}
+	}
+	return nil
+}
+
+func syncTriggers(entity SyncEntity) []string {
+	name := "sync_" + entity.Table
+	row := fmt.Sprintf(`entity = '%s' AND local_id = %%s.id`, entity.Name)
+
+	insert := fmt.Sprintf(`
+		INSERT INTO sync_rows (entity, local_id, global_id, clock, origin, seq, local_seq)
+		SELECT '%s', NEW.id, lower(hex(randomblob(16))), clock, instance_id, seq, seq FROM sync_instance WHERE id = 1;`, entity.Name)
+
+	var changed []string
+	for _, field := range entity.Fields {
+		changed = append(changed, fmt.Sprintf("OLD.%s IS NOT NEW.%s", field.Column, field.Column))
+	}
+
+	update := fmt.Sprintf(`
+		UPDATE sync_rows
+		SET clock = %s, origin = %s, seq = %s, local_seq = %[3]s
+		WHERE %s;`, syncClock, syncOrigin, syncSeq, fmt.Sprintf(row, "NEW"))
+
+	if entity.FieldMerge {
+		insert += fmt.Sprintf(`
+		INSERT INTO sync_fields (entity, global_id, field, clock, origin, local_seq)
+		SELECT '%s', r.global_id, f.value, r.clock, r.origin, r.local_seq
+		FROM sync_rows r, json_each('%s') f
+		WHERE r.entity = '%s' AND r.local_id = NEW.id;`, entity.Name, fieldNamesJSON(entity), entity.Name)
+
+		for _, field := range entity.Fields {
+			update += fmt.Sprintf(`
+		UPDATE sync_fields
+		SET clock = %s, origin = %s, local_seq = %s
+		WHERE entity = '%s' AND field = '%s' AND OLD.%s IS NOT NEW.%s
+		  AND global_id = (SELECT global_id FROM sync_rows WHERE %s);`,
+				syncClock, syncOrigin, syncSeq, entity.Name, field.Name, field.Column, field.Column, fmt.Sprintf(row, "NEW"))
+		}
+	}
+
+	remove := fmt.Sprintf(`
+		UPDATE sync_rows
+		SET deleted = 1, clock = %s, origin = %s, seq = %s, local_seq = %[3]s
+		WHERE %s;`, syncClock, syncOrigin, syncSeq, fmt.Sprintf(row, "OLD"))
+
+	return []string{
+		fmt.Sprintf(`DROP TRIGGER IF EXISTS %[1]s_insert;
+		CREATE TRIGGER %[1]s_insert AFTER INSERT ON %[2]s FOR EACH ROW WHEN %[3]s
+		BEGIN %[4]s %[5]s END;`, name, entity.Table, syncTriggerGuard, syncNextVersion, insert),
+		fmt.Sprintf(`DROP TRIGGER IF EXISTS %[1]s_update;
+		CREATE TRIGGER %[1]s_update AFTER UPDATE ON %[2]s FOR EACH ROW WHEN %[3]s AND (%[4]s)
+		BEGIN %[5]s %[6]s END;`, name, entity.Table, syncTriggerGuard, strings.Join(changed, " OR "), syncNextVersion, update),
+		fmt.Sprintf(`DROP TRIGGER IF EXISTS %[1]s_delete;
+		CREATE TRIGGER %[1]s_delete AFTER DELETE ON %[2]s FOR EACH ROW WHEN %[3]s
+		BEGIN %[4]s %[5]s END;`, name, entity.Table, syncTriggerGuard, syncNextVersion, remove),
+	}
+}
+
+func fieldNamesJSON(entity SyncEntity) string {
+	names := make([]string, len(entity.Fields))
+	for i, field := range entity.Fields {
+		names[i] = `"` + field.Name + `"`
+	}
+	return "[" + strings.Join(names, ",") + "]"
+}
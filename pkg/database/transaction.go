@@ -0,0 +1,144 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Handle runs statements against the database, either directly or inside a transaction
+type Handle interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*Rows, error)
+	QueryRow(query string, args ...interface{}) *Row
+	Begin() (Tx, error)
+}
+
+// Tx is a transaction, or a savepoint when it was begun inside another transaction
+type Tx interface {
+	Handle
+	Commit() error
+	Rollback() error
+}
+
+// pool runs statements on any free connection
+type pool struct {
+	*sql.DB
+}
+
+func (p pool) Query(query string, args ...interface{}) (*Rows, error) {
+	rows, err := p.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &Rows{Rows: rows}, nil
+}
+
+func (p pool) QueryRow(query string, args ...interface{}) *Row {
+	rows, err := p.Query(query, args...)
+	return &Row{rows: rows, err: err}
+}
+
+func (p pool) Begin() (Tx, error) {
+	tx, err := p.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{Tx: tx}, nil
+}
+
+// transaction keeps every statement on the connection of one transaction
+type transaction struct {
+	*sql.Tx
+	savepoints int
+}
+
+func (t *transaction) Query(query string, args ...interface{}) (*Rows, error) {
+	rows, err := t.Tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &Rows{Rows: rows}, nil
+}
+
+func (t *transaction) QueryRow(query string, args ...interface{}) *Row {
+	rows, err := t.Query(query, args...)
+	return &Row{rows: rows, err: err}
+}
+
+// Begin inside a transaction opens a savepoint, so code that manages its own transaction
+// can run unchanged as part of a larger one
+func (t *transaction) Begin() (Tx, error) {
+	t.savepoints++
+	name := fmt.Sprintf("sp_%d", t.savepoints)
+
+	if _, err := t.Tx.Exec("SAVEPOINT " + name); err != nil {
+		return nil, err
+	}
+	return &savepoint{transaction: t, name: name}, nil
+}
+
+type savepoint struct {
+	*transaction
+	name string
+	done bool
+}
+
+func (s *savepoint) Commit() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+
+	_, err := s.transaction.Exec("RELEASE " + s.name)
+	return err
+}
+
+func (s *savepoint) Rollback() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+
+	if _, err := s.transaction.Exec("ROLLBACK TO " + s.name); err != nil {
+		return err
+	}
+	_, err := s.transaction.Exec("RELEASE " + s.name)
+	return err
+}
+
+// Transaction runs fn with a Database whose statements all belong to one transaction, committed
+// when fn returns nil and rolled back otherwise. Repositories and services built from that
+// Database take part in the transaction, their own transactions become savepoints
+func (d *Database) Transaction(fn func(tx *Database) error) error {
+	tx, err := d.Instance().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	afterCommit := []func(){}
+	if err := fn(&Database{handle: tx, fullTextSearch: d.FullTextSearch(), path: d.path, afterCommit: &afterCommit}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// A savepoint hands its functions on to the transaction around it
+	for _, hook := range afterCommit {
+		d.AfterCommit(hook)
+	}
+	return nil
+}
+
+// AfterCommit runs fn once the transaction of d commits, it is dropped when the transaction rolls
+// back. Outside a transaction fn runs right away. Events about changed rows go through it, so
+// nobody hears about changes they cannot read yet or that never happen
+func (d *Database) AfterCommit(fn func()) {
+	if d.afterCommit == nil {
+		fn()
+		return
+	}
+	*d.afterCommit = append(*d.afterCommit, fn)
+}
@@ -22,6 +22,12 @@ type ActivityListResponseDto struct {
 	Total      int                    `json:"total"`
 }
 
+// RevertActivityResponseDto for a change undone from the activity log
+type RevertActivityResponseDto struct {
+	Reverted *ActivityResponseDto `json:"reverted"` // The change that was undone
+	Activity *ActivityResponseDto `json:"activity"` // The reverted activity recording the undo
+}
+
 // GetActivitiesQueryDto for query parameters
 type GetActivitiesQueryDto struct {
 	EntityType *string `validate:"omitempty,oneof=task column user" json:"entity_type"`
@@ -0,0 +1,5 @@
+package dto
+
+type UnarchiveTaskDto struct {
+	OverrideWipLimit bool `json:"override_wip_limit"` // Root only
+}
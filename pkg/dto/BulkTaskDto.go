@@ -0,0 +1,27 @@
+package dto
+
+// BulkTaskDto applies one operation to the listed tasks, or to every task matching a filter of task list
+// query parameters, e.g. {"q": "column:Review", "board_id": "1"}
+type BulkTaskDto struct {
+	TaskIDs          []int             `validate:"required_without=Filter,omitempty,lte=500,dive,gt=0" json:"task_ids"`
+	Filter           map[string]string `validate:"required_without=TaskIDs,omitempty,lte=30,dive,lte=500" json:"filter"`
+	Operation        string            `validate:"required,oneof=move assign priority add_label remove_label delete" json:"operation"`
+	ColumnID         *int              `validate:"omitempty,gt=0" json:"column_id"`                        // move
+	AssignedTo       *int              `validate:"omitempty,gt=0" json:"assigned_to"`                      // assign, null unassigns
+	Priority         *string           `validate:"omitempty,oneof=low medium high urgent" json:"priority"` // priority
+	LabelID          *int              `validate:"omitempty,gt=0" json:"label_id"`                         // add_label, remove_label
+	OverrideWipLimit bool              `json:"override_wip_limit"`                                         // Root only
+}
+
+type BulkTaskResultDto struct {
+	TaskID  int         `json:"task_id"`
+	Success bool        `json:"success"`
+	Error   *string     `json:"error,omitempty"`
+	Details interface{} `json:"details,omitempty"` // WIP limit or blocked task conflict
+}
+
+type BulkTaskResponseDto struct {
+	Results   []BulkTaskResultDto `json:"results"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+}
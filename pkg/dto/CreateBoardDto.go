@@ -0,0 +1,6 @@
+package dto
+
+type CreateBoardDto struct {
+	Title       string  `validate:"required,lte=100,gte=2" json:"title"`
+	Description *string `validate:"omitempty,lte=500" json:"description"`
+}
@@ -1,6 +1,8 @@
 package dto
 
 type CreateColumnDto struct {
-	Title  string  `validate:"required,lte=100,gte=2" json:"title"`
-	Colors *string `validate:"omitempty,lte=50" json:"colors"` // CSS color or hex code
-}
\ No newline at end of file
+	Title   string  `validate:"required,lte=100,gte=2" json:"title"`
+	BoardID *int    `validate:"omitempty,gt=0" json:"board_id"` // Defaults to the first board
+	Colors  *string `validate:"omitempty,lte=50" json:"colors"` // CSS color or hex code
+	IsDone  bool    `json:"is_done"`                            // Tasks in this column count as finished
+}
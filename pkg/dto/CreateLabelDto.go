@@ -0,0 +1,6 @@
+package dto
+
+type CreateLabelDto struct {
+	Name  string  `validate:"required,lte=50,gte=1" json:"name"`
+	Color *string `validate:"omitempty,lte=50" json:"color"` // CSS color or hex code, defaults to grey
+}
@@ -0,0 +1,7 @@
+package dto
+
+type CreateSavedFilterDto struct {
+	Name     string            `validate:"required,lte=100,gte=1" json:"name"`
+	Filter   map[string]string `validate:"required,lte=30,dive,lte=500" json:"filter"` // Task list query parameters, e.g. {"q": "assignee:me"}
+	IsShared bool              `json:"is_shared"`                                      // Visible to all users
+}
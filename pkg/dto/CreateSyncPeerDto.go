@@ -0,0 +1,7 @@
+package dto
+
+type CreateSyncPeerDto struct {
+	Name  string `validate:"required,lte=100" json:"name"`
+	URL   string `validate:"required,url,lte=500" json:"url"`
+	Token string `validate:"required,lte=200" json:"token"` // The peer's sync token
+}
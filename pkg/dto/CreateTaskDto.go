@@ -1,10 +1,13 @@
 package dto
 
 type CreateTaskDto struct {
-	Title       string  `validate:"required,lte=255,gte=3" json:"title"`
-	Description *string `validate:"omitempty,lte=10000" json:"description"`
-	ColumnID    int     `validate:"required,gt=0" json:"column_id"`
-	AssignedTo  *int    `validate:"omitempty,gt=0" json:"assigned_to"`
-	DueDate     *string `validate:"omitempty" json:"due_date"` // ISO format: 2024-01-15T10:30:00Z
-	Priority    *string `validate:"omitempty,oneof=low medium high urgent" json:"priority"`
+	Title            string  `validate:"required,lte=255,gte=3" json:"title"`
+	Description      *string `validate:"omitempty,lte=10000" json:"description"`
+	ColumnID         int     `validate:"required,gt=0" json:"column_id"`
+	AssignedTo       *int    `validate:"omitempty,gt=0" json:"assigned_to"`
+	DueDate          *string `validate:"omitempty" json:"due_date"` // ISO format: 2024-01-15T10:30:00Z
+	Priority         *string `validate:"omitempty,oneof=low medium high urgent" json:"priority"`
+	LabelIDs         []int   `validate:"omitempty,dive,gt=0" json:"label_ids"`
+	ParentID         *int    `validate:"omitempty,gt=0" json:"parent_id"`
+	OverrideWipLimit bool    `json:"override_wip_limit"` // Root only
 }
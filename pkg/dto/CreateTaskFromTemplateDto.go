@@ -0,0 +1,9 @@
+package dto
+
+type CreateTaskFromTemplateDto struct {
+	ColumnID         *int              `validate:"omitempty,gt=0" json:"column_id"`   // Defaults to the template's column
+	AssignedTo       *int              `validate:"omitempty,gt=0" json:"assigned_to"` // Defaults to the template's assignee
+	DueDate          *string           `validate:"omitempty" json:"due_date"`         // ISO format: 2024-01-15T10:30:00Z
+	Values           map[string]string `json:"values"`                                // Fills {placeholders} in the title pattern
+	OverrideWipLimit bool              `json:"override_wip_limit"`                    // Root only
+}
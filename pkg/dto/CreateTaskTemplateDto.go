@@ -0,0 +1,12 @@
+package dto
+
+type CreateTaskTemplateDto struct {
+	Name           string   `validate:"required,lte=100,gte=2" json:"name"`
+	TitlePattern   string   `validate:"required,lte=255,gte=3" json:"title_pattern"` // e.g. "Release {version} ({date})"
+	Description    *string  `validate:"omitempty,lte=10000" json:"description"`
+	Priority       *string  `validate:"omitempty,oneof=low medium high urgent" json:"priority"`
+	ColumnID       *int     `validate:"omitempty,gt=0" json:"column_id"`
+	AssignedTo     *int     `validate:"omitempty,gt=0" json:"assigned_to"`
+	ChecklistItems []string `validate:"omitempty,lte=100,dive,required,lte=255" json:"checklist_items"`
+	LabelIDs       []int    `validate:"omitempty,dive,gt=0" json:"label_ids"`
+}
@@ -0,0 +1,7 @@
+package dto
+
+type LabelDto struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
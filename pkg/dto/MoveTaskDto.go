@@ -1,6 +1,7 @@
 package dto
 
 type MoveTaskDto struct {
-	ColumnID    int `validate:"required,gt=0" json:"column_id"`
-	NewPosition int `json:"new_position"`
+	ColumnID         int  `validate:"required,gt=0" json:"column_id"`
+	NewPosition      int  `json:"new_position"`
+	OverrideWipLimit bool `json:"override_wip_limit"` // Root only
 }
@@ -0,0 +1,26 @@
+package dto
+
+// NotificationResponseDto for API responses
+type NotificationResponseDto struct {
+	ID             int     `json:"id"`
+	Type           string  `json:"type"`
+	Title          string  `json:"title"`
+	Message        string  `json:"message"`
+	SenderID       *int    `json:"sender_id"`
+	SenderName     *string `json:"sender_name"`
+	SenderUsername *string `json:"sender_username"`
+	TaskID         *int    `json:"task_id"`
+	CommentID      *int    `json:"comment_id"`
+	Data           *string `json:"data"`
+	IsRead         bool    `json:"is_read"`
+	IsSystem       bool    `json:"is_system"`
+	ReadAt         *string `json:"read_at"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+// NotificationListResponseDto for listing notifications
+type NotificationListResponseDto struct {
+	Notifications []*NotificationResponseDto `json:"notifications"`
+	Total         int                        `json:"total"`
+	UnreadCount   int                        `json:"unread_count"`
+}
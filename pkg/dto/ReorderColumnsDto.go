@@ -1,7 +1,8 @@
 package dto
 
 type ReorderColumnsDto struct {
-	Orders []ColumnsOrder `json:"orders" validate:"required,dive,required"`
+	BoardID *int           `json:"board_id" validate:"omitempty,gt=0"` // Defaults to the first board
+	Orders  []ColumnsOrder `json:"orders" validate:"required,dive,required"`
 }
 
 type ColumnsOrder struct {
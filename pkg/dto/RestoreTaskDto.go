@@ -0,0 +1,5 @@
+package dto
+
+type RestoreTaskDto struct {
+	ColumnID *int `validate:"omitempty,gt=0" json:"column_id"` // Required when the task's column was deleted or archived
+}
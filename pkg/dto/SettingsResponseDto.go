@@ -10,6 +10,9 @@ type SettingsResponse struct {
 	AppDescription      *string   `json:"app_description"`
 	DefaultTheme        string    `json:"default_theme"`
 	EnableNotifications bool      `json:"enable_notifications"`
+	EnforceDependencies bool      `json:"enforce_dependencies"`
+	TrashRetentionDays  int       `json:"trash_retention_days"`
+	AutoArchiveDays     int       `json:"auto_archive_days"`
 	CreatedAt           time.Time `json:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at"`
 }
@@ -0,0 +1,17 @@
+package dto
+
+type AttachSubtaskDto struct {
+	TaskID int `validate:"required,gt=0" json:"task_id"` // The task becoming a child
+}
+
+type SubtaskProgressDto struct {
+	Total   int `json:"total"`
+	Done    int `json:"done"`    // Children sitting in a done column
+	Percent int `json:"percent"` // 0 when there are no children
+}
+
+type SubtaskListResponseDto struct {
+	Subtasks []TaskResponseDto  `json:"subtasks"`
+	ParentID int                `json:"parent_id"`
+	Progress SubtaskProgressDto `json:"progress"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+import "encoding/json"
+
+// SyncMutationDto is one queued client request. Strings in the path or body of the form
+// {{<idempotency key>:<field>}} are replaced by a field of the response to an earlier request,
+// e.g. {{create-task-1:task.id}}
+type SyncMutationDto struct {
+	IdempotencyKey string          `validate:"required,lte=255" json:"idempotency_key"`
+	Method         string          `validate:"required,oneof=POST PUT PATCH DELETE" json:"method"`
+	Path           string          `validate:"required,lte=500" json:"path"`
+	Body           json.RawMessage `json:"body"`
+}
+
+type SyncMutationsDto struct {
+	Mutations       []SyncMutationDto `validate:"required,min=1,max=200,dive" json:"mutations"`
+	ContinueOnError bool              `json:"continue_on_error"` // By default the mutations after a failed one are skipped
+}
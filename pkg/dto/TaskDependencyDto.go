@@ -0,0 +1,19 @@
+package dto
+
+type CreateTaskDependencyDto struct {
+	BlockedByTaskID int `validate:"required,gt=0" json:"blocked_by_task_id"`
+}
+
+type TaskDependencyDto struct {
+	TaskID      int    `json:"task_id"`
+	Title       string `json:"title"`
+	ColumnID    int    `json:"column_id"`
+	ColumnTitle string `json:"column_title"`
+	IsDone      bool   `json:"is_done"`
+}
+
+type TaskDependenciesResponseDto struct {
+	BlockedBy []TaskDependencyDto `json:"blocked_by"`
+	Blocks    []TaskDependencyDto `json:"blocks"`
+	IsBlocked bool                `json:"is_blocked"`
+}
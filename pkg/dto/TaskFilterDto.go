@@ -1,17 +1,25 @@
 package dto
 
 type TaskFilterDto struct {
-	Search      *string `validate:"omitempty,lte=100" json:"search"`
-	ColumnID    *int    `validate:"omitempty,gt=0" json:"column_id"`
-	AssignedTo  *int    `validate:"omitempty,gt=0" json:"assigned_to"`
-	CreatedBy   *int    `validate:"omitempty,gt=0" json:"created_by"`
-	Priority    *string `validate:"omitempty,oneof=low medium high urgent" json:"priority"`
-	DueDateFrom *string `validate:"omitempty" json:"due_date_from"`  // ISO format
-	DueDateTo   *string `validate:"omitempty" json:"due_date_to"`    // ISO format
-	CreatedFrom *string `validate:"omitempty" json:"created_from"`   // ISO format
-	CreatedTo   *string `validate:"omitempty" json:"created_to"`     // ISO format
-	Page        *int    `validate:"omitempty,gt=0" json:"page"`      // Page number (1-based)
-	PageSize    *int    `validate:"omitempty,gt=0" json:"page_size"` // Items per page
-	OrderBy     *string `validate:"omitempty,oneof=position created_at updated_at title due_date" json:"order_by"`
-	OrderDir    *string `validate:"omitempty,oneof=asc desc" json:"order_dir"`
+	Search          *string `validate:"omitempty,lte=100" json:"search"`
+	Query           *string `validate:"omitempty,lte=500" json:"q"` // Structured query, see pkg/taskquery
+	BoardID         *int    `validate:"omitempty,gt=0" json:"board_id"`
+	ColumnID        *int    `validate:"omitempty,gt=0" json:"column_id"`
+	AssignedTo      *int    `validate:"omitempty,gt=0" json:"assigned_to"`
+	CreatedBy       *int    `validate:"omitempty,gt=0" json:"created_by"`
+	Priority        *string `validate:"omitempty,oneof=low medium high urgent" json:"priority"`
+	MentionedUserID *int    `validate:"omitempty,gt=0" json:"mentioned_user_id"` // Set from mentioned_me=true
+	ParentID        *int    `validate:"omitempty,gt=0" json:"parent_id"`
+	TopLevelOnly    bool    `json:"top_level_only"`                        // Only tasks without a parent
+	IncludeArchived bool    `json:"include_archived"`                      // Archived tasks are left out otherwise
+	LabelIDs        []int   `validate:"omitempty,dive,gt=0" json:"labels"` // From label_id and labels=1,2,3
+	LabelMode       *string `validate:"omitempty,oneof=any all" json:"label_mode"`
+	DueDateFrom     *string `validate:"omitempty" json:"due_date_from"`  // ISO format
+	DueDateTo       *string `validate:"omitempty" json:"due_date_to"`    // ISO format
+	CreatedFrom     *string `validate:"omitempty" json:"created_from"`   // ISO format
+	CreatedTo       *string `validate:"omitempty" json:"created_to"`     // ISO format
+	Page            *int    `validate:"omitempty,gt=0" json:"page"`      // Page number (1-based)
+	PageSize        *int    `validate:"omitempty,gt=0" json:"page_size"` // Items per page
+	OrderBy         *string `validate:"omitempty,oneof=position created_at updated_at title due_date deleted_at relevance" json:"order_by"`
+	OrderDir        *string `validate:"omitempty,oneof=asc desc" json:"order_dir"`
 }
@@ -0,0 +1,10 @@
+package dto
+
+type SetTaskRecurrenceDto struct {
+	Frequency string  `validate:"required,oneof=daily weekly monthly cron" json:"frequency"`
+	Interval  int     `validate:"omitempty,gte=1,lte=365" json:"interval"` // Every n days, weeks or months, defaults to 1
+	CronExpr  *string `validate:"omitempty,lte=100" json:"cron_expr"`      // Required for cron, e.g. "0 9 * * 1-5"
+	ColumnID  *int    `validate:"omitempty,gt=0" json:"column_id"`         // Defaults to the template's column
+	StartsAt  *string `validate:"omitempty" json:"starts_at"`              // ISO format, defaults to now
+	CatchUp   *bool   `json:"catch_up"`                                    // Make up every missed run, defaults to true
+}
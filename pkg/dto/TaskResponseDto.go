@@ -1,30 +1,40 @@
 package dto
 
 type TaskResponseDto struct {
-	ID          int    `json:"id"`
-	Title       string `json:"title"`
+	ID          int     `json:"id"`
+	Title       string  `json:"title"`
 	Description *string `json:"description"`
-	ColumnID    int    `json:"column_id"`
-	AssignedTo  *int   `json:"assigned_to"`
-	CreatedBy   int    `json:"created_by"`
-	DueDate     *string `json:"due_date"`     // ISO format
+	ColumnID    int     `json:"column_id"`
+	AssignedTo  *int    `json:"assigned_to"`
+	CreatedBy   int     `json:"created_by"`
+	DueDate     *string `json:"due_date"` // ISO format
 	Priority    *string `json:"priority"`
-	Position    int    `json:"position"`
-	Weight      int    `json:"weight"`
-	CreatedAt   string `json:"created_at"`   // ISO format
-	UpdatedAt   string `json:"updated_at"`   // ISO format
-	
+	Position    int     `json:"position"`
+	Weight      int     `json:"weight"`
+	ParentID    *int    `json:"parent_id"`
+	CreatedAt   string  `json:"created_at"` // ISO format
+	UpdatedAt   string  `json:"updated_at"` // ISO format
+
 	// Related data (when included)
-	AssignedUser  *UserDto `json:"assigned_user,omitempty"`
-	CreatedByUser *UserDto `json:"created_by_user,omitempty"`
-	ColumnTitle   *string  `json:"column_title,omitempty"`
-	CommentCount  int      `json:"comment_count,omitempty"`
+	AssignedUser  *UserDto            `json:"assigned_user,omitempty"`
+	CreatedByUser *UserDto            `json:"created_by_user,omitempty"`
+	ColumnTitle   *string             `json:"column_title,omitempty"`
+	CommentCount  int                 `json:"comment_count,omitempty"`
+	Labels        []LabelDto          `json:"labels"`
+	BlockedBy     []TaskDependencyDto `json:"blocked_by"`
+	Blocks        []TaskDependencyDto `json:"blocks"`
+	IsBlocked     bool                `json:"is_blocked"` // Waiting on a task outside a done column
+	Subtasks      SubtaskProgressDto  `json:"subtasks"`
+	SearchSnippet *string             `json:"search_snippet,omitempty"` // Only when searching, matches wrapped in <mark>
+	DeletedAt     *string             `json:"deleted_at,omitempty"`     // ISO format, only for tasks in the trash
+	DeletedBy     *int                `json:"deleted_by,omitempty"`
+	ArchivedAt    *string             `json:"archived_at,omitempty"` // ISO format, only for archived tasks
 }
 
 type TaskListResponseDto struct {
 	Tasks      []TaskResponseDto `json:"tasks"`
-	Total      int              `json:"total"`
-	Page       int              `json:"page"`
-	PageSize   int              `json:"page_size"`
-	TotalPages int              `json:"total_pages"`
-}
\ No newline at end of file
+	Total      int               `json:"total"`
+	Page       int               `json:"page"`
+	PageSize   int               `json:"page_size"`
+	TotalPages int               `json:"total_pages"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+type UpdateBackupSettingsDto struct {
+	Enabled       *bool   `json:"enabled"`                                           // Unchanged when omitted
+	IntervalHours *int    `validate:"omitempty,gte=1,lte=720" json:"interval_hours"` // Unchanged when omitted
+	Directory     *string `validate:"omitempty,lte=500" json:"directory"`            // Unchanged when omitted, "" resets it to the default
+	KeepDaily     *int    `validate:"omitempty,gte=1,lte=365" json:"keep_daily"`     // Unchanged when omitted
+	KeepWeekly    *int    `validate:"omitempty,gte=0,lte=520" json:"keep_weekly"`    // Unchanged when omitted
+}
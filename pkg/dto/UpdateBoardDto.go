@@ -0,0 +1,6 @@
+package dto
+
+type UpdateBoardDto struct {
+	Title       *string `validate:"omitempty,lte=100,gte=2" json:"title"`
+	Description *string `validate:"omitempty,lte=500" json:"description"`
+}
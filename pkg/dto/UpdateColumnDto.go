@@ -1,6 +1,9 @@
 package dto
 
 type UpdateColumnDto struct {
-	Title  *string `validate:"omitempty,lte=100,gte=2" json:"title"`
-	Colors *string `validate:"omitempty,lte=50" json:"colors"` // CSS color or hex code
-}
\ No newline at end of file
+	Title           *string `validate:"omitempty,lte=100,gte=2" json:"title"`
+	Colors          *string `validate:"omitempty,lte=50" json:"colors"`   // CSS color or hex code
+	WipLimit        *int    `validate:"omitempty,min=0" json:"wip_limit"` // 0 removes the limit
+	IsDone          *bool   `json:"is_done"`
+	AutoArchiveDays *int    `validate:"omitempty,min=0,max=365" json:"auto_archive_days"` // Days before done tasks are archived, 0 follows the app setting
+}
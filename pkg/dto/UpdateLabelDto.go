@@ -0,0 +1,6 @@
+package dto
+
+type UpdateLabelDto struct {
+	Name  *string `validate:"omitempty,lte=50,gte=1" json:"name"`
+	Color *string `validate:"omitempty,lte=50" json:"color"` // CSS color or hex code
+}
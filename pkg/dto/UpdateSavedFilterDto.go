@@ -0,0 +1,7 @@
+package dto
+
+type UpdateSavedFilterDto struct {
+	Name     *string           `validate:"omitempty,lte=100,gte=1" json:"name"`
+	Filter   map[string]string `validate:"omitempty,lte=30,dive,lte=500" json:"filter"` // Replaces the stored filter when present
+	IsShared *bool             `json:"is_shared"`
+}
@@ -5,4 +5,7 @@ type UpdateSettingsDto struct {
 	AppDescription       *string `validate:"omitempty,lte=200" json:"app_description"`
 	DefaultTheme         string `validate:"required,oneof=light dark system" json:"default_theme"`
 	EnableNotifications  bool   `json:"enable_notifications"`
+	EnforceDependencies  *bool  `json:"enforce_dependencies"` // Unchanged when omitted
+	TrashRetentionDays   *int   `validate:"omitempty,gte=0,lte=3650" json:"trash_retention_days"` // Unchanged when omitted, 0 never purges
+	AutoArchiveDays      *int   `validate:"omitempty,gte=0,lte=365" json:"auto_archive_days"`     // Unchanged when omitted, 0 never archives
 }
\ No newline at end of file
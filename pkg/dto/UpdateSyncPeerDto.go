@@ -0,0 +1,7 @@
+package dto
+
+type UpdateSyncPeerDto struct {
+	Name  *string `validate:"omitempty,gte=1,lte=100" json:"name"`  // Unchanged when omitted
+	URL   *string `validate:"omitempty,url,lte=500" json:"url"`     // Unchanged when omitted
+	Token *string `validate:"omitempty,gte=1,lte=200" json:"token"` // Unchanged when omitted
+}
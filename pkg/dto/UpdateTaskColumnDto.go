@@ -1,5 +1,6 @@
 package dto
 
 type UpdateTaskColumnDto struct {
-	ColumnID *int `validate:"required,lte=255,gte=0" json:"column_id"`
+	ColumnID         *int `validate:"required,lte=255,gte=0" json:"column_id"`
+	OverrideWipLimit bool `json:"override_wip_limit"` // Root only
 }
@@ -0,0 +1,154 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types pushed to connected clients
+const (
+	TaskCreated    = "task.created"
+	TaskUpdated    = "task.updated"
+	TaskMoved      = "task.moved"
+	TaskDeleted    = "task.deleted" // Moved to the trash
+	TaskRestored   = "task.restored"
+	TaskArchived   = "task.archived"
+	TaskUnarchived = "task.unarchived"
+
+	CommentCreated = "comment.created"
+	CommentUpdated = "comment.updated"
+	CommentDeleted = "comment.deleted"
+
+	ChecklistCreated = "checklist.created"
+	ChecklistUpdated = "checklist.updated"
+	ChecklistToggled = "checklist.toggled"
+	ChecklistDeleted = "checklist.deleted"
+
+	ColumnCreated    = "column.created"
+	ColumnUpdated    = "column.updated"
+	ColumnArchived   = "column.archived"
+	ColumnUnarchived = "column.unarchived"
+	ColumnsReordered = "columns.reordered"
+	ColumnTasksMoved = "column.tasks_moved"
+
+	NotificationCreated = "notification.created"
+)
+
+type Event struct {
+	Type        string      `json:"type"`
+	BoardID     *int        `json:"board_id,omitempty"`
+	TaskID      *int        `json:"task_id,omitempty"`
+	UserID      int         `json:"user_id"`
+	RecipientID *int        `json:"recipient_id,omitempty"` // Only delivered to this user when set
+	Data        interface{} `json:"data,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// Subscriber receives the events of one open stream
+type Subscriber struct {
+	UserID  int
+	BoardID *int
+	events  chan Event
+}
+
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscriber) wants(event Event) bool {
+	if event.RecipientID != nil {
+		return *event.RecipientID == s.UserID
+	}
+	if s.BoardID == nil || event.BoardID == nil {
+		return true
+	}
+	return *s.BoardID == *event.BoardID
+}
+
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscriber]struct{}
+	closed      bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[*Subscriber]struct{}),
+	}
+}
+
+var defaultBroker = NewBroker()
+
+// Default returns the broker shared by the whole server
+func Default() *Broker {
+	return defaultBroker
+}
+
+// Publish sends an event through the default broker
+func Publish(event Event) {
+	defaultBroker.Publish(event)
+}
+
+// Subscribe registers a stream, optionally limited to one board
+func (b *Broker) Subscribe(userID int, boardID *int) *Subscriber {
+	sub := &Subscriber{
+		UserID:  userID,
+		BoardID: boardID,
+		events:  make(chan Event, 64),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(sub.events)
+		return sub
+	}
+	b.subscribers[sub] = struct{}{}
+
+	return sub
+}
+
+// Unsubscribe removes a stream and closes its channel
+func (b *Broker) Unsubscribe(sub *Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Publish delivers an event to every matching subscriber without blocking;
+// slow clients miss events and are expected to refetch
+func (b *Broker) Publish(event Event) {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscribers {
+		if !sub.wants(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+// Close ends every open stream so the HTTP server can shut down
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+	b.closed = true
+}
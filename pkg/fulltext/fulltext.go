@@ -0,0 +1,128 @@
+package fulltext
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Markers placed around matched terms by the snippet() SQL function. Control characters never
+// appear in indexed text, so they survive HTML escaping and are swapped for <mark> afterwards
+const (
+	MarkStart = "\x02"
+	MarkEnd   = "\x03"
+)
+
+// StripHTML turns rich-text editor markup into plain text for indexing. Tags become spaces so
+// words from neighbouring blocks do not run together, and entities are decoded
+func StripHTML(markup string) string {
+	var text strings.Builder
+	inTag := false
+
+	for _, r := range markup {
+		switch {
+		case r == '<':
+			inTag = true
+			text.WriteRune(' ')
+		case r == '>' && inTag:
+			inTag = false
+		case !inTag:
+			text.WriteRune(r)
+		}
+	}
+
+	return strings.Join(strings.Fields(html.UnescapeString(text.String())), " ")
+}
+
+// MatchQuery converts the search box input into an FTS5 MATCH expression. Words and "quoted
+// phrases" must all match, a trailing * makes a prefix query and upper case OR / NOT between
+// terms are kept as operators. Everything else is quoted, so user input can never be a syntax
+// error. An empty result means the input holds nothing searchable
+func MatchQuery(input string) string {
+	var terms []string
+	pendingOperator := ""
+
+	for _, token := range tokenize(input) {
+		if !token.quoted && (token.text == "OR" || token.text == "NOT" || token.text == "AND") {
+			if len(terms) > 0 {
+				pendingOperator = token.text
+			}
+			continue
+		}
+
+		text := strings.TrimRight(token.text, "*")
+		prefix := len(text) < len(token.text) || token.prefix
+		if !hasWordCharacter(text) {
+			continue
+		}
+
+		term := `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+
+		if pendingOperator != "" {
+			terms = append(terms, pendingOperator)
+			pendingOperator = ""
+		}
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// HighlightSnippet escapes a snippet for HTML and wraps its matched terms in <mark>
+func HighlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, MarkStart, "<mark>")
+	return strings.ReplaceAll(escaped, MarkEnd, "</mark>")
+}
+
+type token struct {
+	text   string
+	quoted bool
+	prefix bool // "phrase"* form
+}
+
+// tokenize splits input on white space, keeping double quoted phrases together
+func tokenize(input string) []token {
+	var tokens []token
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		switch {
+		case unicode.IsSpace(runes[i]):
+			i++
+		case runes[i] == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			phrase := token{text: string(runes[i+1 : min(end, len(runes))]), quoted: true}
+			i = end + 1
+			if i < len(runes) && runes[i] == '*' {
+				phrase.prefix = true
+				i++
+			}
+			tokens = append(tokens, phrase)
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '"' {
+				end++
+			}
+			tokens = append(tokens, token{text: string(runes[i:end])})
+			i = end
+		}
+	}
+
+	return tokens
+}
+
+func hasWordCharacter(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies a recurrence rule can use
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+	Cron    = "cron"
+)
+
+// Cron expressions never look further ahead than this for a matching minute
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// Rule describes when a recurring task comes back. Daily, weekly and monthly rules repeat every
+// Interval periods counted from StartsAt, cron rules follow a five field expression
+// (minute hour day-of-month month day-of-week) evaluated in local time
+type Rule struct {
+	Frequency string
+	Interval  int
+	CronExpr  string
+	StartsAt  time.Time
+
+	cron *cronSchedule
+}
+
+// New validates a rule and prepares it for Next
+func New(frequency string, interval int, cronExpr string, startsAt time.Time) (*Rule, error) {
+	rule := &Rule{
+		Frequency: frequency,
+		Interval:  interval,
+		CronExpr:  strings.TrimSpace(cronExpr),
+		StartsAt:  startsAt,
+	}
+
+	switch frequency {
+	case Daily, Weekly, Monthly:
+		if rule.Interval < 1 {
+			rule.Interval = 1
+		}
+	case Cron:
+		schedule, err := parseCron(rule.CronExpr)
+		if err != nil {
+			return nil, err
+		}
+		rule.cron = schedule
+	default:
+		return nil, fmt.Errorf("unknown frequency %q", frequency)
+	}
+
+	return rule, nil
+}
+
+// Next returns the first occurrence strictly after the given time, or the zero time when there is none
+func (rule *Rule) Next(after time.Time) time.Time {
+	if rule.Frequency == Cron {
+		return rule.cron.next(after)
+	}
+
+	start := rule.StartsAt.In(time.Local)
+	if start.After(after) {
+		return start
+	}
+
+	// Jump close to the answer instead of walking every period since the start
+	step := 0
+	switch rule.Frequency {
+	case Daily:
+		step = int(after.Sub(start).Hours()/24) / rule.Interval
+	case Weekly:
+		step = int(after.Sub(start).Hours()/(24*7)) / rule.Interval
+	case Monthly:
+		after := after.In(time.Local)
+		months := (after.Year()-start.Year())*12 + int(after.Month()) - int(start.Month())
+		step = months / rule.Interval
+	}
+	if step > 0 {
+		step--
+	}
+
+	for {
+		occurrence := rule.occurrence(start, step)
+		if occurrence.After(after) {
+			return occurrence
+		}
+		step++
+	}
+}
+
+// occurrence returns the nth occurrence counted from the start
+func (rule *Rule) occurrence(start time.Time, n int) time.Time {
+	switch rule.Frequency {
+	case Daily:
+		return start.AddDate(0, 0, n*rule.Interval)
+	case Weekly:
+		return start.AddDate(0, 0, n*rule.Interval*7)
+	default:
+		return addMonthsClamped(start, n*rule.Interval)
+	}
+}
+
+// addMonthsClamped adds months keeping the day of month, or the last day of shorter months
+func addMonthsClamped(t time.Time, months int) time.Time {
+	firstOfMonth := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+	target := firstOfMonth.AddDate(0, months, 0)
+
+	lastDay := target.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return target.AddDate(0, 0, day-1)
+}
+
+// cronSchedule holds the allowed values of each cron field
+type cronSchedule struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+
+	// Standard cron matches either day field when both are restricted
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+// ValidateCron reports whether a cron expression can be used in a rule
+func ValidateCron(expr string) error {
+	_, err := parseCron(strings.TrimSpace(expr))
+	return err
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.New("cron expression needs 5 fields: minute hour day-of-month month day-of-week")
+	}
+
+	schedule := &cronSchedule{
+		anyDayOfMonth: fields[2] == "*",
+		anyDayOfWeek:  fields[4] == "*",
+	}
+
+	var err error
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if schedule.daysOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if schedule.daysOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+
+	// Both 0 and 7 mean Sunday
+	if schedule.daysOfWeek[7] {
+		schedule.daysOfWeek[0] = true
+	}
+
+	return schedule, nil
+}
+
+// parseCronField understands *, single values, a-b ranges, */n and a-b/n steps and comma separated lists
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if rangePart, stepPart, found := strings.Cut(part, "/"); found {
+			parsed, err := strconv.Atoi(stepPart)
+			if err != nil || parsed < 1 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = parsed
+			part = rangePart
+		}
+
+		low, high := min, max
+		if part != "*" {
+			from, to, isRange := strings.Cut(part, "-")
+
+			parsed, err := strconv.Atoi(from)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", from)
+			}
+			low, high = parsed, parsed
+
+			if isRange {
+				parsed, err = strconv.Atoi(to)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value %q", to)
+				}
+				high = parsed
+			} else if step > 1 {
+				// 5/15 means from 5 to the end in steps of 15
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for value := low; value <= high; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+func (schedule *cronSchedule) next(after time.Time) time.Time {
+	t := after.In(time.Local).Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+
+	for t.Before(limit) {
+		if !schedule.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !schedule.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !schedule.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !schedule.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (schedule *cronSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth := schedule.daysOfMonth[t.Day()]
+	dayOfWeek := schedule.daysOfWeek[int(t.Weekday())]
+
+	switch {
+	case schedule.anyDayOfMonth && schedule.anyDayOfWeek:
+		return true
+	case schedule.anyDayOfMonth:
+		return dayOfWeek
+	case schedule.anyDayOfWeek:
+		return dayOfMonth
+	default:
+		return dayOfMonth || dayOfWeek
+	}
+}
@@ -0,0 +1,562 @@
+// Package taskquery parses the task filter language used by the task list and saved filters.
+//
+// A query is a list of terms that must all match, for example
+//
+//	assignee:me priority:>=high due:<7d label:backend -column:Done
+//
+// Terms are field:value pairs or plain words searched in the task text. Terms can be grouped with
+// parentheses, combined with OR and negated with a leading - or NOT. Ordered fields (priority,
+// due, created, updated, id) accept =, !=, <, <=, > and >= right after the colon, the others
+// accept = and !=. Values with spaces are quoted: column:"In Progress".
+package taskquery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Node is one part of a parsed query: And, Or, Not or Condition
+type Node interface {
+	isNode()
+}
+
+// And matches when every node matches
+type And struct {
+	Nodes []Node
+}
+
+// Or matches when any node matches
+type Or struct {
+	Nodes []Node
+}
+
+// Not matches when its node does not
+type Not struct {
+	Node Node
+}
+
+// Condition is a single field:value term with its value already checked and resolved
+type Condition struct {
+	Field    string    // One of the Field constants
+	Operator string    // =, !=, <, <=, >, >=
+	Text     string    // User name, label name, column or board title, or words to search
+	Number   int       // Ids and priority ranks
+	UserID   *int      // Set when the value was "me"
+	None     bool      // The value was "none", the field must be empty
+	From     time.Time // Date values cover one local day [From, To)
+	To       time.Time
+}
+
+func (And) isNode()       {}
+func (Or) isNode()        {}
+func (Not) isNode()       {}
+func (Condition) isNode() {}
+
+// Fields a condition can filter on
+const (
+	FieldAssignee = "assignee"
+	FieldAuthor   = "author"
+	FieldMentions = "mentions"
+	FieldPriority = "priority"
+	FieldDue      = "due"
+	FieldCreated  = "created"
+	FieldUpdated  = "updated"
+	FieldLabel    = "label"
+	FieldColumn   = "column"
+	FieldBoard    = "board"
+	FieldParent   = "parent"
+	FieldID       = "id"
+	FieldTitle    = "title"
+	FieldIs       = "is"
+	FieldText     = "text" // Plain words outside a field:value pair
+)
+
+// Flags accepted by is:
+const (
+	IsDone    = "done"    // In a done column
+	IsOpen    = "open"    // Not in a done column
+	IsBlocked = "blocked" // Waiting on a task outside a done column
+	IsSubtask = "subtask" // Has a parent
+	IsParent  = "parent"  // Has subtasks
+)
+
+// Priorities in ascending order, the rank of a priority is its index plus one
+var Priorities = []string{"low", "medium", "high", "urgent"}
+
+type valueKind int
+
+const (
+	kindUser valueKind = iota
+	kindPriority
+	kindDate
+	kindName
+	kindColumn
+	kindNumber
+	kindText
+	kindFlag
+)
+
+type fieldSpec struct {
+	name      string
+	kind      valueKind
+	allowNone bool
+}
+
+// Field names and their aliases
+var fields = map[string]fieldSpec{
+	"assignee":   {FieldAssignee, kindUser, true},
+	"assigned":   {FieldAssignee, kindUser, true},
+	"author":     {FieldAuthor, kindUser, false},
+	"creator":    {FieldAuthor, kindUser, false},
+	"created_by": {FieldAuthor, kindUser, false},
+	"mentions":   {FieldMentions, kindUser, false},
+	"priority":   {FieldPriority, kindPriority, true},
+	"due":        {FieldDue, kindDate, true},
+	"created":    {FieldCreated, kindDate, false},
+	"updated":    {FieldUpdated, kindDate, false},
+	"label":      {FieldLabel, kindName, true},
+	"column":     {FieldColumn, kindColumn, false},
+	"board":      {FieldBoard, kindColumn, false},
+	"parent":     {FieldParent, kindNumber, true},
+	"id":         {FieldID, kindNumber, false},
+	"title":      {FieldTitle, kindText, false},
+	"is":         {FieldIs, kindFlag, false},
+}
+
+// Context resolves values that depend on who runs the query and when
+type Context struct {
+	UserID int       // Value of "me"
+	Now    time.Time // Reference for today and relative dates like 7d
+}
+
+// Error points at the token of a query that could not be parsed
+type Error struct {
+	Message  string `json:"message"`
+	Position int    `json:"position"` // 1-based character offset of the token
+	Token    string `json:"token"`
+}
+
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("%s at position %d", e.Message, e.Position)
+	}
+	return fmt.Sprintf("%s at position %d (%q)", e.Message, e.Position, e.Token)
+}
+
+// Parse turns a query into a tree of nodes, an empty query gives a nil node
+func Parse(query string, ctx Context) (Node, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, ctx: ctx, end: len([]rune(query))}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.done() {
+		return nil, p.errorAt(p.peek(), "unexpected "+describe(p.peek()))
+	}
+
+	return node, nil
+}
+
+type tokenType int
+
+const (
+	tokenWord tokenType = iota
+	tokenOpen
+	tokenClose
+	tokenMinus
+	tokenOr
+	tokenAnd
+	tokenNot
+)
+
+type token struct {
+	typ    tokenType
+	text   string // Raw text as typed
+	pos    int    // 0-based rune offset
+	quoted bool   // The whole word was a "quoted phrase"
+}
+
+// lex splits a query into words, parentheses, operators and negation signs
+func lex(query string) ([]token, error) {
+	var tokens []token
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{typ: tokenOpen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{typ: tokenClose, text: ")", pos: i})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			tokens = append(tokens, token{typ: tokenMinus, text: "-", pos: i})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+				if runes[i] == '"' {
+					end := i + 1
+					for end < len(runes) && runes[end] != '"' {
+						end++
+					}
+					if end == len(runes) {
+						return nil, &Error{Message: "unterminated quote", Position: i + 1, Token: string(runes[start:])}
+					}
+					i = end
+				}
+				i++
+			}
+
+			word := token{typ: tokenWord, text: string(runes[start:i]), pos: start}
+			switch word.text {
+			case "OR":
+				word.typ = tokenOr
+			case "AND":
+				word.typ = tokenAnd
+			case "NOT":
+				word.typ = tokenNot
+			}
+			word.quoted = len(word.text) >= 2 && strings.HasPrefix(word.text, `"`) && strings.HasSuffix(word.text, `"`) &&
+				strings.Count(word.text, `"`) == 2
+			tokens = append(tokens, word)
+		}
+	}
+
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	index  int
+	ctx    Context
+	end    int // Length of the query, where errors about a missing token point
+}
+
+func (p *parser) done() bool {
+	return p.index >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.index]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.index]
+	p.index++
+	return t
+}
+
+// parseOr handles a OR b OR c, OR binds looser than the implicit AND
+func (p *parser) parseOr() (Node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []Node{first}
+	for !p.done() && p.peek().typ == tokenOr {
+		p.next()
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	if len(nodes) == 1 {
+		return first, nil
+	}
+	return Or{Nodes: nodes}, nil
+}
+
+// parseAnd handles terms next to each other, with or without AND between them
+func (p *parser) parseAnd() (Node, error) {
+	var nodes []Node
+
+	for !p.done() {
+		t := p.peek()
+		if t.typ == tokenOr || t.typ == tokenClose {
+			break
+		}
+		if t.typ == tokenAnd {
+			if len(nodes) == 0 {
+				return nil, p.errorAt(t, "AND needs a term before it")
+			}
+			p.next()
+			if p.done() || p.peek().typ == tokenOr || p.peek().typ == tokenClose {
+				return nil, p.errorAt(t, "AND needs a term after it")
+			}
+			continue
+		}
+
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	if len(nodes) == 0 {
+		if p.done() {
+			return nil, &Error{Message: "expected a term", Position: p.end + 1}
+		}
+		return nil, p.errorAt(p.peek(), "expected a term before "+describe(p.peek()))
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return And{Nodes: nodes}, nil
+}
+
+// parseUnary handles -term and NOT term
+func (p *parser) parseUnary() (Node, error) {
+	t := p.peek()
+	if t.typ == tokenMinus || t.typ == tokenNot {
+		p.next()
+		if p.done() {
+			return nil, p.errorAt(t, "nothing to negate")
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Node: node}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+// parsePrimary handles (group) and single terms
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.next()
+
+	switch t.typ {
+	case tokenOpen:
+		if !p.done() && p.peek().typ == tokenClose {
+			return nil, p.errorAt(t, "empty group")
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.done() {
+			return nil, p.errorAt(t, "missing closing parenthesis")
+		}
+		p.next()
+		return node, nil
+	case tokenWord:
+		return p.parseTerm(t)
+	default:
+		return nil, p.errorAt(t, "unexpected "+describe(t))
+	}
+}
+
+// parseTerm checks a field:value pair or turns a plain word into a text search
+func (p *parser) parseTerm(t token) (Node, error) {
+	name, value, hasField := strings.Cut(t.text, ":")
+	if !hasField || t.quoted || strings.HasPrefix(name, `"`) {
+		text := unquote(t.text)
+		if strings.TrimSpace(text) == "" {
+			return nil, p.errorAt(t, "empty search term")
+		}
+		return Condition{Field: FieldText, Operator: "=", Text: text}, nil
+	}
+
+	spec, known := fields[strings.ToLower(name)]
+	if !known {
+		return nil, p.errorAt(t, fmt.Sprintf("unknown field %q", name))
+	}
+
+	operator, value := splitOperator(value)
+	value = unquote(value)
+	if value == "" {
+		return nil, p.errorAt(t, fmt.Sprintf("%s needs a value", name))
+	}
+
+	ordered := spec.kind == kindPriority || spec.kind == kindDate || spec.kind == kindNumber && spec.name == FieldID
+	if !ordered && operator != "=" && operator != "!=" {
+		return nil, p.errorAt(t, fmt.Sprintf("%s only supports = and !=", name))
+	}
+
+	condition := Condition{Field: spec.name, Operator: operator}
+
+	if strings.EqualFold(value, "none") {
+		if !spec.allowNone {
+			return nil, p.errorAt(t, fmt.Sprintf("%s cannot be none", name))
+		}
+		if operator != "=" && operator != "!=" {
+			return nil, p.errorAt(t, "none only supports = and !=")
+		}
+		condition.None = true
+		return negateIfNotEqual(condition), nil
+	}
+
+	switch spec.kind {
+	case kindUser:
+		if strings.EqualFold(value, "me") {
+			if p.ctx.UserID <= 0 {
+				return nil, p.errorAt(t, "me needs a signed in user")
+			}
+			userID := p.ctx.UserID
+			condition.UserID = &userID
+		} else {
+			condition.Text = strings.TrimPrefix(value, "@")
+		}
+	case kindPriority:
+		rank := priorityRank(value)
+		if rank == 0 {
+			return nil, p.errorAt(t, fmt.Sprintf("unknown priority %q, use %s", value, strings.Join(Priorities, ", ")))
+		}
+		condition.Number = rank
+	case kindDate:
+		from, ok := p.parseDate(value)
+		if !ok {
+			return nil, p.errorAt(t, fmt.Sprintf("invalid date %q, use YYYY-MM-DD, today, tomorrow, yesterday or an offset like 7d, -2w, 1m", value))
+		}
+		condition.From = from
+		condition.To = from.AddDate(0, 0, 1)
+	case kindColumn:
+		if id, err := strconv.Atoi(value); err == nil && id > 0 {
+			condition.Number = id
+		} else {
+			condition.Text = value
+		}
+	case kindNumber:
+		id, err := strconv.Atoi(strings.TrimPrefix(value, "#"))
+		if err != nil || id <= 0 {
+			return nil, p.errorAt(t, fmt.Sprintf("%s needs a task number", name))
+		}
+		condition.Number = id
+	case kindName, kindText:
+		condition.Text = value
+	case kindFlag:
+		value = strings.ToLower(value)
+		switch value {
+		case IsDone, IsOpen, IsBlocked, IsSubtask, IsParent:
+			condition.Text = value
+		default:
+			return nil, p.errorAt(t, fmt.Sprintf("unknown is: value %q, use done, open, blocked, subtask or parent", value))
+		}
+	}
+
+	if ordered {
+		return condition, nil
+	}
+	return negateIfNotEqual(condition), nil
+}
+
+// parseDate resolves a date value to the start of its local day
+func (p *parser) parseDate(value string) (time.Time, bool) {
+	now := p.ctx.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	now = now.In(time.Local)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	switch strings.ToLower(value) {
+	case "today", "now":
+		return today, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	}
+
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return date, true
+	}
+
+	// Offsets from today: 7d, +2w, -1m
+	if len(value) < 2 {
+		return time.Time{}, false
+	}
+	amount, err := strconv.Atoi(strings.TrimPrefix(value[:len(value)-1], "+"))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	switch unicode.ToLower(rune(value[len(value)-1])) {
+	case 'd':
+		return today.AddDate(0, 0, amount), true
+	case 'w':
+		return today.AddDate(0, 0, amount*7), true
+	case 'm':
+		return today.AddDate(0, amount, 0), true
+	case 'y':
+		return today.AddDate(amount, 0, 0), true
+	}
+
+	return time.Time{}, false
+}
+
+func (p *parser) errorAt(t token, message string) *Error {
+	return &Error{Message: message, Position: t.pos + 1, Token: t.text}
+}
+
+// splitOperator takes a comparison off the front of a value, = when there is none
+func splitOperator(value string) (string, string) {
+	for _, operator := range []string{">=", "<=", "!=", ">", "<", "="} {
+		if strings.HasPrefix(value, operator) {
+			return operator, value[len(operator):]
+		}
+	}
+	return "=", value
+}
+
+// negateIfNotEqual turns field:!=value into NOT field:value for fields without an order
+func negateIfNotEqual(condition Condition) Node {
+	if condition.Operator != "!=" {
+		return condition
+	}
+	condition.Operator = "="
+	return Not{Node: condition}
+}
+
+// priorityRank returns the 1-based rank of a priority, 0 when unknown
+func priorityRank(priority string) int {
+	for i, known := range Priorities {
+		if strings.EqualFold(priority, known) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+func describe(t token) string {
+	switch t.typ {
+	case tokenClose:
+		return "closing parenthesis"
+	case tokenOpen:
+		return "opening parenthesis"
+	case tokenOr, tokenAnd, tokenNot:
+		return t.text
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/dev-parvej/offline_kanban/pkg/database"
@@ -14,6 +15,8 @@ type Activity struct {
 	FieldName  *string   `json:"field_name"`
 	OldValue   *string   `json:"old_value"`
 	NewValue   *string   `json:"new_value"`
+	OldRef     *int      `json:"old_ref"` // ID behind old_value for assignee and column changes, 0 for unassigned
+	NewRef     *int      `json:"new_ref"`
 	UserID     int       `json:"user_id"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
@@ -33,12 +36,17 @@ func NewActivityRepository(db *database.Database) *ActivityRepository {
 
 // Create a new activity record
 func (ar *ActivityRepository) Create(entityType string, entityID int, action string, fieldName, oldValue, newValue *string, userID int) (*Activity, error) {
+	return ar.CreateWithRefs(entityType, entityID, action, fieldName, oldValue, newValue, nil, nil, userID)
+}
+
+// Create a new activity record keeping the IDs behind the old and new values
+func (ar *ActivityRepository) CreateWithRefs(entityType string, entityID int, action string, fieldName, oldValue, newValue *string, oldRef, newRef *int, userID int) (*Activity, error) {
 	query := `
-		INSERT INTO activities (entity_type, entity_id, action, field_name, old_value, new_value, user_id, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		INSERT INTO activities (entity_type, entity_id, action, field_name, old_value, new_value, old_ref, new_ref, user_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 	`
 
-	result, err := ar.db.Instance().Exec(query, entityType, entityID, action, fieldName, oldValue, newValue, userID)
+	result, err := ar.db.Instance().Exec(query, entityType, entityID, action, fieldName, oldValue, newValue, oldRef, newRef, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +64,7 @@ func (ar *ActivityRepository) FindByID(id int) (*Activity, error) {
 	query := `
 		SELECT 
 			a.id, a.entity_type, a.entity_id, a.action, a.field_name, 
-			a.old_value, a.new_value, a.user_id, a.created_at, a.updated_at,
+			a.old_value, a.new_value, a.old_ref, a.new_ref, a.user_id, a.created_at, a.updated_at,
 			u.name, u.username
 		FROM activities a
 		LEFT JOIN users u ON a.user_id = u.id
@@ -68,7 +76,7 @@ func (ar *ActivityRepository) FindByID(id int) (*Activity, error) {
 
 	err := row.Scan(
 		&activity.ID, &activity.EntityType, &activity.EntityID, &activity.Action,
-		&activity.FieldName, &activity.OldValue, &activity.NewValue, &activity.UserID,
+		&activity.FieldName, &activity.OldValue, &activity.NewValue, &activity.OldRef, &activity.NewRef, &activity.UserID,
 		&activity.CreatedAt, &activity.UpdatedAt, &activity.UserName, &activity.Username,
 	)
 
@@ -84,7 +92,7 @@ func (ar *ActivityRepository) GetByEntity(entityType string, entityID int) ([]*A
 	query := `
 		SELECT 
 			a.id, a.entity_type, a.entity_id, a.action, a.field_name, 
-			a.old_value, a.new_value, a.user_id, a.created_at, a.updated_at,
+			a.old_value, a.new_value, a.old_ref, a.new_ref, a.user_id, a.created_at, a.updated_at,
 			u.name, u.username
 		FROM activities a
 		LEFT JOIN users u ON a.user_id = u.id
@@ -103,7 +111,7 @@ func (ar *ActivityRepository) GetByEntity(entityType string, entityID int) ([]*A
 		activity := &Activity{}
 		err := rows.Scan(
 			&activity.ID, &activity.EntityType, &activity.EntityID, &activity.Action,
-			&activity.FieldName, &activity.OldValue, &activity.NewValue, &activity.UserID,
+			&activity.FieldName, &activity.OldValue, &activity.NewValue, &activity.OldRef, &activity.NewRef, &activity.UserID,
 			&activity.CreatedAt, &activity.UpdatedAt, &activity.UserName, &activity.Username,
 		)
 		if err != nil {
@@ -115,20 +123,32 @@ func (ar *ActivityRepository) GetByEntity(entityType string, entityID int) ([]*A
 	return activities, nil
 }
 
-// Get recent activities with pagination
-func (ar *ActivityRepository) GetRecent(limit, offset int) ([]*Activity, error) {
+// Get recent activities with pagination, optionally limited to the tasks of one board
+func (ar *ActivityRepository) GetRecent(limit, offset int, boardID *int) ([]*Activity, error) {
 	query := `
 		SELECT 
 			a.id, a.entity_type, a.entity_id, a.action, a.field_name, 
-			a.old_value, a.new_value, a.user_id, a.created_at, a.updated_at,
+			a.old_value, a.new_value, a.old_ref, a.new_ref, a.user_id, a.created_at, a.updated_at,
 			u.name, u.username
 		FROM activities a
-		LEFT JOIN users u ON a.user_id = u.id
+		LEFT JOIN users u ON a.user_id = u.id`
+
+	var args []interface{}
+	if boardID != nil {
+		query += `
+		WHERE a.entity_type = 'task' AND a.entity_id IN (
+			SELECT t.id FROM tasks t JOIN columns c ON t.column_id = c.id WHERE c.board_id = ?
+		)`
+		args = append(args, *boardID)
+	}
+
+	query += `
 		ORDER BY a.created_at DESC
 		LIMIT ? OFFSET ?
 	`
+	args = append(args, limit, offset)
 
-	rows, err := ar.db.Instance().Query(query, limit, offset)
+	rows, err := ar.db.Instance().Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +159,7 @@ func (ar *ActivityRepository) GetRecent(limit, offset int) ([]*Activity, error)
 		activity := &Activity{}
 		err := rows.Scan(
 			&activity.ID, &activity.EntityType, &activity.EntityID, &activity.Action,
-			&activity.FieldName, &activity.OldValue, &activity.NewValue, &activity.UserID,
+			&activity.FieldName, &activity.OldValue, &activity.NewValue, &activity.OldRef, &activity.NewRef, &activity.UserID,
 			&activity.CreatedAt, &activity.UpdatedAt, &activity.UserName, &activity.Username,
 		)
 		if err != nil {
@@ -151,6 +171,74 @@ func (ar *ActivityRepository) GetRecent(limit, offset int) ([]*Activity, error)
 	return activities, nil
 }
 
+// Get the latest task changes a user made to the given fields, newest first
+func (ar *ActivityRepository) GetTaskChangesByUser(userID int, actions, fields []string, limit int) ([]*Activity, error) {
+	query := `
+		SELECT 
+			a.id, a.entity_type, a.entity_id, a.action, a.field_name, 
+			a.old_value, a.new_value, a.old_ref, a.new_ref, a.user_id, a.created_at, a.updated_at,
+			u.name, u.username
+		FROM activities a
+		LEFT JOIN users u ON a.user_id = u.id
+		WHERE a.entity_type = 'task' AND a.user_id = ?
+		  AND a.action IN (` + placeholders(len(actions)) + `)
+		  AND a.field_name IN (` + placeholders(len(fields)) + `)
+		  AND a.entity_id IN (` + liveTaskIDs + `)
+		ORDER BY a.id DESC
+		LIMIT ?
+	`
+
+	args := []interface{}{userID}
+	for _, action := range actions {
+		args = append(args, action)
+	}
+	for _, field := range fields {
+		args = append(args, field)
+	}
+	args = append(args, limit)
+
+	rows, err := ar.db.Instance().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var activities []*Activity
+	for rows.Next() {
+		activity := &Activity{}
+		err := rows.Scan(
+			&activity.ID, &activity.EntityType, &activity.EntityID, &activity.Action,
+			&activity.FieldName, &activity.OldValue, &activity.NewValue, &activity.OldRef, &activity.NewRef, &activity.UserID,
+			&activity.CreatedAt, &activity.UpdatedAt, &activity.UserName, &activity.Username,
+		)
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, activity)
+	}
+
+	return activities, rows.Err()
+}
+
+// Find the first activity changing the same field of the same entity after the given one, nil when there is none
+func (ar *ActivityRepository) FindLaterChange(activity *Activity) (*Activity, error) {
+	var laterID int
+	err := ar.db.Instance().QueryRow(`
+		SELECT id FROM activities
+		WHERE entity_type = ? AND entity_id = ? AND field_name = ? AND id > ?
+		ORDER BY id ASC
+		LIMIT 1`, activity.EntityType, activity.EntityID, activity.FieldName, activity.ID).Scan(&laterID)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ar.FindByID(laterID)
+}
+
 // Delete activities older than specified days
 func (ar *ActivityRepository) DeleteOlderThan(days int) error {
 	query := `
@@ -176,15 +264,51 @@ func (ar *ActivityRepository) RecordTaskUpdate(taskID, userID int, fieldName, ol
 	return err
 }
 
-// Record task deletion
+// Record task deletion (moved to the trash)
 func (ar *ActivityRepository) RecordTaskDeleted(taskID, userID int, taskTitle string) error {
 	_, err := ar.Create("task", taskID, "deleted", nil, &taskTitle, nil, userID)
 	return err
 }
 
+// Record task restored from the trash
+func (ar *ActivityRepository) RecordTaskRestored(taskID, userID int, taskTitle string) error {
+	_, err := ar.Create("task", taskID, "restored", nil, nil, &taskTitle, userID)
+	return err
+}
+
+// Record task permanently deleted from the trash
+func (ar *ActivityRepository) RecordTaskPurged(taskID, userID int, taskTitle string) error {
+	_, err := ar.Create("task", taskID, "purged", nil, &taskTitle, nil, userID)
+	return err
+}
+
+// Record task taken off the board
+func (ar *ActivityRepository) RecordTaskArchived(taskID, userID int, taskTitle string) error {
+	_, err := ar.Create("task", taskID, "archived", nil, nil, &taskTitle, userID)
+	return err
+}
+
+// Record task put back on the board
+func (ar *ActivityRepository) RecordTaskUnarchived(taskID, userID int, taskTitle string) error {
+	_, err := ar.Create("task", taskID, "unarchived", nil, nil, &taskTitle, userID)
+	return err
+}
+
 // Record task moved to different column
-func (ar *ActivityRepository) RecordTaskMoved(taskID, userID int, oldColumn string, newColumn string) error {
-	_, err := ar.Create("task", taskID, "moved", stringPtr("column"), &oldColumn, &newColumn, userID)
+func (ar *ActivityRepository) RecordTaskMoved(taskID, userID int, oldColumnID, newColumnID int, oldColumn string, newColumn string) error {
+	_, err := ar.CreateWithRefs("task", taskID, "moved", stringPtr("column"), &oldColumn, &newColumn, &oldColumnID, &newColumnID, userID)
+	return err
+}
+
+// Record task update of a field referencing another row, such as the assignee
+func (ar *ActivityRepository) RecordTaskRefUpdate(taskID, userID int, fieldName, oldValue, newValue string, oldRef, newRef *int) error {
+	_, err := ar.CreateWithRefs("task", taskID, "updated", &fieldName, &oldValue, &newValue, oldRef, newRef, userID)
+	return err
+}
+
+// Record a task change undone, the values are the ones of the revert itself
+func (ar *ActivityRepository) RecordTaskReverted(taskID, userID int, fieldName, oldValue, newValue string, oldRef, newRef *int) error {
+	_, err := ar.CreateWithRefs("task", taskID, "reverted", &fieldName, &oldValue, &newValue, oldRef, newRef, userID)
 	return err
 }
 
@@ -206,6 +330,24 @@ func (ar *ActivityRepository) RecordCommentCreated(taskID, userID int) error {
 	return err
 }
 
+// Record a user mentioned in a task description or comment
+func (ar *ActivityRepository) RecordMention(taskID, userID int, source, mentionedUsername string) error {
+	_, err := ar.Create("task", taskID, "mentioned", &source, nil, &mentionedUsername, userID)
+	return err
+}
+
+// Record a scheduled instance generated from a recurring template task
+func (ar *ActivityRepository) RecordTaskRecurred(templateID, userID int, instance string) error {
+	_, err := ar.Create("task", templateID, "recurred", stringPtr("instance"), nil, &instance, userID)
+	return err
+}
+
+// Record the template a scheduled task instance was generated from
+func (ar *ActivityRepository) RecordTaskGenerated(taskID, userID int, template string) error {
+	_, err := ar.Create("task", taskID, "generated", stringPtr("template"), nil, &template, userID)
+	return err
+}
+
 // Helper function to convert string to *string
 func stringPtr(s string) *string {
 	return &s
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+)
+
+const (
+	BackupRunSuccess = "success"
+	BackupRunFailed  = "failed"
+)
+
+// BackupRun is one scheduled backup, FileName and Size are only set when the archive was written
+type BackupRun struct {
+	ID         int       `json:"id"`
+	Status     string    `json:"status"`
+	Directory  string    `json:"directory"`
+	FileName   *string   `json:"file_name"`
+	Size       *int64    `json:"size"`
+	Pruned     int       `json:"pruned"` // Older backups removed by the rotation
+	Error      *string   `json:"error"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+type BackupRunRepository struct {
+	db *database.Database
+}
+
+func NewBackupRunRepository(db *database.Database) *BackupRunRepository {
+	return &BackupRunRepository{
+		db: db,
+	}
+}
+
+const backupRunSelect = `
+	SELECT id, status, directory, file_name, size, pruned, error, started_at, finished_at
+	FROM backup_runs`
+
+// Create records a finished backup run
+func (br *BackupRunRepository) Create(run *BackupRun) (*BackupRun, error) {
+	query := `
+		INSERT INTO backup_runs (status, directory, file_name, size, pruned, error, started_at, finished_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := br.db.Instance().Exec(query, run.Status, run.Directory, run.FileName, run.Size, run.Pruned, run.Error,
+		run.StartedAt.UTC().Format("2006-01-02 15:04:05"), run.FinishedAt.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return br.FindByID(int(id))
+}
+
+func (br *BackupRunRepository) FindByID(id int) (*BackupRun, error) {
+	run, err := br.scanRun(br.db.Instance().QueryRow(backupRunSelect+` WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("backup run not found")
+		}
+		return nil, err
+	}
+	return run, nil
+}
+
+// Latest returns the most recent runs, newest first
+func (br *BackupRunRepository) Latest(limit int) ([]*BackupRun, error) {
+	rows, err := br.db.Instance().Query(backupRunSelect+` ORDER BY started_at DESC, id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []*BackupRun{}
+	for rows.Next() {
+		run, err := br.scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// LastSuccess returns the most recent successful run, nil when there is none
+func (br *BackupRunRepository) LastSuccess() (*BackupRun, error) {
+	run, err := br.scanRun(br.db.Instance().QueryRow(backupRunSelect+` WHERE status = ? ORDER BY started_at DESC, id DESC LIMIT 1`, BackupRunSuccess))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return run, err
+}
+
+// DeleteOlderThanLatest keeps the history to the given number of runs
+func (br *BackupRunRepository) DeleteOlderThanLatest(keep int) error {
+	query := `
+		DELETE FROM backup_runs
+		WHERE id NOT IN (SELECT id FROM backup_runs ORDER BY started_at DESC, id DESC LIMIT ?)`
+
+	_, err := br.db.Instance().Exec(query, keep)
+	return err
+}
+
+func (br *BackupRunRepository) scanRun(row interface{ Scan(...interface{}) error }) (*BackupRun, error) {
+	run := &BackupRun{}
+	err := row.Scan(&run.ID, &run.Status, &run.Directory, &run.FileName, &run.Size, &run.Pruned, &run.Error,
+		&run.StartedAt, &run.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+)
+
+type Board struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Description *string   `json:"description"`
+	CreatedBy   *int      `json:"created_by"`
+	Position    int       `json:"position"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Related data (loaded separately)
+	ColumnCount int `json:"column_count,omitempty"`
+	TaskCount   int `json:"task_count,omitempty"`
+}
+
+type BoardRepository struct {
+	db *database.Database
+}
+
+func NewBoardRepository(db *database.Database) *BoardRepository {
+	return &BoardRepository{
+		db: db,
+	}
+}
+
+// Find board by ID
+func (br *BoardRepository) FindByID(id int) (*Board, error) {
+	board := &Board{}
+	query := `
+		SELECT id, title, description, created_by, position, created_at, updated_at
+		FROM boards
+		WHERE id = ?`
+
+	err := br.db.Instance().QueryRow(query, id).Scan(
+		&board.ID,
+		&board.Title,
+		&board.Description,
+		&board.CreatedBy,
+		&board.Position,
+		&board.CreatedAt,
+		&board.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("board not found")
+		}
+		return nil, err
+	}
+
+	return board, nil
+}
+
+// Get all boards with column and task counts
+func (br *BoardRepository) GetAll() ([]*Board, error) {
+	query := `
+		SELECT b.id, b.title, b.description, b.created_by, b.position, b.created_at, b.updated_at,
+		       (SELECT COUNT(*) FROM columns c WHERE c.board_id = b.id AND c.deleted_at IS NULL) as column_count,
+		       (SELECT COUNT(*) FROM tasks t JOIN columns c ON t.column_id = c.id WHERE c.board_id = b.id AND t.deleted_at IS NULL AND t.archived_at IS NULL) as task_count
+		FROM boards b
+		ORDER BY b.position ASC, b.created_at ASC`
+
+	rows, err := br.db.Instance().Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	boards := make([]*Board, 0)
+	for rows.Next() {
+		board := &Board{}
+		err := rows.Scan(
+			&board.ID,
+			&board.Title,
+			&board.Description,
+			&board.CreatedBy,
+			&board.Position,
+			&board.CreatedAt,
+			&board.UpdatedAt,
+			&board.ColumnCount,
+			&board.TaskCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		boards = append(boards, board)
+	}
+
+	return boards, nil
+}
+
+// Create new board at the end of the board list
+func (br *BoardRepository) Create(title string, description *string, createdBy int) (*Board, error) {
+	query := `
+		INSERT INTO boards (title, description, created_by, position, created_at, updated_at)
+		VALUES (?, ?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM boards), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+
+	result, err := br.db.Instance().Exec(query, title, description, createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return br.FindByID(int(id))
+}
+
+// Update board
+func (br *BoardRepository) Update(id int, title *string, description *string) (*Board, error) {
+	query := `
+		UPDATE boards
+		SET title = COALESCE(?, title),
+		    description = COALESCE(?, description),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	result, err := br.db.Instance().Exec(query, title, description, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, errors.New("board not found")
+	}
+
+	return br.FindByID(id)
+}
+
+// Delete board (only if it has no columns)
+func (br *BoardRepository) Delete(id int) error {
+	var columnCount int
+	err := br.db.Instance().QueryRow(`SELECT COUNT(*) FROM columns WHERE board_id = ?`, id).Scan(&columnCount)
+	if err != nil {
+		return err
+	}
+	if columnCount > 0 {
+		return errors.New("cannot delete board with existing columns")
+	}
+
+	result, err := br.db.Instance().Exec(`DELETE FROM boards WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("board not found")
+	}
+
+	return nil
+}
+
+// Count all boards
+func (br *BoardRepository) Count() (int, error) {
+	var count int
+	err := br.db.Instance().QueryRow(`SELECT COUNT(*) FROM boards`).Scan(&count)
+	return count, err
+}
+
+// Check if board title exists (for validation)
+func (br *BoardRepository) TitleExists(title string, excludeID *int) (bool, error) {
+	var query string
+	var args []interface{}
+
+	if excludeID != nil {
+		query = `SELECT COUNT(*) FROM boards WHERE title = ? AND id != ?`
+		args = []interface{}{title, *excludeID}
+	} else {
+		query = `SELECT COUNT(*) FROM boards WHERE title = ?`
+		args = []interface{}{title}
+	}
+
+	var count int
+	err := br.db.Instance().QueryRow(query, args...).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Get the first board, used when a request does not name one
+func (br *BoardRepository) GetDefault() (*Board, error) {
+	var id int
+	err := br.db.Instance().QueryRow(`SELECT id FROM boards ORDER BY position ASC, id ASC LIMIT 1`).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("board not found")
+		}
+		return nil, err
+	}
+
+	return br.FindByID(id)
+}
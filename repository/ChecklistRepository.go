@@ -59,7 +59,7 @@ func (r *ChecklistRepository) FindByID(id int) (*Checklist, error) {
 		FROM checklists c
 		LEFT JOIN users cu ON c.created_by = cu.id
 		LEFT JOIN users comu ON c.completed_by = comu.id
-		WHERE c.id = ?
+		WHERE c.id = ? AND c.task_id IN (` + liveTaskIDs + `)
 	`
 
 	row := r.db.Instance().QueryRow(query, id)
@@ -123,7 +123,7 @@ func (r *ChecklistRepository) FindByTaskID(taskID int) ([]*Checklist, error) {
 		FROM checklists c
 		LEFT JOIN users cu ON c.created_by = cu.id
 		LEFT JOIN users comu ON c.completed_by = comu.id
-		WHERE c.task_id = ?
+		WHERE c.task_id = ? AND c.task_id IN (` + liveTaskIDs + `)
 		ORDER BY c.created_at ASC
 	`
 
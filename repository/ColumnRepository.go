@@ -10,24 +10,52 @@ import (
 	"github.com/dev-parvej/js_array_method"
 	"github.com/dev-parvej/offline_kanban/pkg/database"
 	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/pkg/events"
 	"github.com/dev-parvej/offline_kanban/pkg/util"
 )
 
 type Column struct {
-	ID        int       `json:"id"`
-	Title     string    `json:"title"`
-	CreatedBy int       `json:"created_by"`
-	Colors    *string   `json:"colors"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              int       `json:"id"`
+	Title           string    `json:"title"`
+	BoardID         int       `json:"board_id"`
+	CreatedBy       int       `json:"created_by"`
+	Colors          *string   `json:"colors"`
+	WipLimit        *int      `json:"wip_limit"`         // NULL means no limit
+	IsDone          bool      `json:"is_done"`           // Tasks in this column count as finished
+	AutoArchiveDays *int      `json:"auto_archive_days"` // Days before done tasks here are archived, NULL follows the app setting
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 
 	// Related data (loaded separately)
 	CreatedByUser *User        `json:"created_by_user,omitempty"`
 	TaskCount     int          `json:"task_count,omitempty"`
+	WipState      string       `json:"wip_state,omitempty"` // none, under, at_limit, over_limit
 	Position      int          `json:"position,omitempty"`
 	DeletedAt     sql.NullTime `json:"deleted_at,omitempty"`
 }
 
+// WIP states reported with task counts
+const (
+	WipStateNone      = "none"
+	WipStateUnder     = "under"
+	WipStateAtLimit   = "at_limit"
+	WipStateOverLimit = "over_limit"
+)
+
+// WipLimitError is returned when tasks would push a column past its WIP limit
+type WipLimitError struct {
+	Message     string `json:"message"`
+	ColumnID    int    `json:"column_id"`
+	ColumnTitle string `json:"column_title"`
+	WipLimit    int    `json:"wip_limit"`
+	TaskCount   int    `json:"task_count"`
+	Incoming    int    `json:"incoming"`
+}
+
+func (e *WipLimitError) Error() string {
+	return e.Message
+}
+
 type ColumnRepository struct {
 	db *database.Database
 }
@@ -42,15 +70,19 @@ func NewColumnRepository(db *database.Database) *ColumnRepository {
 func (cr *ColumnRepository) FindByID(id int) (*Column, error) {
 	column := &Column{}
 	query := `
-		SELECT id, title, created_by, colors, created_at, updated_at, position, deleted_at
+		SELECT id, title, board_id, created_by, colors, wip_limit, is_done, auto_archive_days, created_at, updated_at, position, deleted_at
 		FROM columns 
 		WHERE id = ?`
 
 	err := cr.db.Instance().QueryRow(query, id).Scan(
 		&column.ID,
 		&column.Title,
+		&column.BoardID,
 		&column.CreatedBy,
 		&column.Colors,
+		&column.WipLimit,
+		&column.IsDone,
+		&column.AutoArchiveDays,
 		&column.CreatedAt,
 		&column.UpdatedAt,
 		&column.Position,
@@ -72,7 +104,7 @@ func (cr *ColumnRepository) FindByIDs(ids []int) ([]*Column, error) {
 	placeholders := strings.Repeat("?,", len(ids))
 	placeholders = placeholders[:len(placeholders)-1] // remove last comma
 
-	query := fmt.Sprintf(`SELECT id, title, created_by, colors, created_at, updated_at, position
+	query := fmt.Sprintf(`SELECT id, title, board_id, created_by, colors, wip_limit, is_done, auto_archive_days, created_at, updated_at, position
                       FROM columns 
                       WHERE id IN (%s)`, placeholders)
 
@@ -85,13 +117,35 @@ func (cr *ColumnRepository) FindByIDs(ids []int) ([]*Column, error) {
 	return cr.scanColumns(rows)
 }
 
+// Find the columns with a title, ignoring case, optionally on one board. Deleted columns are left out
+func (cr *ColumnRepository) FindByTitle(title string, boardID *int) ([]*Column, error) {
+	query := `
+		SELECT id, title, board_id, created_by, colors, wip_limit, is_done, auto_archive_days, created_at, updated_at, position
+		FROM columns
+		WHERE title = ? COLLATE NOCASE AND deleted_at IS NULL`
+	args := []interface{}{title}
+
+	if boardID != nil {
+		query += ` AND board_id = ?`
+		args = append(args, *boardID)
+	}
+
+	rows, err := cr.db.Instance().Query(query+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return cr.scanColumns(rows)
+}
+
 // Create new column
-func (cr *ColumnRepository) Create(title string, createdBy int, colors *string) (*Column, error) {
+func (cr *ColumnRepository) Create(title string, boardID, createdBy int, colors *string) (*Column, error) {
 	query := `
-		INSERT INTO columns (title, created_by, colors, created_at, updated_at)
-		VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+		INSERT INTO columns (title, board_id, created_by, colors, created_at, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
 
-	result, err := cr.db.Instance().Exec(query, title, createdBy, colors)
+	result, err := cr.db.Instance().Exec(query, title, boardID, createdBy, colors)
 	if err != nil {
 		return nil, err
 	}
@@ -101,7 +155,14 @@ func (cr *ColumnRepository) Create(title string, createdBy int, colors *string)
 		return nil, err
 	}
 
-	return cr.FindByID(int(id))
+	column, err := cr.FindByID(int(id))
+	if err != nil {
+		return nil, err
+	}
+
+	cr.publish(events.ColumnCreated, column.BoardID, column)
+
+	return column, nil
 }
 
 // Update column
@@ -127,7 +188,140 @@ func (cr *ColumnRepository) Update(id int, title *string, colors *string) (*Colu
 		return nil, errors.New("column not found")
 	}
 
-	return cr.FindByID(id)
+	column, err := cr.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	cr.publish(events.ColumnUpdated, column.BoardID, column)
+
+	return column, nil
+}
+
+// Set or clear (nil) the WIP limit of a column
+func (cr *ColumnRepository) SetWipLimit(id int, wipLimit *int) (*Column, error) {
+	query := `
+		UPDATE columns
+		SET wip_limit = ?,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	result, err := cr.db.Instance().Exec(query, wipLimit, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, errors.New("column not found")
+	}
+
+	column, err := cr.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	cr.publish(events.ColumnUpdated, column.BoardID, column)
+
+	return column, nil
+}
+
+// Flag or unflag a column as holding finished tasks
+func (cr *ColumnRepository) SetDone(id int, isDone bool) (*Column, error) {
+	query := `
+		UPDATE columns
+		SET is_done = ?,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	result, err := cr.db.Instance().Exec(query, isDone, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, errors.New("column not found")
+	}
+
+	column, err := cr.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	cr.publish(events.ColumnUpdated, column.BoardID, column)
+
+	return column, nil
+}
+
+// Set the days before done tasks in a column are archived, or follow the app setting (nil)
+func (cr *ColumnRepository) SetAutoArchiveDays(id int, days *int) (*Column, error) {
+	query := `
+		UPDATE columns
+		SET auto_archive_days = ?,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	result, err := cr.db.Instance().Exec(query, days, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, errors.New("column not found")
+	}
+
+	column, err := cr.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	cr.publish(events.ColumnUpdated, column.BoardID, column)
+
+	return column, nil
+}
+
+// Check that a column can take the incoming number of tasks, returns a *WipLimitError when it can't
+func (cr *ColumnRepository) CheckWipLimit(columnID, incoming int) error {
+	column, err := cr.FindByID(columnID)
+	if err != nil {
+		return err
+	}
+
+	if column.WipLimit == nil {
+		return nil
+	}
+
+	count, err := cr.GetTaskCount(columnID)
+	if err != nil {
+		return err
+	}
+
+	if count+incoming <= *column.WipLimit {
+		return nil
+	}
+
+	return &WipLimitError{
+		Message:     fmt.Sprintf("Column '%s' has a WIP limit of %d and already holds %d tasks", column.Title, *column.WipLimit, count),
+		ColumnID:    column.ID,
+		ColumnTitle: column.Title,
+		WipLimit:    *column.WipLimit,
+		TaskCount:   count,
+		Incoming:    incoming,
+	}
 }
 
 func (cr *ColumnRepository) Archive(id int) error {
@@ -152,6 +346,10 @@ func (cr *ColumnRepository) Archive(id int) error {
 		return errors.New("column not found")
 	}
 
+	if column, err := cr.FindByID(id); err == nil {
+		cr.publish(events.ColumnArchived, column.BoardID, column)
+	}
+
 	return nil
 }
 
@@ -177,6 +375,10 @@ func (cr *ColumnRepository) UnArchive(id int) error {
 		return errors.New("column not found")
 	}
 
+	if column, err := cr.FindByID(id); err == nil {
+		cr.publish(events.ColumnUnarchived, column.BoardID, column)
+	}
+
 	return nil
 }
 
@@ -210,14 +412,15 @@ func (cr *ColumnRepository) Delete(id int) error {
 	return nil
 }
 
-// Get all columns
-func (cr *ColumnRepository) GetAll() ([]*Column, error) {
+// Get all columns of a board
+func (cr *ColumnRepository) GetAll(boardID int) ([]*Column, error) {
 	query := `
-		SELECT id, title, created_by, colors, created_at, updated_at, position
+		SELECT id, title, board_id, created_by, colors, wip_limit, is_done, auto_archive_days, created_at, updated_at, position
 		FROM columns 
+		WHERE board_id = ?
 		ORDER BY created_at ASC`
 
-	rows, err := cr.db.Instance().Query(query)
+	rows, err := cr.db.Instance().Query(query, boardID)
 	if err != nil {
 		return nil, err
 	}
@@ -226,21 +429,22 @@ func (cr *ColumnRepository) GetAll() ([]*Column, error) {
 	return cr.scanColumns(rows)
 }
 
-// Get columns with task counts
-func (cr *ColumnRepository) GetAllWithTaskCounts(showArchived bool) ([]*Column, error) {
+// Get columns of a board with task counts
+func (cr *ColumnRepository) GetAllWithTaskCounts(boardID int, showArchived bool) ([]*Column, error) {
 	query := `
-		SELECT c.id, c.title, c.created_by, c.colors, c.created_at, c.updated_at,
+		SELECT c.id, c.title, c.board_id, c.created_by, c.colors, c.wip_limit, c.is_done, c.auto_archive_days, c.created_at, c.updated_at,
 		       COUNT(t.id) as task_count, c.position, c.deleted_at
 		FROM columns c
-		LEFT JOIN tasks t ON c.id = t.column_id`
+		LEFT JOIN tasks t ON c.id = t.column_id AND t.deleted_at IS NULL AND t.archived_at IS NULL
+		WHERE c.board_id = ?`
 
 	if !showArchived {
-		query += ` WHERE c.deleted_at IS NULL `
+		query += ` AND c.deleted_at IS NULL `
 	}
 
 	query += ` GROUP BY c.id, c.title, c.created_by, c.colors, c.created_at, c.updated_at ORDER BY c.position ASC`
 
-	rows, err := cr.db.Instance().Query(query)
+	rows, err := cr.db.Instance().Query(query, boardID)
 	if err != nil {
 		return nil, err
 	}
@@ -252,8 +456,12 @@ func (cr *ColumnRepository) GetAllWithTaskCounts(showArchived bool) ([]*Column,
 		err := rows.Scan(
 			&column.ID,
 			&column.Title,
+			&column.BoardID,
 			&column.CreatedBy,
 			&column.Colors,
+			&column.WipLimit,
+			&column.IsDone,
+			&column.AutoArchiveDays,
 			&column.CreatedAt,
 			&column.UpdatedAt,
 			&column.TaskCount,
@@ -263,26 +471,28 @@ func (cr *ColumnRepository) GetAllWithTaskCounts(showArchived bool) ([]*Column,
 		if err != nil {
 			return nil, err
 		}
+		column.WipState = wipState(column.WipLimit, column.TaskCount)
 		columns = append(columns, column)
 	}
 
 	return columns, nil
 }
 
-// Get columns with related data (creator info)
-func (cr *ColumnRepository) GetAllWithCreators() ([]*Column, error) {
+// Get columns of a board with related data (creator info)
+func (cr *ColumnRepository) GetAllWithCreators(boardID int) ([]*Column, error) {
 	query := `
-		SELECT c.id, c.title, c.created_by, c.colors, c.created_at, c.updated_at,
+		SELECT c.id, c.title, c.board_id, c.created_by, c.colors, c.wip_limit, c.is_done, c.created_at, c.updated_at,
 		       u.username as creator_username, u.name as creator_name,
-		       COUNT(t.id) as task_count
+		       COUNT(t.id) as task_count, c.position
 		FROM columns c
 		LEFT JOIN users u ON c.created_by = u.id
-		LEFT JOIN tasks t ON c.id = t.column_id
+		LEFT JOIN tasks t ON c.id = t.column_id AND t.deleted_at IS NULL AND t.archived_at IS NULL
+		WHERE c.board_id = ?
 		GROUP BY c.id, c.title, c.created_by, c.colors, c.created_at, c.updated_at,
 		         u.username, u.name
 		ORDER BY c.created_at ASC`
 
-	rows, err := cr.db.Instance().Query(query)
+	rows, err := cr.db.Instance().Query(query, boardID)
 	if err != nil {
 		return nil, err
 	}
@@ -296,8 +506,11 @@ func (cr *ColumnRepository) GetAllWithCreators() ([]*Column, error) {
 		err := rows.Scan(
 			&column.ID,
 			&column.Title,
+			&column.BoardID,
 			&column.CreatedBy,
 			&column.Colors,
+			&column.WipLimit,
+			&column.IsDone,
 			&column.CreatedAt,
 			&column.UpdatedAt,
 			&creatorUsername,
@@ -326,7 +539,7 @@ func (cr *ColumnRepository) GetAllWithCreators() ([]*Column, error) {
 // Check if column has tasks
 func (cr *ColumnRepository) HasTasks(columnID int) (bool, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM tasks WHERE column_id = ?`
+	query := `SELECT COUNT(*) FROM tasks WHERE column_id = ? AND deleted_at IS NULL`
 
 	err := cr.db.Instance().QueryRow(query, columnID).Scan(&count)
 	if err != nil {
@@ -336,26 +549,26 @@ func (cr *ColumnRepository) HasTasks(columnID int) (bool, error) {
 	return count > 0, nil
 }
 
-// Get task count for column
+// Get count of the tasks on the board in a column, archived ones aside
 func (cr *ColumnRepository) GetTaskCount(columnID int) (int, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM tasks WHERE column_id = ?`
+	query := `SELECT COUNT(*) FROM tasks WHERE column_id = ? AND deleted_at IS NULL AND archived_at IS NULL`
 
 	err := cr.db.Instance().QueryRow(query, columnID).Scan(&count)
 	return count, err
 }
 
-// Check if column title exists (for validation)
-func (cr *ColumnRepository) TitleExists(title string, excludeID *int) (bool, error) {
+// Check if column title exists on a board (for validation)
+func (cr *ColumnRepository) TitleExists(boardID int, title string, excludeID *int) (bool, error) {
 	var query string
 	var args []interface{}
 
 	if excludeID != nil {
-		query = `SELECT COUNT(*) FROM columns WHERE title = ? AND id != ?`
-		args = []interface{}{title, *excludeID}
+		query = `SELECT COUNT(*) FROM columns WHERE board_id = ? AND title = ? AND id != ?`
+		args = []interface{}{boardID, title, *excludeID}
 	} else {
-		query = `SELECT COUNT(*) FROM columns WHERE title = ?`
-		args = []interface{}{title}
+		query = `SELECT COUNT(*) FROM columns WHERE board_id = ? AND title = ?`
+		args = []interface{}{boardID, title}
 	}
 
 	var count int
@@ -367,7 +580,7 @@ func (cr *ColumnRepository) TitleExists(title string, excludeID *int) (bool, err
 	return count > 0, nil
 }
 
-func (cr *ColumnRepository) Reorder(columnOrders []dto.ColumnsOrder) error {
+func (cr *ColumnRepository) Reorder(boardID int, columnOrders []dto.ColumnsOrder) error {
 	tx, err := cr.db.Instance().Begin()
 	if err != nil {
 		return err
@@ -379,13 +592,19 @@ func (cr *ColumnRepository) Reorder(columnOrders []dto.ColumnsOrder) error {
 			UPDATE columns
 			SET position = ?,
 			    updated_at = CURRENT_TIMESTAMP
-			WHERE id = ?`, order.Position, order.ID)
+			WHERE id = ? AND board_id = ?`, order.Position, order.ID, boardID)
 		if err != nil {
 			panic(err)
 		}
 	})
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	cr.publish(events.ColumnsReordered, boardID, columnOrders)
+
+	return nil
 }
 
 // Move all tasks from one column to another
@@ -414,19 +633,31 @@ func (cr *ColumnRepository) MoveAllTasks(fromColumnID, toColumnID int) error {
 		UPDATE tasks 
 		SET column_id = ?, 
 		    position = position + ?,
+		    moved_at = CURRENT_TIMESTAMP,
 		    updated_at = CURRENT_TIMESTAMP
 		WHERE column_id = ?`, toColumnID, startPosition-1, fromColumnID)
 	if err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if column, err := cr.FindByID(toColumnID); err == nil {
+		cr.publish(events.ColumnTasksMoved, column.BoardID, map[string]int{
+			"from_column_id": fromColumnID,
+			"to_column_id":   toColumnID,
+		})
+	}
+
+	return nil
 }
 
 // Get columns created by specific user
 func (cr *ColumnRepository) GetByCreator(createdBy int) ([]*Column, error) {
 	query := `
-		SELECT id, title, created_by, colors, created_at, updated_at
+		SELECT id, title, board_id, created_by, colors, wip_limit, is_done, auto_archive_days, created_at, updated_at, position
 		FROM columns 
 		WHERE created_by = ?
 		ORDER BY created_at ASC`
@@ -441,15 +672,19 @@ func (cr *ColumnRepository) GetByCreator(createdBy int) ([]*Column, error) {
 }
 
 // Helper method to scan columns
-func (cr *ColumnRepository) scanColumns(rows *sql.Rows) ([]*Column, error) {
+func (cr *ColumnRepository) scanColumns(rows *database.Rows) ([]*Column, error) {
 	columns := make([]*Column, 0)
 	for rows.Next() {
 		column := &Column{}
 		err := rows.Scan(
 			&column.ID,
 			&column.Title,
+			&column.BoardID,
 			&column.CreatedBy,
 			&column.Colors,
+			&column.WipLimit,
+			&column.IsDone,
+			&column.AutoArchiveDays,
 			&column.CreatedAt,
 			&column.UpdatedAt,
 			&column.Position,
@@ -461,3 +696,28 @@ func (cr *ColumnRepository) scanColumns(rows *sql.Rows) ([]*Column, error) {
 	}
 	return columns, nil
 }
+
+// Helper to describe how full a column is compared to its WIP limit
+func wipState(wipLimit *int, taskCount int) string {
+	switch {
+	case wipLimit == nil:
+		return WipStateNone
+	case taskCount > *wipLimit:
+		return WipStateOverLimit
+	case taskCount == *wipLimit:
+		return WipStateAtLimit
+	default:
+		return WipStateUnder
+	}
+}
+
+// Helper method to push a column event to the column's board
+func (cr *ColumnRepository) publish(eventType string, boardID int, data interface{}) {
+	cr.db.AfterCommit(func() {
+		events.Publish(events.Event{
+			Type:    eventType,
+			BoardID: &boardID,
+			Data:    data,
+		})
+	})
+}
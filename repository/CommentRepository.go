@@ -58,7 +58,7 @@ func (cr *CommentRepository) FindByID(id int) (*Comment, error) {
 		       u.name, u.username
 		FROM comments c
 		LEFT JOIN users u ON c.created_by = u.id
-		WHERE c.id = ?`
+		WHERE c.id = ? AND c.task_id IN (` + liveTaskIDs + `)`
 
 	err := cr.db.Instance().QueryRow(query, id).Scan(
 		&comment.ID,
@@ -88,7 +88,7 @@ func (cr *CommentRepository) GetByTaskID(taskID int) ([]*Comment, error) {
 		       u.name, u.username
 		FROM comments c
 		LEFT JOIN users u ON c.created_by = u.id
-		WHERE c.task_id = ?
+		WHERE c.task_id = ? AND c.task_id IN (` + liveTaskIDs + `)
 		ORDER BY c.created_at ASC`
 
 	rows, err := cr.db.Instance().Query(query, taskID)
@@ -178,7 +178,7 @@ func (cr *CommentRepository) Delete(id int, userID int) error {
 // Get comment count for a task
 func (cr *CommentRepository) GetCommentCount(taskID int) (int, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM comments WHERE task_id = ?`
+	query := `SELECT COUNT(*) FROM comments WHERE task_id = ? AND task_id IN (` + liveTaskIDs + `)`
 	
 	err := cr.db.Instance().QueryRow(query, taskID).Scan(&count)
 	if err != nil {
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+)
+
+// IdempotencyKey is a request sent with an Idempotency-Key header and, once it was handled, its response
+type IdempotencyKey struct {
+	ID           int
+	UserID       int
+	Key          string
+	Method       string
+	Path         string
+	RequestHash  string
+	StatusCode   *int // nil while the request is still being handled
+	ContentType  *string
+	ResponseBody []byte
+	CreatedAt    time.Time
+}
+
+type IdempotencyRepository struct {
+	db *database.Database
+}
+
+func NewIdempotencyRepository(db *database.Database) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db: db,
+	}
+}
+
+const idempotencyKeySelect = `
+	SELECT id, user_id, idempotency_key, method, path, request_hash, status_code, content_type, response_body, created_at
+	FROM idempotency_keys`
+
+// Reserve claims a key for a request about to be handled. When the user already used the key it
+// returns the earlier request instead, with reserved false
+func (ir *IdempotencyRepository) Reserve(userID int, key, method, path, requestHash string) (*IdempotencyKey, bool, error) {
+	query := `
+		INSERT OR IGNORE INTO idempotency_keys (user_id, idempotency_key, method, path, request_hash)
+		VALUES (?, ?, ?, ?, ?)`
+
+	result, err := ir.db.Instance().Exec(query, userID, key, method, path, requestHash)
+	if err != nil {
+		return nil, false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+
+	record, err := ir.FindByKey(userID, key)
+	if err != nil {
+		return nil, false, err
+	}
+	return record, affected > 0, nil
+}
+
+// FindByKey returns the request the user sent with the key, nil when there is none
+func (ir *IdempotencyRepository) FindByKey(userID int, key string) (*IdempotencyKey, error) {
+	record := &IdempotencyKey{}
+	err := ir.db.Instance().QueryRow(idempotencyKeySelect+` WHERE user_id = ? AND idempotency_key = ?`, userID, key).Scan(
+		&record.ID, &record.UserID, &record.Key, &record.Method, &record.Path, &record.RequestHash,
+		&record.StatusCode, &record.ContentType, &record.ResponseBody, &record.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Complete stores the response of a reserved request for replaying it
+func (ir *IdempotencyRepository) Complete(id, statusCode int, contentType string, body []byte) error {
+	query := `UPDATE idempotency_keys SET status_code = ?, content_type = NULLIF(?, ''), response_body = ? WHERE id = ?`
+	_, err := ir.db.Instance().Exec(query, statusCode, contentType, body, id)
+	return err
+}
+
+// Release frees a reserved key so the request can be retried
+func (ir *IdempotencyRepository) Release(id int) error {
+	_, err := ir.db.Instance().Exec(`DELETE FROM idempotency_keys WHERE id = ?`, id)
+	return err
+}
+
+// DeleteExpired forgets the keys used before the given time
+func (ir *IdempotencyRepository) DeleteExpired(before time.Time) error {
+	_, err := ir.db.Instance().Exec(`DELETE FROM idempotency_keys WHERE created_at < ?`, before.UTC().Format("2006-01-02 15:04:05"))
+	return err
+}
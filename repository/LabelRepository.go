@@ -0,0 +1,309 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+)
+
+type Label struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	CreatedBy *int      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Related data (loaded separately)
+	TaskCount int `json:"task_count"`
+}
+
+type LabelRepository struct {
+	db *database.Database
+}
+
+func NewLabelRepository(db *database.Database) *LabelRepository {
+	return &LabelRepository{
+		db: db,
+	}
+}
+
+// Find label by ID
+func (lr *LabelRepository) FindByID(id int) (*Label, error) {
+	label := &Label{}
+	query := `
+		SELECT l.id, l.name, l.color, l.created_by, l.created_at, l.updated_at,
+		       (SELECT COUNT(*) FROM task_labels tl WHERE tl.label_id = l.id AND tl.task_id IN (` + liveTaskIDs + `)) as task_count
+		FROM labels l
+		WHERE l.id = ?`
+
+	err := lr.db.Instance().QueryRow(query, id).Scan(
+		&label.ID,
+		&label.Name,
+		&label.Color,
+		&label.CreatedBy,
+		&label.CreatedAt,
+		&label.UpdatedAt,
+		&label.TaskCount,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("label not found")
+		}
+		return nil, err
+	}
+
+	return label, nil
+}
+
+// Get all labels with the number of tasks using them
+func (lr *LabelRepository) GetAll() ([]*Label, error) {
+	query := `
+		SELECT l.id, l.name, l.color, l.created_by, l.created_at, l.updated_at,
+		       (SELECT COUNT(*) FROM task_labels tl WHERE tl.label_id = l.id AND tl.task_id IN (` + liveTaskIDs + `)) as task_count
+		FROM labels l
+		ORDER BY l.name COLLATE NOCASE ASC`
+
+	rows, err := lr.db.Instance().Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels := make([]*Label, 0)
+	for rows.Next() {
+		label := &Label{}
+		err := rows.Scan(
+			&label.ID,
+			&label.Name,
+			&label.Color,
+			&label.CreatedBy,
+			&label.CreatedAt,
+			&label.UpdatedAt,
+			&label.TaskCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, label)
+	}
+
+	return labels, nil
+}
+
+// Create new label
+func (lr *LabelRepository) Create(name, color string, createdBy int) (*Label, error) {
+	query := `
+		INSERT INTO labels (name, color, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+
+	result, err := lr.db.Instance().Exec(query, name, color, createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return lr.FindByID(int(id))
+}
+
+// Update label
+func (lr *LabelRepository) Update(id int, name, color *string) (*Label, error) {
+	query := `
+		UPDATE labels
+		SET name = COALESCE(?, name),
+		    color = COALESCE(?, color),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	result, err := lr.db.Instance().Exec(query, name, color, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, errors.New("label not found")
+	}
+
+	return lr.FindByID(id)
+}
+
+// Delete label and remove it from every task
+func (lr *LabelRepository) Delete(id int) error {
+	tx, err := lr.db.Instance().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM task_labels WHERE label_id = ?`, id); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM labels WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("label not found")
+	}
+
+	return tx.Commit()
+}
+
+// Check if label name exists, ignoring case (for validation)
+func (lr *LabelRepository) NameExists(name string, excludeID *int) (bool, error) {
+	var query string
+	var args []interface{}
+
+	if excludeID != nil {
+		query = `SELECT COUNT(*) FROM labels WHERE name = ? COLLATE NOCASE AND id != ?`
+		args = []interface{}{name, *excludeID}
+	} else {
+		query = `SELECT COUNT(*) FROM labels WHERE name = ? COLLATE NOCASE`
+		args = []interface{}{name}
+	}
+
+	var count int
+	err := lr.db.Instance().QueryRow(query, args...).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Check that every given label exists
+func (lr *LabelRepository) AllExist(ids []int) (bool, error) {
+	unique := uniqueIDs(ids)
+	if len(unique) == 0 {
+		return true, nil
+	}
+
+	query := `SELECT COUNT(*) FROM labels WHERE id IN (` + placeholders(len(unique)) + `)`
+
+	var count int
+	err := lr.db.Instance().QueryRow(query, util.ConvertToInterface(unique)...).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count == len(unique), nil
+}
+
+// Get the labels of a task
+func (lr *LabelRepository) GetByTask(taskID int) ([]*Label, error) {
+	byTask, err := lr.GetByTasks([]int{taskID})
+	if err != nil {
+		return nil, err
+	}
+
+	labels := byTask[taskID]
+	if labels == nil {
+		labels = make([]*Label, 0)
+	}
+
+	return labels, nil
+}
+
+// Get the labels of several tasks at once, keyed by task ID
+func (lr *LabelRepository) GetByTasks(taskIDs []int) (map[int][]*Label, error) {
+	byTask := make(map[int][]*Label)
+	if len(taskIDs) == 0 {
+		return byTask, nil
+	}
+
+	query := `
+		SELECT tl.task_id, l.id, l.name, l.color, l.created_by, l.created_at, l.updated_at
+		FROM task_labels tl
+		JOIN labels l ON tl.label_id = l.id
+		WHERE tl.task_id IN (` + placeholders(len(taskIDs)) + `)
+		ORDER BY l.name COLLATE NOCASE ASC`
+
+	rows, err := lr.db.Instance().Query(query, util.ConvertToInterface(taskIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int
+		label := &Label{}
+		err := rows.Scan(
+			&taskID,
+			&label.ID,
+			&label.Name,
+			&label.Color,
+			&label.CreatedBy,
+			&label.CreatedAt,
+			&label.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		byTask[taskID] = append(byTask[taskID], label)
+	}
+
+	return byTask, nil
+}
+
+// Replace the labels of a task
+func (lr *LabelRepository) SetTaskLabels(taskID int, labelIDs []int) error {
+	tx, err := lr.db.Instance().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM task_labels WHERE task_id = ?`, taskID); err != nil {
+		return err
+	}
+
+	for _, labelID := range uniqueIDs(labelIDs) {
+		_, err := tx.Exec(`
+			INSERT INTO task_labels (task_id, label_id, created_at)
+			VALUES (?, ?, CURRENT_TIMESTAMP)`, taskID, labelID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// placeholders returns "?,?,?" for an IN (...) clause with n values
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// uniqueIDs drops repeated IDs, keeping the first occurrence
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool)
+	var unique []int
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+)
+
+type Mention struct {
+	ID              int       `json:"id"`
+	TaskID          int       `json:"task_id"`
+	CommentID       *int      `json:"comment_id"`
+	MentionedUserID int       `json:"mentioned_user_id"`
+	MentionedBy     int       `json:"mentioned_by"`
+	Source          string    `json:"source"` // description, comment
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type MentionRepository struct {
+	db *database.Database
+}
+
+func NewMentionRepository(db *database.Database) *MentionRepository {
+	return &MentionRepository{
+		db: db,
+	}
+}
+
+// Create a new mention record
+func (mr *MentionRepository) Create(taskID int, commentID *int, mentionedUserID, mentionedBy int, source string) (*Mention, error) {
+	query := `
+		INSERT INTO mentions (task_id, comment_id, mentioned_user_id, mentioned_by, source, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+
+	result, err := mr.db.Instance().Exec(query, taskID, commentID, mentionedUserID, mentionedBy, source)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return mr.FindByID(int(id))
+}
+
+// Find mention by ID
+func (mr *MentionRepository) FindByID(id int) (*Mention, error) {
+	mention := &Mention{}
+	query := `
+		SELECT id, task_id, comment_id, mentioned_user_id, mentioned_by, source, created_at, updated_at
+		FROM mentions
+		WHERE id = ?`
+
+	err := mr.db.Instance().QueryRow(query, id).Scan(
+		&mention.ID, &mention.TaskID, &mention.CommentID, &mention.MentionedUserID,
+		&mention.MentionedBy, &mention.Source, &mention.CreatedAt, &mention.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return mention, nil
+}
+
+// Delete the mentions made in a comment
+func (mr *MentionRepository) DeleteByComment(commentID int) error {
+	_, err := mr.db.Instance().Exec(`DELETE FROM mentions WHERE comment_id = ?`, commentID)
+	return err
+}
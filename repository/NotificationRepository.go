@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+)
+
+type Notification struct {
+	ID          int        `json:"id"`
+	RecipientID int        `json:"recipient_id"`
+	SenderID    *int       `json:"sender_id"`
+	Type        string     `json:"type"`
+	Title       string     `json:"title"`
+	Message     string     `json:"message"`
+	TaskID      *int       `json:"task_id"`
+	CommentID   *int       `json:"comment_id"`
+	Data        *string    `json:"data"`
+	IsRead      bool       `json:"is_read"`
+	IsSystem    bool       `json:"is_system"`
+	ReadAt      *time.Time `json:"read_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// Related data (loaded with joins)
+	SenderName     *string `json:"sender_name,omitempty"`
+	SenderUsername *string `json:"sender_username,omitempty"`
+}
+
+type NotificationRepository struct {
+	db *database.Database
+}
+
+func NewNotificationRepository(db *database.Database) *NotificationRepository {
+	return &NotificationRepository{
+		db: db,
+	}
+}
+
+const notificationSelect = `
+	SELECT n.id, n.recipient_id, n.sender_id, n.type, n.title, n.message,
+	       n.task_id, n.comment_id, n.data, n.is_read, n.is_system, n.read_at,
+	       n.created_at, n.updated_at,
+	       u.name, u.username
+	FROM notifications n
+	LEFT JOIN users u ON n.sender_id = u.id`
+
+// Notifications about trashed tasks are hidden until the task is restored
+const liveNotificationTask = `(n.task_id IS NULL OR n.task_id IN (` + liveTaskIDs + `))`
+
+// Create a new notification
+func (nr *NotificationRepository) Create(recipientID int, senderID *int, notificationType, title, message string,
+	taskID, commentID *int, data *string, isSystem bool) (*Notification, error) {
+
+	query := `
+		INSERT INTO notifications (recipient_id, sender_id, type, title, message, task_id, comment_id,
+		                           data, is_read, is_system, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 0, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+
+	result, err := nr.db.Instance().Exec(query, recipientID, senderID, notificationType, title, message,
+		taskID, commentID, data, isSystem)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return nr.FindByID(int(id))
+}
+
+// Find notification by ID with sender information
+func (nr *NotificationRepository) FindByID(id int) (*Notification, error) {
+	row := nr.db.Instance().QueryRow(notificationSelect+` WHERE n.id = ?`, id)
+
+	notification, err := nr.scanNotification(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("notification not found")
+		}
+		return nil, err
+	}
+
+	return notification, nil
+}
+
+// Get notifications of a user, newest first
+func (nr *NotificationRepository) GetByRecipient(recipientID, limit, offset int, unreadOnly bool) ([]*Notification, error) {
+	query := notificationSelect + ` WHERE n.recipient_id = ? AND ` + liveNotificationTask
+	if unreadOnly {
+		query += ` AND n.is_read = 0`
+	}
+	query += ` ORDER BY n.created_at DESC, n.id DESC LIMIT ? OFFSET ?`
+
+	rows, err := nr.db.Instance().Query(query, recipientID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*Notification
+	for rows.Next() {
+		notification, err := nr.scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
+
+// Count notifications of a user
+func (nr *NotificationRepository) CountByRecipient(recipientID int, unreadOnly bool) (int, error) {
+	query := `SELECT COUNT(*) FROM notifications n WHERE n.recipient_id = ? AND ` + liveNotificationTask
+	if unreadOnly {
+		query += ` AND n.is_read = 0`
+	}
+
+	var count int
+	err := nr.db.Instance().QueryRow(query, recipientID).Scan(&count)
+	return count, err
+}
+
+// Mark a notification of the user as read
+func (nr *NotificationRepository) MarkRead(id, recipientID int) (*Notification, error) {
+	query := `
+		UPDATE notifications
+		SET is_read = 1,
+		    read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
+		WHERE id = ? AND recipient_id = ?`
+
+	result, err := nr.db.Instance().Exec(query, id, recipientID)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, errors.New("notification not found")
+	}
+
+	return nr.FindByID(id)
+}
+
+// Mark every unread notification of the user as read, returns how many were updated
+func (nr *NotificationRepository) MarkAllRead(recipientID int) (int, error) {
+	query := `
+		UPDATE notifications
+		SET is_read = 1,
+		    read_at = CURRENT_TIMESTAMP
+		WHERE recipient_id = ? AND is_read = 0`
+
+	result, err := nr.db.Instance().Exec(query, recipientID)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rowsAffected), nil
+}
+
+// Delete notifications older than specified days
+func (nr *NotificationRepository) DeleteOlderThan(days int) error {
+	query := `
+		DELETE FROM notifications
+		WHERE created_at < datetime('now', '-' || ? || ' days')`
+
+	_, err := nr.db.Instance().Exec(query, days)
+	return err
+}
+
+func (nr *NotificationRepository) scanNotification(row interface{ Scan(...interface{}) error }) (*Notification, error) {
+	notification := &Notification{}
+	var readAt sql.NullTime
+
+	err := row.Scan(
+		&notification.ID, &notification.RecipientID, &notification.SenderID, &notification.Type,
+		&notification.Title, &notification.Message, &notification.TaskID, &notification.CommentID,
+		&notification.Data, &notification.IsRead, &notification.IsSystem, &readAt,
+		&notification.CreatedAt, &notification.UpdatedAt,
+		&notification.SenderName, &notification.SenderUsername,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if readAt.Valid {
+		notification.ReadAt = &readAt.Time
+	}
+
+	return notification, nil
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+)
+
+// SavedFilter is a named set of task list query parameters, e.g. {"q": "assignee:me", "order_by": "due_date"}
+type SavedFilter struct {
+	ID            int               `json:"id"`
+	Name          string            `json:"name"`
+	Filter        map[string]string `json:"filter"`
+	IsShared      bool              `json:"is_shared"` // Visible to every user, editable by the owner only
+	CreatedBy     int               `json:"created_by"`
+	OwnerUsername string            `json:"owner_username"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+type SavedFilterRepository struct {
+	db *database.Database
+}
+
+func NewSavedFilterRepository(db *database.Database) *SavedFilterRepository {
+	return &SavedFilterRepository{
+		db: db,
+	}
+}
+
+const savedFilterSelect = `
+	SELECT f.id, f.name, f.filter, f.is_shared, f.created_by, COALESCE(u.username, ''), f.created_at, f.updated_at
+	FROM saved_filters f
+	LEFT JOIN users u ON u.id = f.created_by`
+
+// Find saved filter by ID
+func (sfr *SavedFilterRepository) FindByID(id int) (*SavedFilter, error) {
+	savedFilter, err := sfr.scanSavedFilter(sfr.db.Instance().QueryRow(savedFilterSelect+` WHERE f.id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("saved filter not found")
+		}
+		return nil, err
+	}
+
+	return savedFilter, nil
+}
+
+// Get the filters a user can use: their own followed by the ones shared by others
+func (sfr *SavedFilterRepository) GetVisible(userID int) ([]*SavedFilter, error) {
+	query := savedFilterSelect + `
+		WHERE f.created_by = ? OR f.is_shared = 1
+		ORDER BY f.created_by != ?, f.name COLLATE NOCASE ASC`
+
+	rows, err := sfr.db.Instance().Query(query, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	savedFilters := make([]*SavedFilter, 0)
+	for rows.Next() {
+		savedFilter, err := sfr.scanSavedFilter(rows)
+		if err != nil {
+			return nil, err
+		}
+		savedFilters = append(savedFilters, savedFilter)
+	}
+
+	return savedFilters, rows.Err()
+}
+
+// Create new saved filter
+func (sfr *SavedFilterRepository) Create(name string, filter map[string]string, isShared bool, createdBy int) (*SavedFilter, error) {
+	filterJSON, err := encodeSavedFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO saved_filters (name, filter, is_shared, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+
+	result, err := sfr.db.Instance().Exec(query, name, filterJSON, isShared, createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return sfr.FindByID(int(id))
+}
+
+// Update saved filter, nil values are left unchanged and a new filter replaces the old one
+func (sfr *SavedFilterRepository) Update(id int, name *string, filter map[string]string, isShared *bool) (*SavedFilter, error) {
+	var filterJSON *string
+	if filter != nil {
+		encoded, err := encodeSavedFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		filterJSON = &encoded
+	}
+
+	query := `
+		UPDATE saved_filters
+		SET name = COALESCE(?, name),
+		    filter = COALESCE(?, filter),
+		    is_shared = COALESCE(?, is_shared),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	_, err := sfr.db.Instance().Exec(query, name, filterJSON, isShared, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return sfr.FindByID(id)
+}
+
+// Delete saved filter
+func (sfr *SavedFilterRepository) Delete(id int) error {
+	result, err := sfr.db.Instance().Exec(`DELETE FROM saved_filters WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("saved filter not found")
+	}
+
+	return nil
+}
+
+// Check if one user already has a filter with this name, ignoring the filter being updated
+func (sfr *SavedFilterRepository) NameExists(userID int, name string, excludeID *int) (bool, error) {
+	query := `SELECT COUNT(*) FROM saved_filters WHERE created_by = ? AND name = ? COLLATE NOCASE`
+	args := []interface{}{userID, name}
+
+	if excludeID != nil {
+		query += ` AND id != ?`
+		args = append(args, *excludeID)
+	}
+
+	var count int
+	err := sfr.db.Instance().QueryRow(query, args...).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Helper to scan a saved filter from a row or rows
+func (sfr *SavedFilterRepository) scanSavedFilter(row interface{ Scan(...interface{}) error }) (*SavedFilter, error) {
+	savedFilter := &SavedFilter{}
+	var filterJSON string
+
+	err := row.Scan(
+		&savedFilter.ID,
+		&savedFilter.Name,
+		&filterJSON,
+		&savedFilter.IsShared,
+		&savedFilter.CreatedBy,
+		&savedFilter.OwnerUsername,
+		&savedFilter.CreatedAt,
+		&savedFilter.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(filterJSON), &savedFilter.Filter); err != nil {
+		return nil, err
+	}
+	if savedFilter.Filter == nil {
+		savedFilter.Filter = make(map[string]string)
+	}
+
+	return savedFilter, nil
+}
+
+func encodeSavedFilter(filter map[string]string) (string, error) {
+	if filter == nil {
+		filter = make(map[string]string)
+	}
+
+	encoded, err := json.Marshal(filter)
+	if err != nil {
+		return "", err
+	}
+
+	return string(encoded), nil
+}
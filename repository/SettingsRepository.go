@@ -14,6 +14,9 @@ type AppSettings struct {
 	AppDescription       *string   `json:"app_description"`
 	DefaultTheme         string    `json:"default_theme"`
 	EnableNotifications  bool      `json:"enable_notifications"`
+	EnforceDependencies  bool      `json:"enforce_dependencies"` // Blocked tasks can't move into done columns
+	TrashRetentionDays   int       `json:"trash_retention_days"` // Trashed tasks are purged after this many days, 0 keeps them
+	AutoArchiveDays      int       `json:"auto_archive_days"`    // Tasks in done columns are archived after this many days, 0 never
 	CreatedAt            time.Time `json:"created_at"`
 	UpdatedAt            time.Time `json:"updated_at"`
 }
@@ -32,7 +35,8 @@ func NewSettingsRepository(db *database.Database) *SettingsRepository {
 func (sr *SettingsRepository) GetSettings() (*AppSettings, error) {
 	settings := &AppSettings{}
 	query := `
-		SELECT id, app_name, app_description, default_theme, enable_notifications, created_at, updated_at 
+		SELECT id, app_name, app_description, default_theme, enable_notifications, enforce_dependencies,
+		       trash_retention_days, auto_archive_days, created_at, updated_at 
 		FROM app_settings 
 		WHERE id = 1`
 
@@ -42,6 +46,9 @@ func (sr *SettingsRepository) GetSettings() (*AppSettings, error) {
 		&settings.AppDescription,
 		&settings.DefaultTheme,
 		&settings.EnableNotifications,
+		&settings.EnforceDependencies,
+		&settings.TrashRetentionDays,
+		&settings.AutoArchiveDays,
 		&settings.CreatedAt,
 		&settings.UpdatedAt,
 	)
@@ -56,18 +63,21 @@ func (sr *SettingsRepository) GetSettings() (*AppSettings, error) {
 	return settings, nil
 }
 
-// Update app settings
-func (sr *SettingsRepository) UpdateSettings(appName string, appDescription *string, defaultTheme string, enableNotifications bool) (*AppSettings, error) {
+// Update app settings, enforceDependencies, trashRetentionDays and autoArchiveDays are left unchanged when nil
+func (sr *SettingsRepository) UpdateSettings(appName string, appDescription *string, defaultTheme string, enableNotifications bool, enforceDependencies *bool, trashRetentionDays *int, autoArchiveDays *int) (*AppSettings, error) {
 	query := `
 		UPDATE app_settings 
 		SET app_name = ?, 
 		    app_description = ?, 
 		    default_theme = ?, 
 		    enable_notifications = ?, 
+		    enforce_dependencies = COALESCE(?, enforce_dependencies), 
+		    trash_retention_days = COALESCE(?, trash_retention_days), 
+		    auto_archive_days = COALESCE(?, auto_archive_days), 
 		    updated_at = CURRENT_TIMESTAMP 
 		WHERE id = 1`
 
-	_, err := sr.db.Instance().Exec(query, appName, appDescription, defaultTheme, enableNotifications)
+	_, err := sr.db.Instance().Exec(query, appName, appDescription, defaultTheme, enableNotifications, enforceDependencies, trashRetentionDays, autoArchiveDays)
 	if err != nil {
 		return nil, err
 	}
@@ -137,6 +147,9 @@ func (sr *SettingsRepository) ResetToDefaults() (*AppSettings, error) {
 		    app_description = 'A powerful offline-first Kanban board application',
 		    default_theme = 'system',
 		    enable_notifications = 1,
+		    enforce_dependencies = 0,
+		    trash_retention_days = 30,
+		    auto_archive_days = 0,
 		    updated_at = CURRENT_TIMESTAMP 
 		WHERE id = 1`
 
@@ -146,4 +159,59 @@ func (sr *SettingsRepository) ResetToDefaults() (*AppSettings, error) {
 	}
 
 	return sr.GetSettings()
-}
\ No newline at end of file
+}
+
+// Scheduled backup settings, kept apart from AppSettings because they are only shown to root users
+type BackupSettings struct {
+	Enabled       bool    `json:"enabled"`
+	IntervalHours int     `json:"interval_hours"`
+	Directory     *string `json:"directory"`   // Where scheduled backups are written, nil for the default backup directory
+	KeepDaily     int     `json:"keep_daily"`  // Newest backup of each of the last this many days is kept
+	KeepWeekly    int     `json:"keep_weekly"` // Newest backup of each of the last this many weeks is kept
+}
+
+// Get the scheduled backup settings
+func (sr *SettingsRepository) GetBackupSettings() (*BackupSettings, error) {
+	settings := &BackupSettings{}
+	query := `
+		SELECT backup_enabled, backup_interval_hours, backup_directory, backup_keep_daily, backup_keep_weekly
+		FROM app_settings
+		WHERE id = 1`
+
+	err := sr.db.Instance().QueryRow(query).Scan(
+		&settings.Enabled,
+		&settings.IntervalHours,
+		&settings.Directory,
+		&settings.KeepDaily,
+		&settings.KeepWeekly,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("app settings not found")
+		}
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// Update the scheduled backup settings, nil values are left unchanged and an empty directory resets it to the default
+func (sr *SettingsRepository) UpdateBackupSettings(enabled *bool, intervalHours *int, directory *string, keepDaily, keepWeekly *int) (*BackupSettings, error) {
+	query := `
+		UPDATE app_settings
+		SET backup_enabled = COALESCE(?, backup_enabled),
+		    backup_interval_hours = COALESCE(?, backup_interval_hours),
+		    backup_directory = CASE WHEN ? THEN NULLIF(?, '') ELSE backup_directory END,
+		    backup_keep_daily = COALESCE(?, backup_keep_daily),
+		    backup_keep_weekly = COALESCE(?, backup_keep_weekly),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = 1`
+
+	_, err := sr.db.Instance().Exec(query, enabled, intervalHours, directory != nil, directory, keepDaily, keepWeekly)
+	if err != nil {
+		return nil, err
+	}
+
+	return sr.GetBackupSettings()
+}
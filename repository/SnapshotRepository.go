@@ -0,0 +1,550 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+)
+
+// Rows of a board snapshot, every table with its own IDs and raw references
+
+type SnapshotUser struct {
+	ID          int       `json:"id"`
+	UserName    string    `json:"username"`
+	Name        *string   `json:"name"`
+	Designation *string   `json:"designation"`
+	Password    *string   `json:"password,omitempty"` // Password hash, only exported when requested
+	IsRoot      bool      `json:"is_root"`
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SnapshotBoard struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Description *string   `json:"description"`
+	CreatedBy   *int      `json:"created_by"`
+	Position    int       `json:"position"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SnapshotColumn struct {
+	ID              int        `json:"id"`
+	Title           string     `json:"title"`
+	BoardID         *int       `json:"board_id"`
+	CreatedBy       int        `json:"created_by"`
+	Colors          *string    `json:"colors"`
+	WipLimit        *int       `json:"wip_limit"`
+	IsDone          bool       `json:"is_done"`
+	AutoArchiveDays *int       `json:"auto_archive_days"`
+	Position        int        `json:"position"`
+	DeletedAt       *time.Time `json:"deleted_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type SnapshotLabel struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	CreatedBy *int      `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type SnapshotTask struct {
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Description *string    `json:"description"`
+	ColumnID    int        `json:"column_id"`
+	AssignedTo  *int       `json:"assigned_to"`
+	CreatedBy   int        `json:"created_by"`
+	DueDate     *time.Time `json:"due_date"`
+	Priority    *string    `json:"priority"`
+	Position    int        `json:"position"`
+	Weight      int        `json:"weight"`
+	ParentID    *int       `json:"parent_id"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+	DeletedBy   *int       `json:"deleted_by"`
+	ArchivedAt  *time.Time `json:"archived_at"`
+	MovedAt     *time.Time `json:"moved_at"`
+	LabelIDs    []int      `json:"label_ids"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type SnapshotDependency struct {
+	TaskID          int       `json:"task_id"`
+	BlockedByTaskID int       `json:"blocked_by_task_id"`
+	CreatedBy       *int      `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type SnapshotChecklist struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	TaskID      int       `json:"task_id"`
+	CreatedBy   int       `json:"created_by"`
+	CompletedBy *int      `json:"completed_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SnapshotComment struct {
+	ID        int       `json:"id"`
+	Content   string    `json:"content"`
+	TaskID    int       `json:"task_id"`
+	CreatedBy int       `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type SnapshotActivity struct {
+	ID         int       `json:"id"`
+	EntityType string    `json:"entity_type"`
+	EntityID   int       `json:"entity_id"`
+	Action     string    `json:"action"`
+	FieldName  *string   `json:"field_name"`
+	OldValue   *string   `json:"old_value"`
+	NewValue   *string   `json:"new_value"`
+	OldRef     *int      `json:"old_ref"`
+	NewRef     *int      `json:"new_ref"`
+	UserID     int       `json:"user_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SnapshotRepository reads and writes whole tables for export and import, trashed and archived rows included
+type SnapshotRepository struct {
+	db *database.Database
+}
+
+func NewSnapshotRepository(db *database.Database) *SnapshotRepository {
+	return &SnapshotRepository{
+		db: db,
+	}
+}
+
+// Get all users, the password hashes only when asked for
+func (sr *SnapshotRepository) GetUsers(includePasswords bool) ([]*SnapshotUser, error) {
+	rows, err := sr.db.Instance().Query(`
+		SELECT id, username, name, designation, password, is_root, is_active, created_at, updated_at
+		FROM users
+		ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*SnapshotUser, 0)
+	for rows.Next() {
+		user := &SnapshotUser{}
+		var password string
+		if err := rows.Scan(&user.ID, &user.UserName, &user.Name, &user.Designation, &password,
+			&user.IsRoot, &user.IsActive, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if includePasswords {
+			user.Password = &password
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// Get all boards
+func (sr *SnapshotRepository) GetBoards() ([]*SnapshotBoard, error) {
+	rows, err := sr.db.Instance().Query(`
+		SELECT id, title, description, created_by, COALESCE(position, 0), created_at, updated_at
+		FROM boards
+		ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	boards := make([]*SnapshotBoard, 0)
+	for rows.Next() {
+		board := &SnapshotBoard{}
+		if err := rows.Scan(&board.ID, &board.Title, &board.Description, &board.CreatedBy,
+			&board.Position, &board.CreatedAt, &board.UpdatedAt); err != nil {
+			return nil, err
+		}
+		boards = append(boards, board)
+	}
+
+	return boards, rows.Err()
+}
+
+// Get all columns, deleted ones included
+func (sr *SnapshotRepository) GetColumns() ([]*SnapshotColumn, error) {
+	rows, err := sr.db.Instance().Query(`
+		SELECT id, title, board_id, created_by, colors, wip_limit, is_done, auto_archive_days,
+		       COALESCE(position, 0), deleted_at, created_at, updated_at
+		FROM columns
+		ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make([]*SnapshotColumn, 0)
+	for rows.Next() {
+		column := &SnapshotColumn{}
+		if err := rows.Scan(&column.ID, &column.Title, &column.BoardID, &column.CreatedBy, &column.Colors,
+			&column.WipLimit, &column.IsDone, &column.AutoArchiveDays, &column.Position, &column.DeletedAt,
+			&column.CreatedAt, &column.UpdatedAt); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+// Get all labels
+func (sr *SnapshotRepository) GetLabels() ([]*SnapshotLabel, error) {
+	rows, err := sr.db.Instance().Query(`
+		SELECT id, name, color, created_by, created_at, updated_at
+		FROM labels
+		ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels := make([]*SnapshotLabel, 0)
+	for rows.Next() {
+		label := &SnapshotLabel{}
+		if err := rows.Scan(&label.ID, &label.Name, &label.Color, &label.CreatedBy,
+			&label.CreatedAt, &label.UpdatedAt); err != nil {
+			return nil, err
+		}
+		labels = append(labels, label)
+	}
+
+	return labels, rows.Err()
+}
+
+// Get all tasks with their label IDs, trashed and archived tasks included
+func (sr *SnapshotRepository) GetTasks() ([]*SnapshotTask, error) {
+	rows, err := sr.db.Instance().Query(`
+		SELECT id, title, description, column_id, assigned_to, created_by, due_date, priority, position,
+		       COALESCE(weight, 0), parent_id, deleted_at, deleted_by, archived_at, moved_at, created_at, updated_at
+		FROM tasks
+		ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]*SnapshotTask, 0)
+	byID := make(map[int]*SnapshotTask)
+	for rows.Next() {
+		task := &SnapshotTask{LabelIDs: make([]int, 0)}
+		if err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.ColumnID, &task.AssignedTo,
+			&task.CreatedBy, &task.DueDate, &task.Priority, &task.Position, &task.Weight, &task.ParentID,
+			&task.DeletedAt, &task.DeletedBy, &task.ArchivedAt, &task.MovedAt,
+			&task.CreatedAt, &task.UpdatedAt); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+		byID[task.ID] = task
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	labelRows, err := sr.db.Instance().Query(`SELECT task_id, label_id FROM task_labels ORDER BY task_id, label_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer labelRows.Close()
+
+	for labelRows.Next() {
+		var taskID, labelID int
+		if err := labelRows.Scan(&taskID, &labelID); err != nil {
+			return nil, err
+		}
+		if task, ok := byID[taskID]; ok {
+			task.LabelIDs = append(task.LabelIDs, labelID)
+		}
+	}
+
+	return tasks, labelRows.Err()
+}
+
+// Get all task dependencies
+func (sr *SnapshotRepository) GetDependencies() ([]*SnapshotDependency, error) {
+	rows, err := sr.db.Instance().Query(`
+		SELECT task_id, blocked_by_task_id, created_by, created_at
+		FROM task_dependencies
+		ORDER BY task_id, blocked_by_task_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dependencies := make([]*SnapshotDependency, 0)
+	for rows.Next() {
+		dependency := &SnapshotDependency{}
+		if err := rows.Scan(&dependency.TaskID, &dependency.BlockedByTaskID, &dependency.CreatedBy,
+			&dependency.CreatedAt); err != nil {
+			return nil, err
+		}
+		dependencies = append(dependencies, dependency)
+	}
+
+	return dependencies, rows.Err()
+}
+
+// Get all checklist items
+func (sr *SnapshotRepository) GetChecklists() ([]*SnapshotChecklist, error) {
+	rows, err := sr.db.Instance().Query(`
+		SELECT id, title, task_id, created_by, completed_by, created_at, updated_at
+		FROM checklists
+		ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checklists := make([]*SnapshotChecklist, 0)
+	for rows.Next() {
+		checklist := &SnapshotChecklist{}
+		if err := rows.Scan(&checklist.ID, &checklist.Title, &checklist.TaskID, &checklist.CreatedBy,
+			&checklist.CompletedBy, &checklist.CreatedAt, &checklist.UpdatedAt); err != nil {
+			return nil, err
+		}
+		checklists = append(checklists, checklist)
+	}
+
+	return checklists, rows.Err()
+}
+
+// Get all comments
+func (sr *SnapshotRepository) GetComments() ([]*SnapshotComment, error) {
+	rows, err := sr.db.Instance().Query(`
+		SELECT id, content, task_id, created_by, created_at, updated_at
+		FROM comments
+		ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := make([]*SnapshotComment, 0)
+	for rows.Next() {
+		comment := &SnapshotComment{}
+		if err := rows.Scan(&comment.ID, &comment.Content, &comment.TaskID, &comment.CreatedBy,
+			&comment.CreatedAt, &comment.UpdatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+// Get the whole activity log
+func (sr *SnapshotRepository) GetActivities() ([]*SnapshotActivity, error) {
+	rows, err := sr.db.Instance().Query(`
+		SELECT id, entity_type, entity_id, action, field_name, old_value, new_value, old_ref, new_ref,
+		       user_id, created_at, updated_at
+		FROM activities
+		ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activities := make([]*SnapshotActivity, 0)
+	for rows.Next() {
+		activity := &SnapshotActivity{}
+		if err := rows.Scan(&activity.ID, &activity.EntityType, &activity.EntityID, &activity.Action,
+			&activity.FieldName, &activity.OldValue, &activity.NewValue, &activity.OldRef, &activity.NewRef,
+			&activity.UserID, &activity.CreatedAt, &activity.UpdatedAt); err != nil {
+			return nil, err
+		}
+		activities = append(activities, activity)
+	}
+
+	return activities, rows.Err()
+}
+
+// IsEmpty reports whether there is no board content yet, users, settings and boards without columns don't count
+func (sr *SnapshotRepository) IsEmpty() (bool, error) {
+	var count int
+	err := sr.db.Instance().QueryRow(`
+		SELECT (SELECT COUNT(*) FROM columns) + (SELECT COUNT(*) FROM tasks) + (SELECT COUNT(*) FROM labels) +
+		       (SELECT COUNT(*) FROM checklists) + (SELECT COUNT(*) FROM comments) + (SELECT COUNT(*) FROM activities)`).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count == 0, nil
+}
+
+// DeleteEmptyBoards removes boards without columns, like the default board of a fresh install
+func (sr *SnapshotRepository) DeleteEmptyBoards() error {
+	_, err := sr.db.Instance().Exec(`DELETE FROM boards WHERE id NOT IN (SELECT board_id FROM columns WHERE board_id IS NOT NULL)`)
+	return err
+}
+
+// Find the ID of a user by username, inactive users included
+func (sr *SnapshotRepository) FindUserID(username string) (int, bool, error) {
+	var id int
+	err := sr.db.Instance().QueryRow(`SELECT id FROM users WHERE username = ?`, username).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return id, true, nil
+}
+
+// Find the ID of a label by name, ignoring case like the unique index does
+func (sr *SnapshotRepository) FindLabelID(name string) (int, bool, error) {
+	var id int
+	err := sr.db.Instance().QueryRow(`SELECT id FROM labels WHERE name = ? COLLATE NOCASE`, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return id, true, nil
+}
+
+// Get the highest board position, 0 without boards
+func (sr *SnapshotRepository) GetMaxBoardPosition() (int, error) {
+	var position int
+	err := sr.db.Instance().QueryRow(`SELECT COALESCE(MAX(position), 0) FROM boards`).Scan(&position)
+	return position, err
+}
+
+// InsertUser adds a user under a new ID, password is the hash to store
+func (sr *SnapshotRepository) InsertUser(user *SnapshotUser, password string) (int, error) {
+	return sr.insert("users", 0, false,
+		[]string{"username", "password", "name", "designation", "is_root", "is_active", "created_at", "updated_at"},
+		user.UserName, password, user.Name, user.Designation, user.IsRoot, user.IsActive,
+		timestamp(user.CreatedAt), timestamp(user.UpdatedAt))
+}
+
+// InsertBoard adds a board, under its own ID when keepID is set
+func (sr *SnapshotRepository) InsertBoard(board *SnapshotBoard, createdBy *int, position int, keepID bool) (int, error) {
+	return sr.insert("boards", board.ID, keepID,
+		[]string{"title", "description", "created_by", "position", "created_at", "updated_at"},
+		board.Title, board.Description, createdBy, position, timestamp(board.CreatedAt), timestamp(board.UpdatedAt))
+}
+
+// InsertColumn adds a column, under its own ID when keepID is set
+func (sr *SnapshotRepository) InsertColumn(column *SnapshotColumn, boardID, createdBy int, keepID bool) (int, error) {
+	return sr.insert("columns", column.ID, keepID,
+		[]string{"title", "board_id", "created_by", "colors", "wip_limit", "is_done", "auto_archive_days",
+			"position", "deleted_at", "created_at", "updated_at"},
+		column.Title, boardID, createdBy, column.Colors, column.WipLimit, column.IsDone, column.AutoArchiveDays,
+		column.Position, nullTimestamp(column.DeletedAt), timestamp(column.CreatedAt), timestamp(column.UpdatedAt))
+}
+
+// InsertLabel adds a label, under its own ID when keepID is set
+func (sr *SnapshotRepository) InsertLabel(label *SnapshotLabel, createdBy *int, keepID bool) (int, error) {
+	return sr.insert("labels", label.ID, keepID,
+		[]string{"name", "color", "created_by", "created_at", "updated_at"},
+		label.Name, label.Color, createdBy, timestamp(label.CreatedAt), timestamp(label.UpdatedAt))
+}
+
+// InsertTask adds a task with the references already mapped, under its own ID when keepID is set
+func (sr *SnapshotRepository) InsertTask(task *SnapshotTask, columnID int, assignedTo *int, createdBy int,
+	parentID, deletedBy *int, keepID bool) (int, error) {
+	return sr.insert("tasks", task.ID, keepID,
+		[]string{"title", "description", "column_id", "assigned_to", "created_by", "due_date", "priority",
+			"position", "weight", "parent_id", "deleted_at", "deleted_by", "archived_at", "moved_at",
+			"created_at", "updated_at"},
+		task.Title, task.Description, columnID, assignedTo, createdBy, task.DueDate, task.Priority,
+		task.Position, task.Weight, parentID, nullTimestamp(task.DeletedAt), deletedBy,
+		nullTimestamp(task.ArchivedAt), nullTimestamp(task.MovedAt),
+		timestamp(task.CreatedAt), timestamp(task.UpdatedAt))
+}
+
+// Add a label to a task
+func (sr *SnapshotRepository) InsertTaskLabel(taskID, labelID int) error {
+	_, err := sr.db.Instance().Exec(`
+		INSERT OR IGNORE INTO task_labels (task_id, label_id, created_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)`, taskID, labelID)
+	return err
+}
+
+// Add a dependency between two tasks
+func (sr *SnapshotRepository) InsertDependency(taskID, blockedByTaskID int, createdBy *int, createdAt time.Time) error {
+	_, err := sr.db.Instance().Exec(`
+		INSERT OR IGNORE INTO task_dependencies (task_id, blocked_by_task_id, created_by, created_at)
+		VALUES (?, ?, ?, ?)`, taskID, blockedByTaskID, createdBy, timestamp(createdAt))
+	return err
+}
+
+// InsertChecklist adds a checklist item, under its own ID when keepID is set
+func (sr *SnapshotRepository) InsertChecklist(checklist *SnapshotChecklist, taskID, createdBy int, completedBy *int, keepID bool) (int, error) {
+	return sr.insert("checklists", checklist.ID, keepID,
+		[]string{"title", "task_id", "created_by", "completed_by", "created_at", "updated_at"},
+		checklist.Title, taskID, createdBy, completedBy, timestamp(checklist.CreatedAt), timestamp(checklist.UpdatedAt))
+}
+
+// InsertComment adds a comment, under its own ID when keepID is set
+func (sr *SnapshotRepository) InsertComment(comment *SnapshotComment, taskID, createdBy int, keepID bool) (int, error) {
+	return sr.insert("comments", comment.ID, keepID,
+		[]string{"content", "task_id", "created_by", "created_at", "updated_at"},
+		comment.Content, taskID, createdBy, timestamp(comment.CreatedAt), timestamp(comment.UpdatedAt))
+}
+
+// InsertActivity adds an activity with the references already mapped, under its own ID when keepID is set
+func (sr *SnapshotRepository) InsertActivity(activity *SnapshotActivity, entityID int, oldRef, newRef *int, userID int, keepID bool) (int, error) {
+	return sr.insert("activities", activity.ID, keepID,
+		[]string{"entity_type", "entity_id", "action", "field_name", "old_value", "new_value", "old_ref", "new_ref",
+			"user_id", "created_at", "updated_at"},
+		activity.EntityType, entityID, activity.Action, activity.FieldName, activity.OldValue, activity.NewValue,
+		oldRef, newRef, userID, timestamp(activity.CreatedAt), timestamp(activity.UpdatedAt))
+}
+
+// insert adds a row to table, with id as its primary key when keepID is set, and returns the row's ID
+func (sr *SnapshotRepository) insert(table string, id int, keepID bool, columns []string, values ...interface{}) (int, error) {
+	if keepID {
+		columns = append([]string{"id"}, columns...)
+		values = append([]interface{}{id}, values...)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	result, err := sr.db.Instance().Exec(
+		"INSERT INTO "+table+" ("+strings.Join(columns, ", ")+") VALUES ("+placeholders+")", values...)
+	if err != nil {
+		return 0, err
+	}
+
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(newID), nil
+}
+
+// timestamp formats t like CURRENT_TIMESTAMP, so imported rows compare the same as the ones created here
+func timestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+func nullTimestamp(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return timestamp(*t)
+}
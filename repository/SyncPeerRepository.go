@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+)
+
+// SyncPeer is another instance this one syncs with
+type SyncPeer struct {
+	ID               int        `json:"id"`
+	Name             string     `json:"name"`
+	URL              string     `json:"url"`
+	Token            string     `json:"-"`
+	RemoteInstanceID *string    `json:"remote_instance_id"`
+	PulledSeq        int64      `json:"pulled_seq"` // Last change of the peer applied here
+	PushedSeq        int64      `json:"pushed_seq"` // Last change of this instance sent to the peer
+	LastSyncedAt     *time.Time `json:"last_synced_at"`
+	LastError        *string    `json:"last_error"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// SyncConflict is a change from another instance that could not be merged cleanly
+type SyncConflict struct {
+	ID               int       `json:"id"`
+	RemoteInstanceID string    `json:"remote_instance_id"`
+	Entity           string    `json:"entity"`
+	GlobalID         string    `json:"global_id"`
+	LocalID          *int      `json:"local_id"`
+	Field            *string   `json:"field"`
+	Kind             string    `json:"kind"`
+	Message          string    `json:"message"`
+	LocalValue       *string   `json:"local_value"`  // JSON
+	RemoteValue      *string   `json:"remote_value"` // JSON
+	Resolution       string    `json:"resolution"`
+	IsResolved       bool      `json:"is_resolved"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+var ErrSyncPeerNotFound = errors.New("sync peer not found")
+
+type SyncPeerRepository struct {
+	db *database.Database
+}
+
+func NewSyncPeerRepository(db *database.Database) *SyncPeerRepository {
+	return &SyncPeerRepository{
+		db: db,
+	}
+}
+
+const syncPeerSelect = `
+	SELECT id, name, url, token, remote_instance_id, pulled_seq, pushed_seq, last_synced_at, last_error, created_at, updated_at
+	FROM sync_peers`
+
+func (pr *SyncPeerRepository) Create(name, url, token string) (*SyncPeer, error) {
+	result, err := pr.db.Instance().Exec(`INSERT INTO sync_peers (name, url, token) VALUES (?, ?, ?)`, name, url, token)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return pr.FindByID(int(id))
+}
+
+func (pr *SyncPeerRepository) FindByID(id int) (*SyncPeer, error) {
+	peer, err := pr.scanPeer(pr.db.Instance().QueryRow(syncPeerSelect+` WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrSyncPeerNotFound
+	}
+	return peer, err
+}
+
+func (pr *SyncPeerRepository) GetAll() ([]*SyncPeer, error) {
+	rows, err := pr.db.Instance().Query(syncPeerSelect + ` ORDER BY name COLLATE NOCASE, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	peers := []*SyncPeer{}
+	for rows.Next() {
+		peer, err := pr.scanPeer(rows)
+		if err != nil {
+			return nil, err
+		}
+		peers = append(peers, peer)
+	}
+
+	return peers, rows.Err()
+}
+
+// Update the name, address and token of a peer, nil values are left unchanged
+func (pr *SyncPeerRepository) Update(id int, name, url, token *string) (*SyncPeer, error) {
+	query := `
+		UPDATE sync_peers
+		SET name = COALESCE(?, name), url = COALESCE(?, url), token = COALESCE(?, token)
+		WHERE id = ?`
+
+	if _, err := pr.db.Instance().Exec(query, name, url, token, id); err != nil {
+		return nil, err
+	}
+
+	return pr.FindByID(id)
+}
+
+func (pr *SyncPeerRepository) Delete(id int) error {
+	result, err := pr.db.Instance().Exec(`DELETE FROM sync_peers WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrSyncPeerNotFound
+	}
+	return nil
+}
+
+// SetRemoteInstance remembers which instance answered at the peer's address
+func (pr *SyncPeerRepository) SetRemoteInstance(id int, remoteInstanceID string) error {
+	_, err := pr.db.Instance().Exec(`UPDATE sync_peers SET remote_instance_id = ? WHERE id = ?`, remoteInstanceID, id)
+	return err
+}
+
+func (pr *SyncPeerRepository) SetPulledSeq(id int, seq int64) error {
+	_, err := pr.db.Instance().Exec(`UPDATE sync_peers SET pulled_seq = ? WHERE id = ?`, seq, id)
+	return err
+}
+
+func (pr *SyncPeerRepository) SetPushedSeq(id int, seq int64) error {
+	_, err := pr.db.Instance().Exec(`UPDATE sync_peers SET pushed_seq = ? WHERE id = ?`, seq, id)
+	return err
+}
+
+// SetResult records the outcome of a sync, lastError is nil when it succeeded
+func (pr *SyncPeerRepository) SetResult(id int, lastError *string) error {
+	query := `
+		UPDATE sync_peers
+		SET last_error = ?, last_synced_at = CASE WHEN ? IS NULL THEN CURRENT_TIMESTAMP ELSE last_synced_at END
+		WHERE id = ?`
+
+	_, err := pr.db.Instance().Exec(query, lastError, lastError, id)
+	return err
+}
+
+func (pr *SyncPeerRepository) CreateConflict(conflict *SyncConflict) (*SyncConflict, error) {
+	query := `
+		INSERT INTO sync_conflicts (remote_instance_id, entity, global_id, local_id, field, kind, message,
+		                            local_value, remote_value, resolution)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := pr.db.Instance().Exec(query, conflict.RemoteInstanceID, conflict.Entity, conflict.GlobalID, conflict.LocalID,
+		conflict.Field, conflict.Kind, conflict.Message, conflict.LocalValue, conflict.RemoteValue, conflict.Resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return pr.FindConflictByID(int(id))
+}
+
+const syncConflictSelect = `
+	SELECT id, remote_instance_id, entity, global_id, local_id, field, kind, message, local_value, remote_value,
+	       resolution, is_resolved, created_at
+	FROM sync_conflicts`
+
+func (pr *SyncPeerRepository) FindConflictByID(id int) (*SyncConflict, error) {
+	conflict, err := pr.scanConflict(pr.db.Instance().QueryRow(syncConflictSelect+` WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("sync conflict not found")
+	}
+	return conflict, err
+}
+
+// GetConflicts returns the conflicts, newest first, only the unresolved ones unless all is set
+func (pr *SyncPeerRepository) GetConflicts(all bool, limit, offset int) ([]*SyncConflict, int, error) {
+	where := ` WHERE is_resolved = 0`
+	if all {
+		where = ``
+	}
+
+	var total int
+	if err := pr.db.Instance().QueryRow(`SELECT COUNT(*) FROM sync_conflicts` + where).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := pr.db.Instance().Query(syncConflictSelect+where+` ORDER BY id DESC LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	conflicts := []*SyncConflict{}
+	for rows.Next() {
+		conflict, err := pr.scanConflict(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		conflicts = append(conflicts, conflict)
+	}
+
+	return conflicts, total, rows.Err()
+}
+
+// ResolveConflict marks a conflict as dealt with, false when there is no such conflict
+func (pr *SyncPeerRepository) ResolveConflict(id int) (bool, error) {
+	result, err := pr.db.Instance().Exec(`UPDATE sync_conflicts SET is_resolved = 1 WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (pr *SyncPeerRepository) scanPeer(row interface{ Scan(...interface{}) error }) (*SyncPeer, error) {
+	peer := &SyncPeer{}
+	err := row.Scan(&peer.ID, &peer.Name, &peer.URL, &peer.Token, &peer.RemoteInstanceID, &peer.PulledSeq, &peer.PushedSeq,
+		&peer.LastSyncedAt, &peer.LastError, &peer.CreatedAt, &peer.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return peer, nil
+}
+
+func (pr *SyncPeerRepository) scanConflict(row interface{ Scan(...interface{}) error }) (*SyncConflict, error) {
+	conflict := &SyncConflict{}
+	err := row.Scan(&conflict.ID, &conflict.RemoteInstanceID, &conflict.Entity, &conflict.GlobalID, &conflict.LocalID,
+		&conflict.Field, &conflict.Kind, &conflict.Message, &conflict.LocalValue, &conflict.RemoteValue,
+		&conflict.Resolution, &conflict.IsResolved, &conflict.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return conflict, nil
+}
@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+)
+
+// SyncInstance is this instance's identity and counters for syncing
+type SyncInstance struct {
+	InstanceID string  `json:"instance_id"`
+	Clock      int64   `json:"clock"`
+	Seq        int64   `json:"seq"`
+	Token      *string `json:"-"`
+}
+
+// SyncRow is the global ID and version of one synced row
+type SyncRow struct {
+	Entity   string
+	LocalID  int
+	GlobalID string
+	Clock    int64
+	Origin   string
+	Seq      int64
+	LocalSeq int64 // Last edit made on this instance, 0 when the row only changed elsewhere
+	Deleted  bool
+}
+
+// SyncFieldVersion is the version of one field of a row merged field by field
+type SyncFieldVersion struct {
+	Clock    int64
+	Origin   string
+	LocalSeq int64
+}
+
+type SyncRepository struct {
+	db *database.Database
+}
+
+func NewSyncRepository(db *database.Database) *SyncRepository {
+	return &SyncRepository{
+		db: db,
+	}
+}
+
+func (sr *SyncRepository) GetInstance() (*SyncInstance, error) {
+	instance := &SyncInstance{}
+	err := sr.db.Instance().QueryRow(`SELECT instance_id, clock, seq, token FROM sync_instance WHERE id = 1`).Scan(
+		&instance.InstanceID, &instance.Clock, &instance.Seq, &instance.Token)
+	if err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// SetToken sets the token other instances must send to read and write changes, nil turns that off
+func (sr *SyncRepository) SetToken(token *string) error {
+	_, err := sr.db.Instance().Exec(`UPDATE sync_instance SET token = ? WHERE id = 1`, token)
+	return err
+}
+
+// SetApplying switches the sync triggers off while changes from another instance are written
+func (sr *SyncRepository) SetApplying(applying bool) error {
+	_, err := sr.db.Instance().Exec(`UPDATE sync_instance SET applying = ? WHERE id = 1`, applying)
+	return err
+}
+
+// ObserveClock moves the clock past a version seen from another instance, so later local edits win over it
+func (sr *SyncRepository) ObserveClock(clock int64) error {
+	_, err := sr.db.Instance().Exec(`UPDATE sync_instance SET clock = MAX(clock, ?) WHERE id = 1`, clock)
+	return err
+}
+
+// NextSeq takes the next sequence number, for a row changed by another instance
+func (sr *SyncRepository) NextSeq() (int64, error) {
+	if _, err := sr.db.Instance().Exec(`UPDATE sync_instance SET seq = seq + 1 WHERE id = 1`); err != nil {
+		return 0, err
+	}
+
+	var seq int64
+	err := sr.db.Instance().QueryRow(`SELECT seq FROM sync_instance WHERE id = 1`).Scan(&seq)
+	return seq, err
+}
+
+const syncRowSelect = `SELECT entity, local_id, global_id, clock, origin, seq, local_seq, deleted FROM sync_rows`
+
+// GetChangedRows returns the rows changed after the given sequence number, in the order they changed
+func (sr *SyncRepository) GetChangedRows(since int64, limit int) ([]*SyncRow, error) {
+	rows, err := sr.db.Instance().Query(syncRowSelect+` WHERE seq > ? ORDER BY seq LIMIT ?`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var syncRows []*SyncRow
+	for rows.Next() {
+		row, err := scanSyncRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		syncRows = append(syncRows, row)
+	}
+
+	return syncRows, rows.Err()
+}
+
+// FindRow returns the row with the given global ID, nil when this instance never had it
+func (sr *SyncRepository) FindRow(entity, globalID string) (*SyncRow, error) {
+	row, err := scanSyncRow(sr.db.Instance().QueryRow(syncRowSelect+` WHERE entity = ? AND global_id = ?`, entity, globalID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return row, err
+}
+
+// FindRowByLocalID returns the sync row of a local row, nil when it has none
+func (sr *SyncRepository) FindRowByLocalID(entity string, localID int) (*SyncRow, error) {
+	row, err := scanSyncRow(sr.db.Instance().QueryRow(syncRowSelect+` WHERE entity = ? AND local_id = ?`, entity, localID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return row, err
+}
+
+// GetReferencingRows returns the sync rows of entity's rows whose column points to the given local row
+func (sr *SyncRepository) GetReferencingRows(entity *database.SyncEntity, column string, localID int) ([]*SyncRow, error) {
+	query := `
+		SELECT r.entity, r.local_id, r.global_id, r.clock, r.origin, r.seq, r.local_seq, r.deleted
+		FROM sync_rows r
+		JOIN ` + entity.Table + ` t ON t.id = r.local_id
+		WHERE r.entity = ? AND t.` + column + ` = ?`
+
+	rows, err := sr.db.Instance().Query(query, entity.Name, localID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var syncRows []*SyncRow
+	for rows.Next() {
+		row, err := scanSyncRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		syncRows = append(syncRows, row)
+	}
+
+	return syncRows, rows.Err()
+}
+
+// SaveRow inserts or replaces the sync row of a row written for another instance
+func (sr *SyncRepository) SaveRow(row *SyncRow) error {
+	query := `
+		INSERT INTO sync_rows (entity, local_id, global_id, clock, origin, seq, local_seq, deleted)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (entity, global_id) DO UPDATE
+		SET local_id = excluded.local_id, clock = excluded.clock, origin = excluded.origin, seq = excluded.seq,
+		    local_seq = excluded.local_seq, deleted = excluded.deleted`
+
+	_, err := sr.db.Instance().Exec(query, row.Entity, row.LocalID, row.GlobalID, row.Clock, row.Origin, row.Seq, row.LocalSeq, row.Deleted)
+	return err
+}
+
+// GetFieldVersions returns the field versions of a row merged field by field
+func (sr *SyncRepository) GetFieldVersions(entity, globalID string) (map[string]*SyncFieldVersion, error) {
+	rows, err := sr.db.Instance().Query(`SELECT field, clock, origin, local_seq FROM sync_fields WHERE entity = ? AND global_id = ?`, entity, globalID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := map[string]*SyncFieldVersion{}
+	for rows.Next() {
+		var field string
+		version := &SyncFieldVersion{}
+		if err := rows.Scan(&field, &version.Clock, &version.Origin, &version.LocalSeq); err != nil {
+			return nil, err
+		}
+		versions[field] = version
+	}
+
+	return versions, rows.Err()
+}
+
+func (sr *SyncRepository) SaveFieldVersion(entity, globalID, field string, version *SyncFieldVersion) error {
+	query := `
+		INSERT INTO sync_fields (entity, global_id, field, clock, origin, local_seq)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (entity, global_id, field) DO UPDATE
+		SET clock = excluded.clock, origin = excluded.origin, local_seq = excluded.local_seq`
+
+	_, err := sr.db.Instance().Exec(query, entity, globalID, field, version.Clock, version.Origin, version.LocalSeq)
+	return err
+}
+
+// ReadFields reads the synced columns of a local row, false when the row doesn't exist
+func (sr *SyncRepository) ReadFields(entity *database.SyncEntity, localID int) (map[string]interface{}, time.Time, bool, error) {
+	columns := make([]string, len(entity.Fields))
+	values := make([]interface{}, len(entity.Fields))
+	targets := make([]interface{}, len(entity.Fields)+1)
+	for i, field := range entity.Fields {
+		columns[i] = field.Column
+		targets[i] = &values[i]
+	}
+
+	var createdAt sql.NullTime
+	targets[len(entity.Fields)] = &createdAt
+
+	query := `SELECT ` + strings.Join(columns, ", ") + `, created_at FROM ` + entity.Table + ` WHERE id = ?`
+	if err := sr.db.Instance().QueryRow(query, localID).Scan(targets...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, time.Time{}, false, nil
+		}
+		return nil, time.Time{}, false, err
+	}
+
+	fields := make(map[string]interface{}, len(entity.Fields))
+	for i, field := range entity.Fields {
+		fields[field.Name] = values[i]
+	}
+
+	return fields, createdAt.Time, true, nil
+}
+
+// InsertFields inserts a row from column values, keeping the creation time of the original
+func (sr *SyncRepository) InsertFields(entity *database.SyncEntity, values map[string]interface{}, createdAt time.Time) (int, error) {
+	columns := []string{"created_at"}
+	placeholders := []string{"?"}
+	args := []interface{}{createdAt.UTC().Format("2006-01-02 15:04:05")}
+	for column, value := range values {
+		columns = append(columns, column)
+		placeholders = append(placeholders, "?")
+		args = append(args, value)
+	}
+
+	query := `INSERT INTO ` + entity.Table + ` (` + strings.Join(columns, ", ") + `) VALUES (` + strings.Join(placeholders, ", ") + `)`
+	result, err := sr.db.Instance().Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// UpdateFields writes column values to a local row
+func (sr *SyncRepository) UpdateFields(entity *database.SyncEntity, localID int, values map[string]interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	var assignments []string
+	var args []interface{}
+	for column, value := range values {
+		assignments = append(assignments, column+" = ?")
+		args = append(args, value)
+	}
+
+	_, err := sr.db.Instance().Exec(`UPDATE `+entity.Table+` SET `+strings.Join(assignments, ", ")+` WHERE id = ?`, append(args, localID)...)
+	return err
+}
+
+// DeleteRow deletes a local row removed on another instance
+func (sr *SyncRepository) DeleteRow(entity *database.SyncEntity, localID int) error {
+	_, err := sr.db.Instance().Exec(`DELETE FROM `+entity.Table+` WHERE id = ?`, localID)
+	return err
+}
+
+// FindUsername returns the username of a user, false when the user doesn't exist
+func (sr *SyncRepository) FindUsername(userID int64) (string, bool, error) {
+	var username string
+	err := sr.db.Instance().QueryRow(`SELECT username FROM users WHERE id = ?`, userID).Scan(&username)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return username, err == nil, err
+}
+
+// FindUserID returns the user with the given username, ignoring case, false when there is none
+func (sr *SyncRepository) FindUserID(username string) (int, bool, error) {
+	var id int
+	err := sr.db.Instance().QueryRow(`SELECT id FROM users WHERE username = ? COLLATE NOCASE ORDER BY id LIMIT 1`, username).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return id, err == nil, err
+}
+
+// FirstRootUserID returns the oldest root user, rows from another instance whose creator is unknown here belong to them
+func (sr *SyncRepository) FirstRootUserID() (int, error) {
+	var id int
+	err := sr.db.Instance().QueryRow(`SELECT id FROM users WHERE is_root = 1 ORDER BY id LIMIT 1`).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("no root user to own synced rows")
+	}
+	return id, err
+}
+
+func scanSyncRow(row interface{ Scan(...interface{}) error }) (*SyncRow, error) {
+	syncRow := &SyncRow{}
+	err := row.Scan(&syncRow.Entity, &syncRow.LocalID, &syncRow.GlobalID, &syncRow.Clock, &syncRow.Origin,
+		&syncRow.Seq, &syncRow.LocalSeq, &syncRow.Deleted)
+	if err != nil {
+		return nil, err
+	}
+	return syncRow, nil
+}
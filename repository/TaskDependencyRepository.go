@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
+)
+
+// TaskDependency is the task on the other side of a dependency
+type TaskDependency struct {
+	TaskID      int       `json:"task_id"`
+	Title       string    `json:"title"`
+	ColumnID    int       `json:"column_id"`
+	ColumnTitle string    `json:"column_title"`
+	IsDone      bool      `json:"is_done"`
+	CreatedBy   *int      `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BlockedTaskError is returned when a task with unfinished blockers is moved into a done column
+type BlockedTaskError struct {
+	Message   string            `json:"message"`
+	TaskID    int               `json:"task_id"`
+	ColumnID  int               `json:"column_id"`
+	BlockedBy []*TaskDependency `json:"blocked_by"`
+}
+
+func (e *BlockedTaskError) Error() string {
+	return e.Message
+}
+
+type TaskDependencyRepository struct {
+	db *database.Database
+}
+
+func NewTaskDependencyRepository(db *database.Database) *TaskDependencyRepository {
+	return &TaskDependencyRepository{
+		db: db,
+	}
+}
+
+// Mark a task as blocked by another task
+func (dr *TaskDependencyRepository) Create(taskID, blockedByTaskID, createdBy int) error {
+	query := `
+		INSERT INTO task_dependencies (task_id, blocked_by_task_id, created_by, created_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)`
+
+	_, err := dr.db.Instance().Exec(query, taskID, blockedByTaskID, createdBy)
+	return err
+}
+
+// Remove a dependency between two tasks
+func (dr *TaskDependencyRepository) Delete(taskID, blockedByTaskID int) error {
+	query := `DELETE FROM task_dependencies WHERE task_id = ? AND blocked_by_task_id = ?`
+
+	result, err := dr.db.Instance().Exec(query, taskID, blockedByTaskID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("dependency not found")
+	}
+
+	return nil
+}
+
+// Remove every dependency a task takes part in
+func (dr *TaskDependencyRepository) DeleteByTask(taskID int) error {
+	query := `DELETE FROM task_dependencies WHERE task_id = ? OR blocked_by_task_id = ?`
+
+	_, err := dr.db.Instance().Exec(query, taskID, taskID)
+	return err
+}
+
+// Check if a task is already blocked by another task
+func (dr *TaskDependencyRepository) Exists(taskID, blockedByTaskID int) (bool, error) {
+	query := `SELECT COUNT(*) FROM task_dependencies WHERE task_id = ? AND blocked_by_task_id = ?`
+
+	var count int
+	err := dr.db.Instance().QueryRow(query, taskID, blockedByTaskID).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Check if blocking a task by another task would close a loop, which happens when
+// the blocker already waits on the task through its own chain of blockers
+func (dr *TaskDependencyRepository) CreatesCycle(taskID, blockedByTaskID int) (bool, error) {
+	if taskID == blockedByTaskID {
+		return true, nil
+	}
+
+	query := `
+		WITH RECURSIVE chain(id) AS (
+			SELECT blocked_by_task_id FROM task_dependencies WHERE task_id = ?
+			UNION
+			SELECT d.blocked_by_task_id
+			FROM task_dependencies d
+			JOIN chain c ON d.task_id = c.id
+		)
+		SELECT COUNT(*) FROM chain WHERE id = ?`
+
+	var count int
+	err := dr.db.Instance().QueryRow(query, blockedByTaskID, taskID).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Get the tasks blocking each of the given tasks, keyed by task ID
+func (dr *TaskDependencyRepository) GetBlockedBy(taskIDs []int) (map[int][]*TaskDependency, error) {
+	return dr.getRelated(taskIDs, "task_id", "blocked_by_task_id")
+}
+
+// Get the tasks each of the given tasks is blocking, keyed by task ID
+func (dr *TaskDependencyRepository) GetBlocks(taskIDs []int) (map[int][]*TaskDependency, error) {
+	return dr.getRelated(taskIDs, "blocked_by_task_id", "task_id")
+}
+
+// Get the blockers of a task that are not in a done column yet
+func (dr *TaskDependencyRepository) GetOpenBlockers(taskID int) ([]*TaskDependency, error) {
+	byTask, err := dr.GetBlockedBy([]int{taskID})
+	if err != nil {
+		return nil, err
+	}
+
+	var open []*TaskDependency
+	for _, blocker := range byTask[taskID] {
+		if !blocker.IsDone {
+			open = append(open, blocker)
+		}
+	}
+
+	return open, nil
+}
+
+// Helper to load the tasks on the other side of the dependencies of several tasks
+func (dr *TaskDependencyRepository) getRelated(taskIDs []int, ownColumn, otherColumn string) (map[int][]*TaskDependency, error) {
+	byTask := make(map[int][]*TaskDependency)
+	if len(taskIDs) == 0 {
+		return byTask, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT d.%[1]s, t.id, t.title, t.column_id, COALESCE(c.title, ''), COALESCE(c.is_done, 0),
+		       d.created_by, d.created_at
+		FROM task_dependencies d
+		JOIN tasks t ON d.%[2]s = t.id AND t.deleted_at IS NULL
+		LEFT JOIN columns c ON t.column_id = c.id
+		WHERE d.%[1]s IN (%[3]s)
+		ORDER BY d.created_at ASC, t.id ASC`, ownColumn, otherColumn, placeholders(len(taskIDs)))
+
+	rows, err := dr.db.Instance().Query(query, util.ConvertToInterface(taskIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int
+		dependency := &TaskDependency{}
+		err := rows.Scan(
+			&taskID,
+			&dependency.TaskID,
+			&dependency.Title,
+			&dependency.ColumnID,
+			&dependency.ColumnTitle,
+			&dependency.IsDone,
+			&dependency.CreatedBy,
+			&dependency.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		byTask[taskID] = append(byTask[taskID], dependency)
+	}
+
+	return byTask, nil
+}
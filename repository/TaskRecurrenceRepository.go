@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+)
+
+// TaskRecurrence re-creates its template task in a column on a schedule
+type TaskRecurrence struct {
+	ID        int        `json:"id"`
+	TaskID    int        `json:"task_id"`
+	ColumnID  int        `json:"column_id"`
+	Frequency string     `json:"frequency"` // daily, weekly, monthly, cron
+	Interval  int        `json:"interval"`
+	CronExpr  *string    `json:"cron_expr"`
+	CatchUp   bool       `json:"catch_up"` // Create every missed instance instead of only the latest
+	IsActive  bool       `json:"is_active"`
+	StartsAt  time.Time  `json:"starts_at"`
+	NextRunAt *time.Time `json:"next_run_at"`
+	LastRunAt *time.Time `json:"last_run_at"`
+	CreatedBy int        `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type TaskRecurrenceRepository struct {
+	db *database.Database
+}
+
+func NewTaskRecurrenceRepository(db *database.Database) *TaskRecurrenceRepository {
+	return &TaskRecurrenceRepository{
+		db: db,
+	}
+}
+
+const recurrenceSelect = `
+	SELECT id, task_id, column_id, frequency, interval, cron_expr, catch_up, is_active,
+	       starts_at, next_run_at, last_run_at, created_by, created_at, updated_at
+	FROM task_recurrences`
+
+// Find the recurrence rule of a template task
+func (rr *TaskRecurrenceRepository) FindByTask(taskID int) (*TaskRecurrence, error) {
+	recurrence, err := rr.scanRecurrence(rr.db.Instance().QueryRow(recurrenceSelect+` WHERE task_id = ?`, taskID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("recurrence not found")
+		}
+		return nil, err
+	}
+
+	return recurrence, nil
+}
+
+// Create or replace the recurrence rule of a template task
+func (rr *TaskRecurrenceRepository) Save(taskID, columnID int, frequency string, interval int, cronExpr *string,
+	catchUp, isActive bool, startsAt time.Time, nextRunAt *time.Time, createdBy int) (*TaskRecurrence, error) {
+
+	query := `
+		INSERT INTO task_recurrences (task_id, column_id, frequency, interval, cron_expr, catch_up, is_active,
+		                              starts_at, next_run_at, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(task_id) DO UPDATE SET
+			column_id = excluded.column_id,
+			frequency = excluded.frequency,
+			interval = excluded.interval,
+			cron_expr = excluded.cron_expr,
+			catch_up = excluded.catch_up,
+			is_active = excluded.is_active,
+			starts_at = excluded.starts_at,
+			next_run_at = excluded.next_run_at`
+
+	_, err := rr.db.Instance().Exec(query, taskID, columnID, frequency, interval, cronExpr, catchUp, isActive,
+		startsAt.UTC(), utcOrNil(nextRunAt), createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return rr.FindByTask(taskID)
+}
+
+// Remove the recurrence rule of a template task
+func (rr *TaskRecurrenceRepository) DeleteByTask(taskID int) error {
+	result, err := rr.db.Instance().Exec(`DELETE FROM task_recurrences WHERE task_id = ?`, taskID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("recurrence not found")
+	}
+
+	return nil
+}
+
+// Get every active rule, the scheduler decides which ones are due
+func (rr *TaskRecurrenceRepository) GetActive() ([]*TaskRecurrence, error) {
+	rows, err := rr.db.Instance().Query(recurrenceSelect + ` WHERE is_active = 1 AND task_id IN (` + liveTaskIDs + `) ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recurrences []*TaskRecurrence
+	for rows.Next() {
+		recurrence, err := rr.scanRecurrence(rows)
+		if err != nil {
+			return nil, err
+		}
+		recurrences = append(recurrences, recurrence)
+	}
+
+	return recurrences, rows.Err()
+}
+
+// Store the outcome of a scheduler run, a nil nextRunAt means the rule has no further occurrences
+func (rr *TaskRecurrenceRepository) MarkRun(id int, lastRunAt time.Time, nextRunAt *time.Time) error {
+	query := `
+		UPDATE task_recurrences
+		SET last_run_at = ?,
+		    next_run_at = ?,
+		    is_active = ?
+		WHERE id = ?`
+
+	_, err := rr.db.Instance().Exec(query, lastRunAt.UTC(), utcOrNil(nextRunAt), nextRunAt != nil, id)
+	return err
+}
+
+// Helper to scan a recurrence from a row or rows
+func (rr *TaskRecurrenceRepository) scanRecurrence(row interface{ Scan(...interface{}) error }) (*TaskRecurrence, error) {
+	recurrence := &TaskRecurrence{}
+	err := row.Scan(
+		&recurrence.ID,
+		&recurrence.TaskID,
+		&recurrence.ColumnID,
+		&recurrence.Frequency,
+		&recurrence.Interval,
+		&recurrence.CronExpr,
+		&recurrence.CatchUp,
+		&recurrence.IsActive,
+		&recurrence.StartsAt,
+		&recurrence.NextRunAt,
+		&recurrence.LastRunAt,
+		&recurrence.CreatedBy,
+		&recurrence.CreatedAt,
+		&recurrence.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return recurrence, nil
+}
+
+// utcOrNil stores optional times in UTC like CURRENT_TIMESTAMP does
+func utcOrNil(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
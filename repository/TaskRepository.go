@@ -3,10 +3,14 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/fulltext"
+	"github.com/dev-parvej/offline_kanban/pkg/taskquery"
+	"github.com/dev-parvej/offline_kanban/pkg/util"
 )
 
 type Task struct {
@@ -20,32 +24,59 @@ type Task struct {
 	Priority    *string    `json:"priority"`
 	Position    int        `json:"position"`
 	Weight      int        `json:"weight"`
+	ParentID    *int       `json:"parent_id"`            // NULL for top level tasks
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"` // Set while the task is in the trash
+	DeletedBy   *int       `json:"deleted_by,omitempty"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"` // Set once the task is off the board
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 
 	// Related data (loaded separately)
-	AssignedUser  *User   `json:"assigned_user,omitempty"`
-	CreatedByUser *User   `json:"created_by_user,omitempty"`
-	ColumnTitle   *string `json:"column_title,omitempty"`
-	CommentCount  int     `json:"comment_count,omitempty"`
+	AssignedUser  *User             `json:"assigned_user,omitempty"`
+	CreatedByUser *User             `json:"created_by_user,omitempty"`
+	ColumnTitle   *string           `json:"column_title,omitempty"`
+	CommentCount  int               `json:"comment_count,omitempty"`
+	Labels        []*Label          `json:"labels,omitempty"`
+	BlockedBy     []*TaskDependency `json:"blocked_by,omitempty"`
+	Blocks        []*TaskDependency `json:"blocks,omitempty"`
+	Subtasks      *SubtaskProgress  `json:"subtasks,omitempty"`
+	SearchSnippet *string           `json:"search_snippet,omitempty"` // Matched text with <mark> highlights
+}
+
+// SubtaskProgress rolls up the children of a task, a child counts as done once it sits in a done column
+type SubtaskProgress struct {
+	Total int `json:"total"`
+	Done  int `json:"done"`
 }
 
 type TaskFilters struct {
-	Search      *string    `json:"search"`
-	ColumnID    *int       `json:"column_id"`
-	AssignedTo  *int       `json:"assigned_to"`
-	CreatedBy   *int       `json:"created_by"`
-	Priority    *string    `json:"priority"`
-	DueDateFrom *time.Time `json:"due_date_from"`
-	DueDateTo   *time.Time `json:"due_date_to"`
-	CreatedFrom *time.Time `json:"created_from"`
-	CreatedTo   *time.Time `json:"created_to"`
-	Limit       *int       `json:"limit"`
-	Offset      *int       `json:"offset"`
-	OrderBy     string     `json:"order_by"`  // position, created_at, updated_at, title, due_date
-	OrderDir    string     `json:"order_dir"` // asc, desc
+	Search          *string        `json:"search"`
+	Query           taskquery.Node `json:"-"` // Parsed q= query, ANDed with the other filters
+	BoardID         *int           `json:"board_id"`
+	ColumnID        *int           `json:"column_id"`
+	AssignedTo      *int           `json:"assigned_to"`
+	CreatedBy       *int           `json:"created_by"`
+	Priority        *string        `json:"priority"`
+	MentionedUserID *int           `json:"mentioned_user_id"`
+	ParentID        *int           `json:"parent_id"`
+	TopLevelOnly    bool           `json:"top_level_only"` // Skip tasks that have a parent
+	Trashed         bool           `json:"trashed"`        // Only tasks in the trash instead of none of them
+	IncludeArchived bool           `json:"include_archived"`
+	LabelIDs        []int          `json:"label_ids"`
+	LabelMode       string         `json:"label_mode"` // any, all
+	DueDateFrom     *time.Time     `json:"due_date_from"`
+	DueDateTo       *time.Time     `json:"due_date_to"`
+	CreatedFrom     *time.Time     `json:"created_from"`
+	CreatedTo       *time.Time     `json:"created_to"`
+	Limit           *int           `json:"limit"`
+	Offset          *int           `json:"offset"`
+	OrderBy         string         `json:"order_by"`  // position, created_at, updated_at, title, due_date, deleted_at, relevance
+	OrderDir        string         `json:"order_dir"` // asc, desc
 }
 
+// liveTaskIDs selects the tasks that are not in the trash, for queries on tables referencing tasks
+const liveTaskIDs = `SELECT id FROM tasks WHERE deleted_at IS NULL`
+
 type TaskRepository struct {
 	db *database.Database
 }
@@ -61,9 +92,9 @@ func (tr *TaskRepository) FindByID(id int) (*Task, error) {
 	task := &Task{}
 	query := `
 		SELECT id, title, description, column_id, assigned_to, created_by, 
-		       due_date, priority, position, weight, created_at, updated_at
+		       due_date, priority, position, weight, parent_id, archived_at, created_at, updated_at
 		FROM tasks 
-		WHERE id = ?`
+		WHERE id = ? AND deleted_at IS NULL`
 
 	err := tr.db.Instance().QueryRow(query, id).Scan(
 		&task.ID,
@@ -76,6 +107,8 @@ func (tr *TaskRepository) FindByID(id int) (*Task, error) {
 		&task.Priority,
 		&task.Position,
 		&task.Weight,
+		&task.ParentID,
+		&task.ArchivedAt,
 		&task.CreatedAt,
 		&task.UpdatedAt,
 	)
@@ -95,7 +128,7 @@ func (tr *TaskRepository) FindByIDWithRelation(id int) (*Task, error) {
 	task := &Task{}
 	query := `
 		SELECT t.id, t.title, t.description, t.column_id, t.assigned_to, t.created_by, 
-		       t.due_date, t.priority, t.position, t.weight, t.created_at, t.updated_at,
+		       t.due_date, t.priority, t.position, t.weight, t.parent_id, t.archived_at, t.created_at, t.updated_at,
 		       au.username as assigned_username, au.name as assigned_name,
 		       cu.username as created_username, cu.name as created_name,
 		       c.title as column_title,
@@ -105,13 +138,13 @@ func (tr *TaskRepository) FindByIDWithRelation(id int) (*Task, error) {
 		LEFT JOIN users cu ON t.created_by = cu.id
 		LEFT JOIN columns c ON t.column_id = c.id
 		LEFT JOIN comments comm ON t.id = comm.task_id
-		WHERE t.id = ?`
+		WHERE t.id = ? AND t.deleted_at IS NULL`
 
 	var assignedUsername, assignedName, createdUsername, createdName, columnTitle sql.NullString
 
 	err := tr.db.Instance().QueryRow(query, id).Scan(&task.ID, &task.Title, &task.Description, &task.ColumnID,
 		&task.AssignedTo, &task.CreatedBy, &task.DueDate, &task.Priority,
-		&task.Position, &task.Weight, &task.CreatedAt, &task.UpdatedAt,
+		&task.Position, &task.Weight, &task.ParentID, &task.ArchivedAt, &task.CreatedAt, &task.UpdatedAt,
 		&assignedUsername, &assignedName, &createdUsername, &createdName,
 		&columnTitle, &task.CommentCount,
 	)
@@ -140,6 +173,18 @@ func (tr *TaskRepository) FindByIDWithRelation(id int) (*Task, error) {
 		return nil, err
 	}
 
+	if err := tr.LoadLabels(task); err != nil {
+		return nil, err
+	}
+
+	if err := tr.LoadDependencies(task); err != nil {
+		return nil, err
+	}
+
+	if err := tr.LoadSubtaskProgress(task); err != nil {
+		return nil, err
+	}
+
 	return task, nil
 }
 
@@ -172,6 +217,61 @@ func (tr *TaskRepository) Create(title string, description *string, columnID, cr
 	return tr.FindByID(int(id))
 }
 
+// Create a task with its checklist and labels in one transaction, labels that no longer exist are skipped
+func (tr *TaskRepository) CreateWithChecklist(title string, description *string, columnID, createdBy int,
+	assignedTo *int, dueDate *time.Time, priority *string, checklistItems []string, labelIDs []int) (*Task, error) {
+
+	tx, err := tr.db.Instance().Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var maxPosition sql.NullInt64
+	err = tx.QueryRow(`SELECT MAX(position) FROM tasks WHERE column_id = ?`, columnID).Scan(&maxPosition)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO tasks (title, description, column_id, assigned_to, created_by, 
+		                   due_date, priority, position, weight, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 0, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		title, description, columnID, assignedTo, createdBy, dueDate, priority, int(maxPosition.Int64)+1)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range checklistItems {
+		_, err := tx.Exec(`
+			INSERT INTO checklists (title, task_id, created_by, created_at, updated_at)
+			VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`, item, id, createdBy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, labelID := range uniqueIDs(labelIDs) {
+		_, err := tx.Exec(`
+			INSERT INTO task_labels (task_id, label_id, created_at)
+			SELECT ?, id, CURRENT_TIMESTAMP FROM labels WHERE id = ?`, id, labelID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return tr.FindByID(int(id))
+}
+
 // Update task
 func (tr *TaskRepository) Update(id int, title *string, description *string,
 	assignedTo *int, dueDate *time.Time, priority *string) (*Task, error) {
@@ -206,8 +306,9 @@ func (tr *TaskRepository) MoveToColumn(id, columnID, newPosition int) error {
 	// Update task column and position
 	_, err = tx.Exec(`
 		UPDATE tasks 
-		SET column_id = ?, position = ?, updated_at = CURRENT_TIMESTAMP 
-		WHERE id = ?`, columnID, newPosition, id)
+		SET moved_at = CASE WHEN column_id = ? THEN moved_at ELSE CURRENT_TIMESTAMP END,
+		    column_id = ?, position = ?, updated_at = CURRENT_TIMESTAMP 
+		WHERE id = ?`, columnID, columnID, newPosition, id)
 	if err != nil {
 		return err
 	}
@@ -225,7 +326,224 @@ func (tr *TaskRepository) MoveToColumn(id, columnID, newPosition int) error {
 	return tx.Commit()
 }
 
-// Delete task
+// Take a task off the board, it keeps its column and can still be opened
+func (tr *TaskRepository) Archive(id int) error {
+	result, err := tr.db.Instance().Exec(`
+		UPDATE tasks
+		SET archived_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND deleted_at IS NULL AND archived_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("task not found or already archived")
+	}
+
+	return nil
+}
+
+// Put an archived task back on the board at the end of its column. It counts as just moved there,
+// so auto-archive doesn't take it right off again
+func (tr *TaskRepository) Unarchive(id int) error {
+	result, err := tr.db.Instance().Exec(`
+		UPDATE tasks
+		SET archived_at = NULL,
+		    moved_at = CURRENT_TIMESTAMP,
+		    position = (SELECT COALESCE(MAX(c.position), 0) + 1 FROM tasks c
+		                WHERE c.column_id = tasks.column_id AND c.deleted_at IS NULL AND c.archived_at IS NULL)
+		WHERE id = ? AND deleted_at IS NULL AND archived_at IS NOT NULL`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("task not found or not archived")
+	}
+
+	return nil
+}
+
+// Get the IDs of the tasks that have sat in a done column for longer than the column's auto-archive
+// days, or defaultDays for columns without their own. A value of 0 days never archives
+func (tr *TaskRepository) GetArchivable(defaultDays int) ([]int, error) {
+	query := `
+		SELECT t.id
+		FROM tasks t
+		JOIN columns c ON t.column_id = c.id
+		WHERE t.deleted_at IS NULL AND t.archived_at IS NULL AND c.is_done = 1
+		  AND COALESCE(c.auto_archive_days, ?) > 0
+		  AND COALESCE(t.moved_at, t.updated_at) <= datetime('now', '-' || COALESCE(c.auto_archive_days, ?) || ' days')
+		ORDER BY t.id ASC`
+
+	return tr.queryIDs(query, defaultDays, defaultDays)
+}
+
+// Move a task to the trash along with its subtasks, they keep their comments, checklists, labels and
+// dependencies and are left out of every other query until restored
+func (tr *TaskRepository) Trash(id, deletedBy int) error {
+	query := `
+		WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM tasks WHERE id = ? AND deleted_at IS NULL
+			UNION
+			SELECT t.id
+			FROM tasks t
+			JOIN subtree s ON t.parent_id = s.id
+			WHERE t.deleted_at IS NULL
+		)
+		UPDATE tasks
+		SET deleted_at = CURRENT_TIMESTAMP,
+		    deleted_by = ?
+		WHERE id IN (SELECT id FROM subtree)`
+
+	result, err := tr.db.Instance().Exec(query, id, deletedBy)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("task not found")
+	}
+
+	return nil
+}
+
+// Find a task in the trash by ID
+func (tr *TaskRepository) FindTrashedByID(id int) (*Task, error) {
+	task := &Task{}
+	query := `
+		SELECT id, title, description, column_id, assigned_to, created_by, 
+		       due_date, priority, position, weight, parent_id, created_at, updated_at,
+		       deleted_at, deleted_by
+		FROM tasks 
+		WHERE id = ? AND deleted_at IS NOT NULL`
+
+	err := tr.db.Instance().QueryRow(query, id).Scan(
+		&task.ID, &task.Title, &task.Description, &task.ColumnID,
+		&task.AssignedTo, &task.CreatedBy, &task.DueDate, &task.Priority,
+		&task.Position, &task.Weight, &task.ParentID, &task.CreatedAt, &task.UpdatedAt,
+		&task.DeletedAt, &task.DeletedBy,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("task not found in trash")
+		}
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// Get the IDs of a trashed task and the subtasks that went to the trash together with it
+func (tr *TaskRepository) GetTrashedWith(id int) ([]int, error) {
+	query := `
+		WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM tasks WHERE id = ? AND deleted_at IS NOT NULL
+			UNION
+			SELECT t.id
+			FROM tasks t
+			JOIN subtree s ON t.parent_id = s.id
+			WHERE t.deleted_at = (SELECT deleted_at FROM tasks WHERE id = ?)
+		)
+		SELECT id FROM subtree`
+
+	return tr.queryIDs(query, id, id)
+}
+
+// Get the IDs of the tasks that have been in the trash for more than the given number of days
+func (tr *TaskRepository) GetTrashedBefore(days int) ([]int, error) {
+	query := `
+		SELECT id FROM tasks
+		WHERE deleted_at IS NOT NULL
+		  AND deleted_at <= datetime('now', '-' || ? || ' days')
+		ORDER BY id ASC`
+
+	return tr.queryIDs(query, days)
+}
+
+// Get the tasks among ids whose column was deleted or archived
+func (tr *TaskRepository) GetWithoutActiveColumn(ids []int) ([]int, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT id FROM tasks
+		WHERE id IN (` + placeholders(len(ids)) + `)
+		  AND column_id NOT IN (SELECT id FROM columns WHERE deleted_at IS NULL)
+		ORDER BY id ASC`
+
+	return tr.queryIDs(query, util.ConvertToInterface(ids)...)
+}
+
+// Take a trashed task and the subtasks trashed with it out of the trash, the task goes to the end of its column.
+// Those whose column was deleted or archived go to columnID when it is given
+func (tr *TaskRepository) Restore(id int, columnID *int) error {
+	trashedIDs, err := tr.GetTrashedWith(id)
+	if err != nil {
+		return err
+	}
+
+	if len(trashedIDs) == 0 {
+		return errors.New("task not found in trash")
+	}
+
+	tx, err := tr.db.Instance().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE tasks
+		SET deleted_at = NULL,
+		    deleted_by = NULL
+		WHERE id IN (`+placeholders(len(trashedIDs))+`)`, util.ConvertToInterface(trashedIDs)...)
+	if err != nil {
+		return err
+	}
+
+	if columnID != nil {
+		args := append([]interface{}{*columnID}, util.ConvertToInterface(trashedIDs)...)
+		_, err = tx.Exec(`
+			UPDATE tasks
+			SET column_id = ?
+			WHERE id IN (`+placeholders(len(trashedIDs))+`)
+			  AND column_id NOT IN (SELECT id FROM columns WHERE deleted_at IS NULL)`, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Other tasks may have taken its place in the column meanwhile
+	_, err = tx.Exec(`
+		UPDATE tasks
+		SET position = (SELECT COALESCE(MAX(c.position), 0) + 1 FROM tasks c WHERE c.column_id = tasks.column_id AND c.deleted_at IS NULL)
+		WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete task for good along with its comments, checklists and everything else pointing at it
 func (tr *TaskRepository) Delete(id int) error {
 	query := `DELETE FROM tasks WHERE id = ?`
 
@@ -243,16 +561,120 @@ func (tr *TaskRepository) Delete(id int) error {
 		return errors.New("task not found")
 	}
 
-	return nil
+	// Foreign keys are not enforced, so drop the rows referencing the task by hand
+	for _, table := range []string{"task_labels", "task_recurrences", "comments", "checklists", "mentions", "notifications"} {
+		_, err = tr.db.Instance().Exec(`DELETE FROM `+table+` WHERE task_id = ?`, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Children of the deleted task become top level tasks
+	_, err = tr.db.Instance().Exec(`UPDATE tasks SET parent_id = NULL WHERE parent_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return NewTaskDependencyRepository(tr.db).DeleteByTask(id)
+}
+
+// Attach a task to a parent task, or detach it when parentID is nil
+func (tr *TaskRepository) SetParent(id int, parentID *int) (*Task, error) {
+	query := `
+		UPDATE tasks
+		SET parent_id = ?,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	result, err := tr.db.Instance().Exec(query, parentID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, errors.New("task not found")
+	}
+
+	return tr.FindByID(id)
+}
+
+// Get the direct children of a task
+func (tr *TaskRepository) GetChildren(parentID int) ([]*Task, error) {
+	query := `
+		SELECT id, title, description, column_id, assigned_to, created_by, 
+		       due_date, priority, position, weight, parent_id, created_at, updated_at
+		FROM tasks 
+		WHERE parent_id = ? AND deleted_at IS NULL
+		ORDER BY position ASC, id ASC`
+
+	rows, err := tr.db.Instance().Query(query, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return tr.scanTasks(rows)
+}
+
+// Check if making parentID the parent of a task would close a loop, which happens when
+// the task is the parent itself or one of its ancestors
+func (tr *TaskRepository) CreatesParentCycle(id, parentID int) (bool, error) {
+	if id == parentID {
+		return true, nil
+	}
+
+	query := `
+		WITH RECURSIVE ancestors(id) AS (
+			SELECT parent_id FROM tasks WHERE id = ? AND parent_id IS NOT NULL
+			UNION
+			SELECT t.parent_id
+			FROM tasks t
+			JOIN ancestors a ON t.id = a.id
+			WHERE t.parent_id IS NOT NULL
+		)
+		SELECT COUNT(*) FROM ancestors WHERE id = ?`
+
+	var count int
+	err := tr.db.Instance().QueryRow(query, parentID, id).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Get the board a task belongs to through its column
+func (tr *TaskRepository) GetBoardID(id int) (int, error) {
+	var boardID sql.NullInt64
+	query := `
+		SELECT c.board_id
+		FROM tasks t
+		JOIN columns c ON t.column_id = c.id
+		WHERE t.id = ?`
+
+	err := tr.db.Instance().QueryRow(query, id).Scan(&boardID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, errors.New("task not found")
+		}
+		return 0, err
+	}
+
+	return int(boardID.Int64), nil
 }
 
 // Get tasks by column
 func (tr *TaskRepository) GetByColumn(columnID int) ([]*Task, error) {
 	query := `
 		SELECT id, title, description, column_id, assigned_to, created_by, 
-		       due_date, priority, position, weight, created_at, updated_at
+		       due_date, priority, position, weight, parent_id, created_at, updated_at
 		FROM tasks 
-		WHERE column_id = ?
+		WHERE column_id = ? AND deleted_at IS NULL AND archived_at IS NULL
 		ORDER BY position ASC`
 
 	rows, err := tr.db.Instance().Query(query, columnID)
@@ -288,22 +710,137 @@ func (tr *TaskRepository) CountWithFilters(filters TaskFilters) (int, error) {
 
 // Get tasks with related data (joins)
 func (tr *TaskRepository) GetWithRelations(filters TaskFilters) ([]*Task, error) {
+	query, args := tr.listQuery(filters, "SUBSTR(t.description, 1, 400)")
+
+	rows, err := tr.db.Instance().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*Task
+	for rows.Next() {
+		task, err := scanListTask(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tr.LoadLabels(tasks...); err != nil {
+		return nil, err
+	}
+
+	if err := tr.LoadDependencies(tasks...); err != nil {
+		return nil, err
+	}
+
+	if err := tr.LoadSubtaskProgress(tasks...); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// EachWithRelations passes the tasks matching the filters to fn as they are read, with full descriptions
+// and labels, so large exports never hold the whole list. Tasks are read in batches for their labels
+func (tr *TaskRepository) EachWithRelations(filters TaskFilters, fn func(task *Task) error) error {
+	query, args := tr.listQuery(filters, "t.description")
+
+	rows, err := tr.db.Instance().Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	const batchSize = 200
+	batch := make([]*Task, 0, batchSize)
+
+	flush := func() error {
+		if err := tr.LoadLabels(batch...); err != nil {
+			return err
+		}
+		for _, task := range batch {
+			if err := fn(task); err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for rows.Next() {
+		task, err := scanListTask(rows)
+		if err != nil {
+			return err
+		}
+
+		batch = append(batch, task)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(batch) > 0 {
+		return flush()
+	}
+	return nil
+}
+
+// listQuery builds the task list query of GetWithRelations for the filters, selecting description
+// as the description column
+func (tr *TaskRepository) listQuery(filters TaskFilters, description string) (string, []interface{}) {
 	baseQuery := `
-		SELECT t.id, t.title, SUBSTR(t.description, 1, 400), t.column_id, t.assigned_to, t.created_by, 
-		       t.due_date, t.priority, t.position, t.weight, t.created_at, t.updated_at,
+		SELECT t.id, t.title, ` + description + `, t.column_id, t.assigned_to, t.created_by, 
+		       t.due_date, t.priority, t.position, t.weight, t.parent_id, t.deleted_at, t.deleted_by,
+		       t.archived_at, t.created_at, t.updated_at,
 		       au.username as assigned_username, au.name as assigned_name,
 		       cu.username as created_username, cu.name as created_name,
 		       c.title as column_title,
-		       COUNT(comm.id) as comment_count
+		       COUNT(comm.id) as comment_count,
+		       %s as search_snippet
 		FROM tasks t
 		LEFT JOIN users au ON t.assigned_to = au.id
 		LEFT JOIN users cu ON t.created_by = cu.id
 		LEFT JOIN columns c ON t.column_id = c.id
 		LEFT JOIN comments comm ON t.id = comm.task_id`
 
-	whereClause, args := tr.buildWhereClause(filters)
+	var args []interface{}
+
+	// Full-text matches are joined to rank them and cut a highlighted snippet
+	matchQuery := tr.searchMatchQuery(filters)
+	if matchQuery != "" {
+		// Materialized so the ranking functions run against the FTS table, not a flattened join
+		baseQuery = `
+		WITH search AS MATERIALIZED (
+			SELECT rowid AS task_id,
+			       bm25(tasks_search, 10.0, 4.0, 2.0, 2.0) AS rank,
+			       snippet(tasks_search, -1, ?, ?, '…', 16) AS snippet
+			FROM tasks_search
+			WHERE tasks_search MATCH ?
+		)` + fmt.Sprintf(baseQuery, "s.snippet") + `
+		JOIN search s ON s.task_id = t.id`
+		args = append(args, fulltext.MarkStart, fulltext.MarkEnd, matchQuery)
+		filters.Search = nil
+	} else {
+		baseQuery = fmt.Sprintf(baseQuery, "NULL")
+	}
+
+	whereClause, whereArgs := tr.buildWhereClause(filters)
 	if whereClause != "" {
 		baseQuery += " WHERE " + whereClause
+		args = append(args, whereArgs...)
 	}
 
 	baseQuery += " GROUP BY t.id"
@@ -311,10 +848,15 @@ func (tr *TaskRepository) GetWithRelations(filters TaskFilters) ([]*Task, error)
 	// Add ordering
 	orderBy := "t.position"
 	orderDir := "ASC"
-	if filters.OrderBy != "" {
+	if filters.OrderBy == "relevance" {
+		// Best match first, bm25 scores are lower for better matches
+		if matchQuery != "" {
+			orderBy = "s.rank"
+		}
+	} else if filters.OrderBy != "" {
 		orderBy = "t." + filters.OrderBy
 	}
-	if filters.OrderDir == "desc" {
+	if filters.OrderDir == "desc" && orderBy != "s.rank" {
 		orderDir = "DESC"
 	}
 	baseQuery += " ORDER BY " + orderBy + " " + orderDir
@@ -330,52 +872,169 @@ func (tr *TaskRepository) GetWithRelations(filters TaskFilters) ([]*Task, error)
 		}
 	}
 
-	rows, err := tr.db.Instance().Query(baseQuery, args...)
+	return baseQuery, args
+}
+
+// scanListTask reads one row of listQuery with the related data
+func scanListTask(rows *database.Rows) (*Task, error) {
+	task := &Task{}
+	var assignedUsername, assignedName, createdUsername, createdName, columnTitle, searchSnippet sql.NullString
+
+	err := rows.Scan(
+		&task.ID, &task.Title, &task.Description, &task.ColumnID,
+		&task.AssignedTo, &task.CreatedBy, &task.DueDate, &task.Priority,
+		&task.Position, &task.Weight, &task.ParentID, &task.DeletedAt, &task.DeletedBy,
+		&task.ArchivedAt, &task.CreatedAt, &task.UpdatedAt,
+		&assignedUsername, &assignedName, &createdUsername, &createdName,
+		&columnTitle, &task.CommentCount, &searchSnippet,
+	)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var tasks []*Task
-	for rows.Next() {
-		task := &Task{}
-		var assignedUsername, assignedName, createdUsername, createdName, columnTitle sql.NullString
+	// Set related data
+	if assignedUsername.Valid {
+		task.AssignedUser = &User{
+			UserName: assignedUsername.String,
+			Name:     &assignedName.String,
+		}
+	}
+	if createdUsername.Valid {
+		task.CreatedByUser = &User{
+			UserName: createdUsername.String,
+			Name:     &createdName.String,
+		}
+	}
+	if columnTitle.Valid {
+		task.ColumnTitle = &columnTitle.String
+	}
+	if searchSnippet.Valid {
+		snippet := fulltext.HighlightSnippet(searchSnippet.String)
+		task.SearchSnippet = &snippet
+	}
 
-		err := rows.Scan(
-			&task.ID, &task.Title, &task.Description, &task.ColumnID,
-			&task.AssignedTo, &task.CreatedBy, &task.DueDate, &task.Priority,
-			&task.Position, &task.Weight, &task.CreatedAt, &task.UpdatedAt,
-			&assignedUsername, &assignedName, &createdUsername, &createdName,
-			&columnTitle, &task.CommentCount,
-		)
-		if err != nil {
-			return nil, err
+	return task, nil
+}
+
+// Attach the labels of each task
+func (tr *TaskRepository) LoadLabels(tasks ...*Task) error {
+	taskIDs := make([]int, len(tasks))
+	for i, task := range tasks {
+		taskIDs[i] = task.ID
+	}
+
+	byTask, err := NewLabelRepository(tr.db).GetByTasks(taskIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		task.Labels = byTask[task.ID]
+		if task.Labels == nil {
+			task.Labels = make([]*Label, 0)
 		}
+	}
 
-		// Set related data
-		if assignedUsername.Valid {
-			task.AssignedUser = &User{
-				UserName: assignedUsername.String,
-				Name:     &assignedName.String,
-			}
+	return nil
+}
+
+// Load the blocked_by and blocks lists of several tasks at once
+func (tr *TaskRepository) LoadDependencies(tasks ...*Task) error {
+	taskIDs := make([]int, len(tasks))
+	for i, task := range tasks {
+		taskIDs[i] = task.ID
+	}
+
+	dependencyRepository := NewTaskDependencyRepository(tr.db)
+
+	blockedBy, err := dependencyRepository.GetBlockedBy(taskIDs)
+	if err != nil {
+		return err
+	}
+
+	blocks, err := dependencyRepository.GetBlocks(taskIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		task.BlockedBy = blockedBy[task.ID]
+		if task.BlockedBy == nil {
+			task.BlockedBy = make([]*TaskDependency, 0)
 		}
-		if createdUsername.Valid {
-			task.CreatedByUser = &User{
-				UserName: createdUsername.String,
-				Name:     &createdName.String,
-			}
+		task.Blocks = blocks[task.ID]
+		if task.Blocks == nil {
+			task.Blocks = make([]*TaskDependency, 0)
 		}
-		if columnTitle.Valid {
-			task.ColumnTitle = &columnTitle.String
+	}
+
+	return nil
+}
+
+// Roll up the children of several tasks at once
+func (tr *TaskRepository) LoadSubtaskProgress(tasks ...*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	taskIDs := make([]int, len(tasks))
+	for i, task := range tasks {
+		taskIDs[i] = task.ID
+	}
+
+	query := `
+		SELECT t.parent_id, COUNT(*), COALESCE(SUM(CASE WHEN c.is_done THEN 1 ELSE 0 END), 0)
+		FROM tasks t
+		LEFT JOIN columns c ON t.column_id = c.id
+		WHERE t.parent_id IN (` + placeholders(len(taskIDs)) + `) AND t.deleted_at IS NULL
+		GROUP BY t.parent_id`
+
+	rows, err := tr.db.Instance().Query(query, util.ConvertToInterface(taskIDs)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byTask := make(map[int]*SubtaskProgress)
+	for rows.Next() {
+		var parentID int
+		progress := &SubtaskProgress{}
+		if err := rows.Scan(&parentID, &progress.Total, &progress.Done); err != nil {
+			return err
 		}
+		byTask[parentID] = progress
+	}
 
-		tasks = append(tasks, task)
+	for _, task := range tasks {
+		task.Subtasks = byTask[task.ID]
+		if task.Subtasks == nil {
+			task.Subtasks = &SubtaskProgress{}
+		}
 	}
 
-	return tasks, nil
+	return nil
 }
 
 // Helper methods
+func (tr *TaskRepository) queryIDs(query string, args ...interface{}) ([]int, error) {
+	rows, err := tr.db.Instance().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
 func (tr *TaskRepository) getNextPosition(columnID int) (int, error) {
 	var maxPosition sql.NullInt64
 	query := `SELECT MAX(position) FROM tasks WHERE column_id = ?`
@@ -398,7 +1057,7 @@ func (tr *TaskRepository) buildFilterQuery(filters TaskFilters, isCount bool) (s
 	} else {
 		selectClause = `
 			SELECT t.id, t.title, t.description, t.column_id, t.assigned_to, t.created_by, 
-			       t.due_date, t.priority, t.position, t.weight, t.created_at, t.updated_at
+			       t.due_date, t.priority, t.position, t.weight, t.parent_id, t.created_at, t.updated_at
 			FROM tasks t`
 	}
 
@@ -412,7 +1071,7 @@ func (tr *TaskRepository) buildFilterQuery(filters TaskFilters, isCount bool) (s
 		// Add ordering
 		orderBy := "position"
 		orderDir := "ASC"
-		if filters.OrderBy != "" {
+		if filters.OrderBy != "" && filters.OrderBy != "relevance" {
 			orderBy = filters.OrderBy
 		}
 		if filters.OrderDir == "desc" {
@@ -439,12 +1098,39 @@ func (tr *TaskRepository) buildWhereClause(filters TaskFilters) (string, []inter
 	var conditions []string
 	var args []interface{}
 
-	if filters.Search != nil && *filters.Search != "" {
+	// Trashed tasks only show up in the trash
+	if filters.Trashed {
+		conditions = append(conditions, "t.deleted_at IS NOT NULL")
+	} else {
+		conditions = append(conditions, "t.deleted_at IS NULL")
+	}
+
+	// Archived tasks are off the board unless asked for
+	if !filters.Trashed && !filters.IncludeArchived {
+		conditions = append(conditions, "t.archived_at IS NULL")
+	}
+
+	if matchQuery := tr.searchMatchQuery(filters); matchQuery != "" {
+		conditions = append(conditions, "t.id IN (SELECT rowid FROM tasks_search WHERE tasks_search MATCH ?)")
+		args = append(args, matchQuery)
+	} else if filters.Search != nil && *filters.Search != "" {
+		// Without FTS5, or when the input has no words to match
 		conditions = append(conditions, "(t.title LIKE ? OR t.description LIKE ?)")
 		searchTerm := "%" + *filters.Search + "%"
 		args = append(args, searchTerm, searchTerm)
 	}
 
+	if filters.Query != nil {
+		queryCondition, queryArgs := tr.queryCondition(filters.Query)
+		conditions = append(conditions, queryCondition)
+		args = append(args, queryArgs...)
+	}
+
+	if filters.BoardID != nil {
+		conditions = append(conditions, "t.column_id IN (SELECT id FROM columns WHERE board_id = ?)")
+		args = append(args, *filters.BoardID)
+	}
+
 	if filters.ColumnID != nil {
 		conditions = append(conditions, "t.column_id = ?")
 		args = append(args, *filters.ColumnID)
@@ -465,6 +1151,36 @@ func (tr *TaskRepository) buildWhereClause(filters TaskFilters) (string, []inter
 		args = append(args, *filters.Priority)
 	}
 
+	if len(filters.LabelIDs) > 0 {
+		labelIDs := uniqueIDs(filters.LabelIDs)
+		labelCondition := "t.id IN (SELECT task_id FROM task_labels WHERE label_id IN (" + placeholders(len(labelIDs)) + ")"
+		if filters.LabelMode == "all" {
+			// Only tasks carrying every requested label
+			labelCondition += " GROUP BY task_id HAVING COUNT(DISTINCT label_id) = ?"
+		}
+		conditions = append(conditions, labelCondition+")")
+		for _, labelID := range labelIDs {
+			args = append(args, labelID)
+		}
+		if filters.LabelMode == "all" {
+			args = append(args, len(labelIDs))
+		}
+	}
+
+	if filters.ParentID != nil {
+		conditions = append(conditions, "t.parent_id = ?")
+		args = append(args, *filters.ParentID)
+	}
+
+	if filters.TopLevelOnly {
+		conditions = append(conditions, "t.parent_id IS NULL")
+	}
+
+	if filters.MentionedUserID != nil {
+		conditions = append(conditions, "t.id IN (SELECT task_id FROM mentions WHERE mentioned_user_id = ?)")
+		args = append(args, *filters.MentionedUserID)
+	}
+
 	if filters.DueDateFrom != nil {
 		conditions = append(conditions, "t.due_date >= ?")
 		args = append(args, *filters.DueDateFrom)
@@ -488,14 +1204,170 @@ func (tr *TaskRepository) buildWhereClause(filters TaskFilters) (string, []inter
 	return strings.Join(conditions, " AND "), args
 }
 
-func (tr *TaskRepository) scanTasks(rows *sql.Rows) ([]*Task, error) {
+// queryCondition compiles a parsed task query into a WHERE condition
+func (tr *TaskRepository) queryCondition(node taskquery.Node) (string, []interface{}) {
+	switch n := node.(type) {
+	case taskquery.And:
+		return tr.joinQueryConditions(n.Nodes, " AND ")
+	case taskquery.Or:
+		return tr.joinQueryConditions(n.Nodes, " OR ")
+	case taskquery.Not:
+		condition, args := tr.queryCondition(n.Node)
+		// NULL comparisons count as not matching, so -assignee:me keeps unassigned tasks
+		return "NOT COALESCE(" + condition + ", 0)", args
+	case taskquery.Condition:
+		return tr.fieldCondition(n)
+	}
+	return "1", nil
+}
+
+func (tr *TaskRepository) joinQueryConditions(nodes []taskquery.Node, operator string) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, node := range nodes {
+		condition, conditionArgs := tr.queryCondition(node)
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+	}
+	return "(" + strings.Join(conditions, operator) + ")", args
+}
+
+// fieldCondition compiles one field:value term
+func (tr *TaskRepository) fieldCondition(c taskquery.Condition) (string, []interface{}) {
+	switch c.Field {
+	case taskquery.FieldAssignee:
+		return userCondition("t.assigned_to", c)
+	case taskquery.FieldAuthor:
+		return userCondition("t.created_by", c)
+	case taskquery.FieldMentions:
+		if c.UserID != nil {
+			return "t.id IN (SELECT task_id FROM mentions WHERE mentioned_user_id = ?)", []interface{}{*c.UserID}
+		}
+		return `t.id IN (SELECT m.task_id FROM mentions m JOIN users u ON u.id = m.mentioned_user_id
+			WHERE u.username = ? COLLATE NOCASE)`, []interface{}{c.Text}
+	case taskquery.FieldPriority:
+		if c.None {
+			return "t.priority IS NULL", nil
+		}
+		rank := "CASE t.priority"
+		for i, priority := range taskquery.Priorities {
+			rank += fmt.Sprintf(" WHEN '%s' THEN %d", priority, i+1)
+		}
+		return rank + " END " + c.Operator + " ?", []interface{}{c.Number}
+	case taskquery.FieldDue:
+		return dateCondition("t.due_date", c)
+	case taskquery.FieldCreated:
+		return dateCondition("t.created_at", c)
+	case taskquery.FieldUpdated:
+		return dateCondition("t.updated_at", c)
+	case taskquery.FieldLabel:
+		if c.None {
+			return "t.id NOT IN (SELECT task_id FROM task_labels)", nil
+		}
+		return `t.id IN (SELECT tl.task_id FROM task_labels tl JOIN labels l ON l.id = tl.label_id
+			WHERE l.name = ? COLLATE NOCASE)`, []interface{}{c.Text}
+	case taskquery.FieldColumn:
+		if c.Text == "" {
+			return "t.column_id = ?", []interface{}{c.Number}
+		}
+		return "t.column_id IN (SELECT id FROM columns WHERE title = ? COLLATE NOCASE)", []interface{}{c.Text}
+	case taskquery.FieldBoard:
+		if c.Text == "" {
+			return "t.column_id IN (SELECT id FROM columns WHERE board_id = ?)", []interface{}{c.Number}
+		}
+		return `t.column_id IN (SELECT c.id FROM columns c JOIN boards b ON b.id = c.board_id
+			WHERE b.title = ? COLLATE NOCASE)`, []interface{}{c.Text}
+	case taskquery.FieldParent:
+		if c.None {
+			return "t.parent_id IS NULL", nil
+		}
+		return "t.parent_id = ?", []interface{}{c.Number}
+	case taskquery.FieldID:
+		return "t.id " + c.Operator + " ?", []interface{}{c.Number}
+	case taskquery.FieldTitle:
+		return "t.title LIKE ?", []interface{}{"%" + c.Text + "%"}
+	case taskquery.FieldIs:
+		switch c.Text {
+		case taskquery.IsDone:
+			return "t.column_id IN (SELECT id FROM columns WHERE is_done = 1)", nil
+		case taskquery.IsOpen:
+			return "t.column_id NOT IN (SELECT id FROM columns WHERE is_done = 1)", nil
+		case taskquery.IsBlocked:
+			return `EXISTS (SELECT 1 FROM task_dependencies d
+				JOIN tasks b ON b.id = d.blocked_by_task_id
+				LEFT JOIN columns bc ON bc.id = b.column_id
+				WHERE d.task_id = t.id AND b.deleted_at IS NULL AND COALESCE(bc.is_done, 0) = 0)`, nil
+		case taskquery.IsSubtask:
+			return "t.parent_id IS NOT NULL", nil
+		case taskquery.IsParent:
+			return "EXISTS (SELECT 1 FROM tasks child WHERE child.parent_id = t.id AND child.deleted_at IS NULL)", nil
+		}
+	case taskquery.FieldText:
+		search := c.Text
+		if matchQuery := tr.searchMatchQuery(TaskFilters{Search: &search}); matchQuery != "" {
+			return "t.id IN (SELECT rowid FROM tasks_search WHERE tasks_search MATCH ?)", []interface{}{matchQuery}
+		}
+		searchTerm := "%" + c.Text + "%"
+		return "(t.title LIKE ? OR t.description LIKE ?)", []interface{}{searchTerm, searchTerm}
+	}
+	return "1", nil
+}
+
+// userCondition matches a user column against me, none or a username
+func userCondition(column string, c taskquery.Condition) (string, []interface{}) {
+	switch {
+	case c.None:
+		return column + " IS NULL", nil
+	case c.UserID != nil:
+		return column + " = ?", []interface{}{*c.UserID}
+	default:
+		return column + " IN (SELECT id FROM users WHERE username = ? COLLATE NOCASE)", []interface{}{c.Text}
+	}
+}
+
+// dateCondition compares a date column with the local day of a date value. Stored dates are
+// normalized with datetime() since due dates keep their offset and timestamps are UTC
+func dateCondition(column string, c taskquery.Condition) (string, []interface{}) {
+	if c.None {
+		return column + " IS NULL", nil
+	}
+
+	value := "datetime(" + column + ")"
+	from := c.From.UTC().Format("2006-01-02 15:04:05")
+	to := c.To.UTC().Format("2006-01-02 15:04:05")
+
+	switch c.Operator {
+	case "<":
+		return value + " < ?", []interface{}{from}
+	case "<=":
+		return value + " < ?", []interface{}{to}
+	case ">":
+		return value + " >= ?", []interface{}{to}
+	case ">=":
+		return value + " >= ?", []interface{}{from}
+	case "!=":
+		return "NOT (" + value + " >= ? AND " + value + " < ?)", []interface{}{from, to}
+	default:
+		return "(" + value + " >= ? AND " + value + " < ?)", []interface{}{from, to}
+	}
+}
+
+// searchMatchQuery returns the FTS5 query for the search filter, empty when full-text search does not apply
+func (tr *TaskRepository) searchMatchQuery(filters TaskFilters) string {
+	if filters.Search == nil || !tr.db.FullTextSearch() {
+		return ""
+	}
+	return fulltext.MatchQuery(*filters.Search)
+}
+
+func (tr *TaskRepository) scanTasks(rows *database.Rows) ([]*Task, error) {
 	var tasks []*Task
 	for rows.Next() {
 		task := &Task{}
 		err := rows.Scan(
 			&task.ID, &task.Title, &task.Description, &task.ColumnID,
 			&task.AssignedTo, &task.CreatedBy, &task.DueDate, &task.Priority,
-			&task.Position, &task.Weight, &task.CreatedAt, &task.UpdatedAt,
+			&task.Position, &task.Weight, &task.ParentID, &task.CreatedAt, &task.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
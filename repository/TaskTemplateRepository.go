@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+)
+
+type TaskTemplate struct {
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	TitlePattern   string    `json:"title_pattern"` // May contain {date}, {n} and custom {placeholders}
+	Description    *string   `json:"description"`
+	Priority       *string   `json:"priority"`
+	ColumnID       *int      `json:"column_id"` // Default column, NULL asks for one on use
+	AssignedTo     *int      `json:"assigned_to"`
+	ChecklistItems []string  `json:"checklist_items"`
+	LabelIDs       []int     `json:"label_ids"`
+	UseCount       int       `json:"use_count"`
+	CreatedBy      int       `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type TaskTemplateRepository struct {
+	db *database.Database
+}
+
+func NewTaskTemplateRepository(db *database.Database) *TaskTemplateRepository {
+	return &TaskTemplateRepository{
+		db: db,
+	}
+}
+
+const taskTemplateSelect = `
+	SELECT id, name, title_pattern, description, priority, column_id, assigned_to,
+	       checklist_items, label_ids, use_count, created_by, created_at, updated_at
+	FROM task_templates`
+
+// Find template by ID
+func (ttr *TaskTemplateRepository) FindByID(id int) (*TaskTemplate, error) {
+	template, err := ttr.scanTemplate(ttr.db.Instance().QueryRow(taskTemplateSelect+` WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("task template not found")
+		}
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// Get all templates ordered by name
+func (ttr *TaskTemplateRepository) GetAll() ([]*TaskTemplate, error) {
+	rows, err := ttr.db.Instance().Query(taskTemplateSelect + ` ORDER BY name COLLATE NOCASE ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := make([]*TaskTemplate, 0)
+	for rows.Next() {
+		template, err := ttr.scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+// Create new template
+func (ttr *TaskTemplateRepository) Create(name, titlePattern string, description, priority *string, columnID, assignedTo *int,
+	checklistItems []string, labelIDs []int, createdBy int) (*TaskTemplate, error) {
+
+	checklistJSON, labelsJSON, err := encodeTemplateLists(checklistItems, labelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO task_templates (name, title_pattern, description, priority, column_id, assigned_to,
+		                            checklist_items, label_ids, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+
+	result, err := ttr.db.Instance().Exec(query, name, titlePattern, description, priority, columnID, assignedTo,
+		checklistJSON, labelsJSON, createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return ttr.FindByID(int(id))
+}
+
+// Update template, nil values are left unchanged
+func (ttr *TaskTemplateRepository) Update(id int, name, titlePattern, description, priority *string, columnID, assignedTo *int,
+	checklistItems *[]string, labelIDs *[]int) (*TaskTemplate, error) {
+
+	existing, err := ttr.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if checklistItems == nil {
+		checklistItems = &existing.ChecklistItems
+	}
+	if labelIDs == nil {
+		labelIDs = &existing.LabelIDs
+	}
+
+	checklistJSON, labelsJSON, err := encodeTemplateLists(*checklistItems, *labelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE task_templates
+		SET name = COALESCE(?, name),
+		    title_pattern = COALESCE(?, title_pattern),
+		    description = COALESCE(?, description),
+		    priority = COALESCE(?, priority),
+		    column_id = COALESCE(?, column_id),
+		    assigned_to = COALESCE(?, assigned_to),
+		    checklist_items = ?,
+		    label_ids = ?,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	_, err = ttr.db.Instance().Exec(query, name, titlePattern, description, priority, columnID, assignedTo,
+		checklistJSON, labelsJSON, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return ttr.FindByID(id)
+}
+
+// Delete template
+func (ttr *TaskTemplateRepository) Delete(id int) error {
+	result, err := ttr.db.Instance().Exec(`DELETE FROM task_templates WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("task template not found")
+	}
+
+	return nil
+}
+
+// Count a use of the template, the count feeds the {n} placeholder
+func (ttr *TaskTemplateRepository) IncrementUseCount(id int) error {
+	_, err := ttr.db.Instance().Exec(`UPDATE task_templates SET use_count = use_count + 1 WHERE id = ?`, id)
+	return err
+}
+
+// Check if a template name is taken, ignoring the template being updated
+func (ttr *TaskTemplateRepository) NameExists(name string, excludeID *int) (bool, error) {
+	query := `SELECT COUNT(*) FROM task_templates WHERE name = ? COLLATE NOCASE`
+	args := []interface{}{name}
+
+	if excludeID != nil {
+		query += ` AND id != ?`
+		args = append(args, *excludeID)
+	}
+
+	var count int
+	err := ttr.db.Instance().QueryRow(query, args...).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Helper to scan a template from a row or rows
+func (ttr *TaskTemplateRepository) scanTemplate(row interface{ Scan(...interface{}) error }) (*TaskTemplate, error) {
+	template := &TaskTemplate{}
+	var checklistJSON, labelsJSON string
+
+	err := row.Scan(
+		&template.ID,
+		&template.Name,
+		&template.TitlePattern,
+		&template.Description,
+		&template.Priority,
+		&template.ColumnID,
+		&template.AssignedTo,
+		&checklistJSON,
+		&labelsJSON,
+		&template.UseCount,
+		&template.CreatedBy,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(checklistJSON), &template.ChecklistItems); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(labelsJSON), &template.LabelIDs); err != nil {
+		return nil, err
+	}
+	if template.ChecklistItems == nil {
+		template.ChecklistItems = make([]string, 0)
+	}
+	if template.LabelIDs == nil {
+		template.LabelIDs = make([]int, 0)
+	}
+
+	return template, nil
+}
+
+// encodeTemplateLists stores empty lists as [] rather than null
+func encodeTemplateLists(checklistItems []string, labelIDs []int) (string, string, error) {
+	labelIDs = uniqueIDs(labelIDs)
+	if checklistItems == nil {
+		checklistItems = make([]string, 0)
+	}
+	if labelIDs == nil {
+		labelIDs = make([]int, 0)
+	}
+
+	checklistJSON, err := json.Marshal(checklistItems)
+	if err != nil {
+		return "", "", err
+	}
+
+	labelsJSON, err := json.Marshal(labelIDs)
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(checklistJSON), string(labelsJSON), nil
+}
@@ -264,6 +264,28 @@ func (ur *UserRepository) GetAllUsers() ([]*User, error) {
 	return users, nil
 }
 
+// Get the IDs of all active root users
+func (ur *UserRepository) GetRootUserIDs() ([]int, error) {
+	query := `SELECT id FROM users WHERE is_root = 1 AND is_active = 1`
+
+	rows, err := ur.db.Instance().Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
 // Check if username exists
 func (ur *UserRepository) UsernameExists(username string) (bool, error) {
 	var count int
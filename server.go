@@ -18,13 +18,30 @@ func SetUpGorilaMuxServer(db *database.Database) http.Handler {
 
 	controller.SetupController(router, db).Router()
 	controller.AuthController(router, db).Router()
+	controller.BoardController(router, db).Router()
 	controller.ColumnsController(router, db).Router()
+	controller.LabelController(router, db).Router()
+	controller.TaskTemplateController(router, db).Router()
 	controller.UserController(router, db).Router()
 	controller.TaskController(router, db).Router()
+	controller.TaskDependencyController(router, db).Router()
+	controller.TaskSubtaskController(router, db).Router()
+	controller.TaskBulkController(router, db).Router()
+	controller.TaskRecurrenceController(router, db).Router()
+	controller.TaskArchiveController(router, db).Router()
+	controller.TaskTrashController(router, db).Router()
+	controller.TaskCsvController(router, db).Router()
+	controller.SavedFilterController(router, db).Router()
 	controller.SettingsController(router, db).Router()
+	controller.SnapshotController(router, db).Router()
+	controller.BackupController(router, db).Router()
+	controller.SyncController(router, db).Router()
+	controller.MutationController(router, db).Router()
 	controller.FileController(router, db).Router()
 	controller.CommentController(router, db).Router()
 	controller.ActivityController(router, db).Router()
+	controller.NotificationController(router, db).Router()
+	controller.EventController(router, db).Router()
 
 	// Example root API route
 	if isDev() {
@@ -42,7 +59,8 @@ func SetUpGorilaMuxServer(db *database.Database) http.Handler {
 	cors := handlers.CORS(
 		handlers.AllowedOrigins([]string{"*"}),
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"}),
-		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "X-Requested-With"}),
+		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "X-Requested-With", "Idempotency-Key"}),
+		handlers.ExposedHeaders([]string{"Idempotent-Replayed"}),
 	)
 
 	return cors(router)
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/repository"
+)
+
+// ArchiveService takes tasks off the board once they have sat in a done column for longer than the
+// column's auto-archive days, or the app setting for columns without their own
+type ArchiveService struct {
+	settingsRepository *repository.SettingsRepository
+	taskService        *TaskService
+}
+
+func NewArchiveService(db *database.Database) *ArchiveService {
+	return &ArchiveService{
+		settingsRepository: repository.NewSettingsRepository(db),
+		taskService:        NewTaskService(db),
+	}
+}
+
+// Start archives due tasks in the background, the first run covers the time the app was closed
+func (as *ArchiveService) Start(ctx context.Context, every time.Duration) {
+	runEvery(ctx, every, as.ArchiveDone)
+}
+
+// ArchiveDone archives the tasks due for it, columns with their own days are checked even when
+// the app setting is 0
+func (as *ArchiveService) ArchiveDone() {
+	settings, err := as.settingsRepository.GetSettings()
+	if err != nil {
+		fmt.Printf("Failed to load auto-archive days: %v\n", err)
+		return
+	}
+
+	archived, err := as.taskService.AutoArchive(settings.AutoArchiveDays)
+	if err != nil {
+		fmt.Printf("Failed to auto-archive done tasks: %v\n", err)
+		return
+	}
+
+	if archived > 0 {
+		fmt.Printf("Archived %d done tasks\n", archived)
+	}
+}
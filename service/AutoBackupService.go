@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/repository"
+)
+
+// Scheduled backups are named auto-<time>.zip, rotation never touches other archives
+const autoBackupPrefix = "auto"
+
+// Runs kept in the backup history
+const backupRunHistory = 100
+
+// AutoBackupStatus is what root users see of the scheduled backups
+type AutoBackupStatus struct {
+	Settings      *repository.BackupSettings `json:"settings"`
+	Directory     string                     `json:"directory"`
+	NextRunAt     *time.Time                 `json:"next_run_at"`
+	LastRun       *repository.BackupRun      `json:"last_run"`
+	LastSuccessAt *time.Time                 `json:"last_success_at"`
+	Runs          []*repository.BackupRun    `json:"runs"`
+	Backups       []*BackupInfo              `json:"backups"` // Scheduled backups currently kept
+}
+
+// AutoBackupService writes a backup archive every interval set in the app settings, verifies it
+// and rotates the older ones away, keeping the newest backup of each recent day and week
+type AutoBackupService struct {
+	db                  *database.Database
+	backupService       *BackupService
+	settingsRepository  *repository.SettingsRepository
+	backupRunRepository *repository.BackupRunRepository
+	notificationService *NotificationService
+}
+
+// Keeps a manual run and a scheduled one from overlapping, they come from different service instances
+var autoBackupRunning sync.Mutex
+
+func NewAutoBackupService(db *database.Database) *AutoBackupService {
+	return &AutoBackupService{
+		db:                  db,
+		backupService:       NewBackupService(db),
+		settingsRepository:  repository.NewSettingsRepository(db),
+		backupRunRepository: repository.NewBackupRunRepository(db),
+		notificationService: NewNotificationService(db),
+	}
+}
+
+// Start checks in the background whether a backup is due, the first check covers the time the app was closed
+func (abs *AutoBackupService) Start(ctx context.Context, every time.Duration) {
+	runEvery(ctx, every, abs.RunDue)
+}
+
+// RunDue takes a backup when scheduled backups are enabled and the interval has passed since the last run
+func (abs *AutoBackupService) RunDue() {
+	settings, err := abs.settingsRepository.GetBackupSettings()
+	if err != nil {
+		fmt.Printf("Failed to load backup settings: %v\n", err)
+		return
+	}
+	if !settings.Enabled {
+		return
+	}
+
+	nextRunAt, err := abs.nextRunAt(settings)
+	if err != nil {
+		fmt.Printf("Failed to load the last backup run: %v\n", err)
+		return
+	}
+	if nextRunAt.After(time.Now()) {
+		return
+	}
+
+	if _, err := abs.Run(); err != nil {
+		fmt.Printf("Failed to record the backup run: %v\n", err)
+	}
+}
+
+// Run takes a backup now, whatever the schedule, and records the outcome. Root users are
+// notified when it fails
+func (abs *AutoBackupService) Run() (*repository.BackupRun, error) {
+	autoBackupRunning.Lock()
+	defer autoBackupRunning.Unlock()
+
+	settings, err := abs.settingsRepository.GetBackupSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	run := &repository.BackupRun{Status: repository.BackupRunSuccess, Directory: abs.directory(settings), StartedAt: time.Now()}
+
+	backup, pruned, err := abs.backup(run.Directory, settings)
+	if backup != nil {
+		run.FileName = &backup.Name
+		run.Size = &backup.Size
+	}
+	run.Pruned = pruned
+	run.FinishedAt = time.Now()
+
+	if err != nil {
+		message := err.Error()
+		run.Status = repository.BackupRunFailed
+		run.Error = &message
+		fmt.Printf("Scheduled backup failed: %v\n", err)
+	} else {
+		fmt.Printf("Scheduled backup written to %s\n", filepath.Join(run.Directory, backup.Name))
+	}
+
+	recorded, err := abs.backupRunRepository.Create(run)
+	if err != nil {
+		return nil, err
+	}
+	if err := abs.backupRunRepository.DeleteOlderThanLatest(backupRunHistory); err != nil {
+		return nil, err
+	}
+
+	if recorded.Status == repository.BackupRunFailed {
+		abs.notificationService.NotifyBackupFailed(recorded.ID, *recorded.Error)
+	}
+
+	return recorded, nil
+}
+
+// backup writes and verifies one archive, then rotates the older scheduled backups. An archive
+// failing verification is removed, so only usable copies count towards the rotation
+func (abs *AutoBackupService) backup(dir string, settings *repository.BackupSettings) (*BackupInfo, int, error) {
+	backup, err := abs.backupService.CreateIn(dir, autoBackupPrefix)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	path := filepath.Join(dir, backup.Name)
+	if err := abs.backupService.Verify(path); err != nil {
+		os.Remove(path)
+		return backup, 0, fmt.Errorf("verifying %s: %w", backup.Name, err)
+	}
+
+	pruned, err := rotateBackups(dir, settings.KeepDaily, settings.KeepWeekly)
+	if err != nil {
+		return backup, pruned, fmt.Errorf("the backup was written but rotating older backups failed: %w", err)
+	}
+
+	return backup, pruned, nil
+}
+
+// Status returns the schedule, the recent runs and the scheduled backups on disk
+func (abs *AutoBackupService) Status() (*AutoBackupStatus, error) {
+	settings, err := abs.settingsRepository.GetBackupSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &AutoBackupStatus{Settings: settings, Directory: abs.directory(settings)}
+
+	if settings.Enabled {
+		nextRunAt, err := abs.nextRunAt(settings)
+		if err != nil {
+			return nil, err
+		}
+		status.NextRunAt = &nextRunAt
+	}
+
+	if status.Runs, err = abs.backupRunRepository.Latest(20); err != nil {
+		return nil, err
+	}
+	if len(status.Runs) > 0 {
+		status.LastRun = status.Runs[0]
+	}
+
+	lastSuccess, err := abs.backupRunRepository.LastSuccess()
+	if err != nil {
+		return nil, err
+	}
+	if lastSuccess != nil {
+		status.LastSuccessAt = &lastSuccess.FinishedAt
+	}
+
+	backups, err := listBackups(status.Directory)
+	if err != nil {
+		return nil, err
+	}
+	status.Backups = []*BackupInfo{}
+	for _, backup := range backups {
+		if _, ok := autoBackupTime(backup.Name); ok {
+			status.Backups = append(status.Backups, backup)
+		}
+	}
+
+	return status, nil
+}
+
+// CheckDirectory makes sure backups can be written to dir, creating it when missing
+func CheckDirectory(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(dir, ".write-test-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// nextRunAt is the interval after the last run, failed or not, or now when there was none
+func (abs *AutoBackupService) nextRunAt(settings *repository.BackupSettings) (time.Time, error) {
+	runs, err := abs.backupRunRepository.Latest(1)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(runs) == 0 {
+		return time.Now().UTC(), nil
+	}
+	return runs[0].StartedAt.Add(time.Duration(settings.IntervalHours) * time.Hour), nil
+}
+
+func (abs *AutoBackupService) directory(settings *repository.BackupSettings) string {
+	if settings.Directory != nil && *settings.Directory != "" {
+		return *settings.Directory
+	}
+	return abs.db.BackupDir()
+}
+
+// rotateBackups removes the scheduled backups in dir that are neither the newest of one of the
+// last keepDaily days nor the newest of one of the last keepWeekly weeks. The newest backup is always kept
+func rotateBackups(dir string, keepDaily, keepWeekly int) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	type scheduledBackup struct {
+		name      string
+		createdAt time.Time
+	}
+
+	var backups []scheduledBackup
+	for _, entry := range entries {
+		if createdAt, ok := autoBackupTime(entry.Name()); ok && entry.Type().IsRegular() {
+			backups = append(backups, scheduledBackup{name: entry.Name(), createdAt: createdAt})
+		}
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].createdAt.After(backups[j].createdAt)
+	})
+
+	days := map[string]bool{}
+	weeks := map[string]bool{}
+	pruned := 0
+	var errs []error
+
+	for i, backup := range backups {
+		day := backup.createdAt.Format("2006-01-02")
+		year, week := backup.createdAt.ISOWeek()
+		weekKey := fmt.Sprintf("%d-%02d", year, week)
+
+		keep := i == 0
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep = true
+		}
+		if !weeks[weekKey] && len(weeks) < keepWeekly {
+			weeks[weekKey] = true
+			keep = true
+		}
+		if keep {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, backup.name)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		pruned++
+	}
+
+	return pruned, errors.Join(errs...)
+}
+
+// autoBackupTime reads the time from a scheduled backup name, auto-20060102-150405[-n].zip
+func autoBackupTime(name string) (time.Time, bool) {
+	if !backupNamePattern.MatchString(name) || !strings.HasPrefix(name, autoBackupPrefix+"-") {
+		return time.Time{}, false
+	}
+
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, autoBackupPrefix+"-"), ".zip")
+	if len(stamp) < len("20060102-150405") {
+		return time.Time{}, false
+	}
+
+	createdAt, err := time.Parse("20060102-150405", stamp[:len("20060102-150405")])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return createdAt, true
+}
@@ -0,0 +1,462 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+)
+
+// BackupFormat is the version of the backup archive layout written by Create
+const BackupFormat = 1
+
+const (
+	backupManifestFile = "manifest.json"
+	backupDatabaseFile = "database.db"
+	uploadsDir         = "uploads"
+)
+
+// Backup names are generated by Create, anything else is rejected so a name can't leave the backup directory
+var backupNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*\.zip$`)
+
+// BackupInfo describes one backup archive in the backup directory
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BackupManifest is stored in every archive next to the database copy and the uploads
+type BackupManifest struct {
+	Format        int       `json:"format"`
+	CreatedAt     time.Time `json:"created_at"`
+	SchemaVersion int       `json:"schema_version"`
+	Uploads       int       `json:"uploads"`
+}
+
+// RestoreResult describes a completed restore, SafetyBackup holds the state from before it
+type RestoreResult struct {
+	Manifest     BackupManifest `json:"manifest"`
+	SafetyBackup *BackupInfo    `json:"safety_backup"`
+}
+
+var ErrBackupNotFound = errors.New("backup not found")
+
+// BackupError is a backup archive that can't be restored
+type BackupError struct {
+	Message string
+}
+
+func (e *BackupError) Error() string {
+	return e.Message
+}
+
+// BackupService writes archives holding a hot copy of the database and the uploaded files,
+// and restores them
+type BackupService struct {
+	db *database.Database
+}
+
+func NewBackupService(db *database.Database) *BackupService {
+	return &BackupService{
+		db: db,
+	}
+}
+
+// Create writes a new backup archive named after prefix and the current time
+func (bs *BackupService) Create(prefix string) (*BackupInfo, error) {
+	return bs.CreateIn(bs.db.BackupDir(), prefix)
+}
+
+// CreateIn writes a new backup archive to dir
+func (bs *BackupService) CreateIn(dir, prefix string) (*BackupInfo, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	name := prefix + "-" + now.Format("20060102-150405")
+	for n := 2; fileExists(filepath.Join(dir, name+".zip")); n++ {
+		name = fmt.Sprintf("%s-%s-%d", prefix, now.Format("20060102-150405"), n)
+	}
+	name += ".zip"
+
+	// VACUUM INTO gives a consistent copy without stopping writes
+	databaseCopy := filepath.Join(dir, "."+name+".db")
+	defer os.Remove(databaseCopy)
+	if err := bs.db.BackupTo(databaseCopy); err != nil {
+		return nil, err
+	}
+
+	schemaVersion, err := bs.db.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	// Written under a temporary name so a half written archive is never listed
+	partial := filepath.Join(dir, "."+name+".partial")
+	defer os.Remove(partial)
+	if err := writeBackupArchive(partial, databaseCopy, schemaVersion, now); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.Rename(partial, path); err != nil {
+		return nil, err
+	}
+
+	return backupInfo(path)
+}
+
+func writeBackupArchive(path, databaseCopy string, schemaVersion int, createdAt time.Time) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+
+	if err := addFileToArchive(archive, backupDatabaseFile, databaseCopy); err != nil {
+		return err
+	}
+
+	uploads := 0
+	err = filepath.WalkDir(uploadsDir, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == uploadsDir {
+			return filepath.SkipDir
+		}
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+
+		uploads++
+		return addFileToArchive(archive, filepath.ToSlash(path), path)
+	})
+	if err != nil {
+		return err
+	}
+
+	manifest, err := archive.Create(backupManifestFile)
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(manifest).Encode(BackupManifest{
+		Format:        BackupFormat,
+		CreatedAt:     createdAt,
+		SchemaVersion: schemaVersion,
+		Uploads:       uploads,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func addFileToArchive(archive *zip.Writer, name, path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	destination, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(destination, source)
+	return err
+}
+
+// List returns the backup archives, newest first
+func (bs *BackupService) List() ([]*BackupInfo, error) {
+	return listBackups(bs.db.BackupDir())
+}
+
+// Verify checks that the archive at path holds a manifest and an intact copy of the database
+func (bs *BackupService) Verify(path string) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	if _, err := readBackupManifest(&archive.Reader); err != nil {
+		return err
+	}
+
+	for _, entry := range archive.File {
+		if entry.Name != backupDatabaseFile {
+			continue
+		}
+
+		databaseCopy := filepath.Join(filepath.Dir(path), fmt.Sprintf(".verify-%d.db", time.Now().UnixNano()))
+		defer os.Remove(databaseCopy)
+		if err := extractArchiveFile(entry, databaseCopy); err != nil {
+			return err
+		}
+
+		_, err := database.CheckBackup(databaseCopy)
+		return err
+	}
+
+	return &BackupError{Message: "The archive has no " + backupDatabaseFile}
+}
+
+func listBackups(dir string) ([]*BackupInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []*BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []*BackupInfo{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !backupNamePattern.MatchString(entry.Name()) {
+			continue
+		}
+
+		info, err := backupInfo(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, info)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// Open opens a backup archive for download
+func (bs *BackupService) Open(name string) (*os.File, *BackupInfo, error) {
+	path, err := bs.backupPath(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := backupInfo(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, info, nil
+}
+
+// Restore replaces the database and the uploads with the archive read from r
+func (bs *BackupService) Restore(r io.Reader) (*RestoreResult, error) {
+	dir := bs.db.BackupDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	upload, err := os.CreateTemp(dir, ".upload-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(upload.Name())
+	defer upload.Close()
+
+	if _, err := io.Copy(upload, r); err != nil {
+		return nil, err
+	}
+	if err := upload.Close(); err != nil {
+		return nil, err
+	}
+
+	return bs.restoreArchive(upload.Name())
+}
+
+// RestoreBackup replaces the database and the uploads with a backup from the backup directory
+func (bs *BackupService) RestoreBackup(name string) (*RestoreResult, error) {
+	path, err := bs.backupPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return bs.restoreArchive(path)
+}
+
+// restoreArchive validates the whole archive before anything is replaced, then takes a safety
+// backup of the current state and swaps in the database and the uploads
+func (bs *BackupService) restoreArchive(path string) (*RestoreResult, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, &BackupError{Message: "The file is not a backup archive: " + err.Error()}
+	}
+	defer archive.Close()
+
+	manifest, err := readBackupManifest(&archive.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var databaseEntry *zip.File
+	for _, entry := range archive.File {
+		if entry.Name == backupDatabaseFile {
+			databaseEntry = entry
+			continue
+		}
+		if entry.Name == backupManifestFile || entry.FileInfo().IsDir() {
+			continue
+		}
+		// Checked on the part below uploads/, "uploads/../.env" is local as a whole
+		relative, ok := strings.CutPrefix(entry.Name, uploadsDir+"/")
+		if !ok || !filepath.IsLocal(filepath.FromSlash(relative)) {
+			return nil, &BackupError{Message: fmt.Sprintf("The archive holds an unexpected file %q", entry.Name)}
+		}
+	}
+	if databaseEntry == nil {
+		return nil, &BackupError{Message: "The archive has no " + backupDatabaseFile}
+	}
+
+	databaseCopy := filepath.Join(bs.db.BackupDir(), fmt.Sprintf(".restore-%d.db", time.Now().UnixNano()))
+	defer os.Remove(databaseCopy)
+	if err := extractArchiveFile(databaseEntry, databaseCopy); err != nil {
+		return nil, err
+	}
+
+	if _, err := database.CheckBackup(databaseCopy); err != nil {
+		if errors.Is(err, database.ErrInvalidBackup) || errors.Is(err, database.ErrSchemaTooNew) {
+			return nil, &BackupError{Message: err.Error()}
+		}
+		return nil, err
+	}
+
+	// Uploads are unpacked next to the live directory and swapped in once the database is restored
+	restoredUploads := fmt.Sprintf("%s.restore-%d", uploadsDir, time.Now().UnixNano())
+	defer os.RemoveAll(restoredUploads)
+	if err := os.MkdirAll(restoredUploads, 0755); err != nil {
+		return nil, err
+	}
+	for _, entry := range archive.File {
+		if !strings.HasPrefix(entry.Name, uploadsDir+"/") || entry.FileInfo().IsDir() {
+			continue
+		}
+
+		relative := filepath.FromSlash(strings.TrimPrefix(entry.Name, uploadsDir+"/"))
+		destination := filepath.Join(restoredUploads, relative)
+		if !filepath.IsLocal(relative) || !strings.HasPrefix(destination, filepath.Clean(restoredUploads)+string(filepath.Separator)) {
+			return nil, &BackupError{Message: fmt.Sprintf("The archive holds an unexpected file %q", entry.Name)}
+		}
+		if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+			return nil, err
+		}
+		if err := extractArchiveFile(entry, destination); err != nil {
+			return nil, err
+		}
+	}
+
+	safetyBackup, err := bs.Create("pre-restore")
+	if err != nil {
+		return nil, fmt.Errorf("backup before restoring: %w", err)
+	}
+
+	if err := bs.db.Restore(databaseCopy); err != nil {
+		return nil, err
+	}
+
+	if err := replaceUploads(restoredUploads); err != nil {
+		return nil, fmt.Errorf("the database was restored but the uploads were not: %w", err)
+	}
+
+	return &RestoreResult{Manifest: *manifest, SafetyBackup: safetyBackup}, nil
+}
+
+func readBackupManifest(archive *zip.Reader) (*BackupManifest, error) {
+	file, err := archive.Open(backupManifestFile)
+	if err != nil {
+		return nil, &BackupError{Message: "The archive has no " + backupManifestFile}
+	}
+	defer file.Close()
+
+	manifest := &BackupManifest{}
+	if err := json.NewDecoder(file).Decode(manifest); err != nil {
+		return nil, &BackupError{Message: "Invalid " + backupManifestFile + ": " + err.Error()}
+	}
+	if manifest.Format != BackupFormat {
+		return nil, &BackupError{Message: fmt.Sprintf("Unsupported backup format %d, expected %d", manifest.Format, BackupFormat)}
+	}
+
+	return manifest, nil
+}
+
+func extractArchiveFile(entry *zip.File, path string) error {
+	source, err := entry.Open()
+	if err != nil {
+		return &BackupError{Message: fmt.Sprintf("Can't read %s from the archive: %v", entry.Name, err)}
+	}
+	defer source.Close()
+
+	destination, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer destination.Close()
+
+	if _, err := io.Copy(destination, source); err != nil {
+		return &BackupError{Message: fmt.Sprintf("Can't read %s from the archive: %v", entry.Name, err)}
+	}
+	return destination.Close()
+}
+
+// replaceUploads swaps the uploads directory for restored, the files it replaces are removed
+func replaceUploads(restored string) error {
+	previous := fmt.Sprintf("%s.old-%d", uploadsDir, time.Now().UnixNano())
+	if err := os.Rename(uploadsDir, previous); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := os.Rename(restored, uploadsDir); err != nil {
+		os.Rename(previous, uploadsDir)
+		return err
+	}
+
+	return os.RemoveAll(previous)
+}
+
+func (bs *BackupService) backupPath(name string) (string, error) {
+	if !backupNamePattern.MatchString(name) {
+		return "", ErrBackupNotFound
+	}
+
+	path := filepath.Join(bs.db.BackupDir(), name)
+	if !fileExists(path) {
+		return "", ErrBackupNotFound
+	}
+	return path, nil
+}
+
+func backupInfo(path string) (*BackupInfo, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &BackupInfo{Name: filepath.Base(path), Size: stat.Size(), CreatedAt: stat.ModTime().UTC()}, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
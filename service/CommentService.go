@@ -4,18 +4,27 @@ import (
 	"fmt"
 
 	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/events"
 	"github.com/dev-parvej/offline_kanban/repository"
 )
 
 type CommentService struct {
-	commentRepository  *repository.CommentRepository
-	activityRepository *repository.ActivityRepository
+	commentRepository   *repository.CommentRepository
+	activityRepository  *repository.ActivityRepository
+	taskRepository      *repository.TaskRepository
+	notificationService *NotificationService
+	mentionService      *MentionService
+	db                  *database.Database
 }
 
 func NewCommentService(db *database.Database) *CommentService {
 	return &CommentService{
-		commentRepository:  repository.NewCommentRepository(db),
-		activityRepository: repository.NewActivityRepository(db),
+		commentRepository:   repository.NewCommentRepository(db),
+		activityRepository:  repository.NewActivityRepository(db),
+		taskRepository:      repository.NewTaskRepository(db),
+		notificationService: NewNotificationService(db),
+		mentionService:      NewMentionService(db),
+		db:                  db,
 	}
 }
 
@@ -33,15 +42,88 @@ func (cs *CommentService) CreateComment(content string, taskID, userID int) (*re
 		fmt.Printf("Failed to record comment creation activity: %v\n", err)
 	}
 
+	// Notify the people involved with the task and anyone mentioned
+	if task, err := cs.taskRepository.FindByID(taskID); err == nil {
+		cs.notificationService.NotifyTaskComment(task, comment.ID, userID)
+		cs.mentionService.ProcessComment(task, comment.ID, content, userID)
+	}
+
+	cs.publish(events.CommentCreated, taskID, userID, comment)
+
 	return comment, nil
 }
 
-// UpdateComment updates a comment (no activity tracking needed for content changes)
+// UpdateComment updates a comment and its mentions (no activity tracking needed for content changes)
 func (cs *CommentService) UpdateComment(commentID int, content string, userID int) (*repository.Comment, error) {
-	return cs.commentRepository.Update(commentID, content, userID)
+	existingComment, err := cs.commentRepository.FindByID(commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var comment *repository.Comment
+	err = cs.db.Transaction(func(tx *database.Database) error {
+		comment, err = repository.NewCommentRepository(tx).Update(commentID, content, userID)
+		if err != nil {
+			return err
+		}
+
+		// Mentions are only processed for tasks still on the board, as for new comments
+		task, err := repository.NewTaskRepository(tx).FindByID(comment.TaskID)
+		if err != nil {
+			return nil
+		}
+
+		return NewMentionService(tx).ProcessCommentEdit(task, commentID, existingComment.Content, content, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cs.publish(events.CommentUpdated, comment.TaskID, userID, comment)
+
+	return comment, nil
 }
 
 // DeleteComment deletes a comment (no activity tracking needed)
 func (cs *CommentService) DeleteComment(commentID, userID int) error {
-	return cs.commentRepository.Delete(commentID, userID)
-}
\ No newline at end of file
+	comment, err := cs.commentRepository.FindByID(commentID)
+	if err != nil {
+		return err
+	}
+
+	err = cs.db.Transaction(func(tx *database.Database) error {
+		if err := repository.NewCommentRepository(tx).Delete(commentID, userID); err != nil {
+			return err
+		}
+
+		// Foreign keys are off, the comment's mentions would keep the task in mentioned_me
+		return repository.NewMentionRepository(tx).DeleteByComment(commentID)
+	})
+	if err != nil {
+		return err
+	}
+
+	cs.publish(events.CommentDeleted, comment.TaskID, userID, map[string]int{
+		"id": commentID,
+	})
+
+	return nil
+}
+
+// publish pushes a comment event to the board of the commented task
+func (cs *CommentService) publish(eventType string, taskID, userID int, data interface{}) {
+	event := events.Event{
+		Type:   eventType,
+		TaskID: &taskID,
+		UserID: userID,
+		Data:   data,
+	}
+
+	if boardID, err := cs.taskRepository.GetBoardID(taskID); err == nil {
+		event.BoardID = &boardID
+	}
+
+	cs.db.AfterCommit(func() {
+		events.Publish(event)
+	})
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/repository"
+)
+
+// Mention sources
+const (
+	MentionSourceDescription = "description"
+	MentionSourceComment     = "comment"
+)
+
+// An @ only starts a mention at the beginning of the text or after a character that
+// can't be part of a username, so e-mail addresses are not picked up
+var mentionPattern = regexp.MustCompile(`(?:^|[^a-zA-Z0-9._-])@([a-zA-Z0-9._-]+)`)
+
+// ExtractMentions returns the distinct usernames mentioned in the text, in order of appearance
+func ExtractMentions(text string) []string {
+	var usernames []string
+	seen := map[string]bool{}
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		// A sentence ending right after the name is not part of it
+		username := strings.TrimRight(match[1], ".-")
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+
+	return usernames
+}
+
+type MentionService struct {
+	mentionRepository   *repository.MentionRepository
+	userRepository      *repository.UserRepository
+	activityRepository  *repository.ActivityRepository
+	notificationService *NotificationService
+}
+
+func NewMentionService(db *database.Database) *MentionService {
+	return &MentionService{
+		mentionRepository:   repository.NewMentionRepository(db),
+		userRepository:      repository.NewUserRepository(db),
+		activityRepository:  repository.NewActivityRepository(db),
+		notificationService: NewNotificationService(db),
+	}
+}
+
+// ProcessDescription records the mentions added to a task description; users that
+// were already mentioned in the previous description are not recorded again
+func (ms *MentionService) ProcessDescription(task *repository.Task, oldDescription *string, userID int) {
+	if task.Description == nil {
+		return
+	}
+
+	previous := map[string]bool{}
+	if oldDescription != nil {
+		for _, username := range ExtractMentions(*oldDescription) {
+			previous[username] = true
+		}
+	}
+
+	var added []string
+	for _, username := range ExtractMentions(*task.Description) {
+		if !previous[username] {
+			added = append(added, username)
+		}
+	}
+
+	ms.record(task, nil, MentionSourceDescription, added, userID)
+}
+
+// ProcessComment records the mentions in a new comment
+func (ms *MentionService) ProcessComment(task *repository.Task, commentID int, content string, userID int) {
+	ms.record(task, &commentID, MentionSourceComment, ExtractMentions(content), userID)
+}
+
+// ProcessCommentEdit replaces the mentions of an edited comment with the ones in its new content,
+// only users the edit adds get an activity and a notification
+func (ms *MentionService) ProcessCommentEdit(task *repository.Task, commentID int, oldContent, content string, userID int) error {
+	if err := ms.mentionRepository.DeleteByComment(commentID); err != nil {
+		return err
+	}
+
+	previous := map[string]bool{}
+	for _, username := range ExtractMentions(oldContent) {
+		previous[username] = true
+	}
+
+	var added []string
+	for _, username := range ExtractMentions(content) {
+		if !previous[username] {
+			added = append(added, username)
+			continue
+		}
+
+		user, err := ms.userRepository.FindByUsername(username)
+		if err != nil {
+			continue
+		}
+		if _, err := ms.mentionRepository.Create(task.ID, &commentID, user.ID, userID, MentionSourceComment); err != nil {
+			return err
+		}
+	}
+
+	ms.record(task, &commentID, MentionSourceComment, added, userID)
+	return nil
+}
+
+// record resolves the usernames and stores a mention, an activity and a notification for each user found
+func (ms *MentionService) record(task *repository.Task, commentID *int, source string, usernames []string, userID int) {
+	for _, username := range usernames {
+		user, err := ms.userRepository.FindByUsername(username)
+		if err != nil {
+			// Not a user, just text that looks like a mention
+			continue
+		}
+
+		_, err = ms.mentionRepository.Create(task.ID, commentID, user.ID, userID, source)
+		if err != nil {
+			fmt.Printf("Failed to record mention of %s: %v\n", username, err)
+			continue
+		}
+
+		err = ms.activityRepository.RecordMention(task.ID, userID, source, user.UserName)
+		if err != nil {
+			fmt.Printf("Failed to record mention activity: %v\n", err)
+		}
+
+		ms.notificationService.NotifyMention(task, user.ID, commentID, userID)
+	}
+}
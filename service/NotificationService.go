@@ -0,0 +1,248 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/events"
+	"github.com/dev-parvej/offline_kanban/repository"
+)
+
+// Notification types
+const (
+	NotificationTaskAssigned   = "task_assigned"
+	NotificationTaskUnassigned = "task_unassigned"
+	NotificationTaskCommented  = "task_commented"
+	NotificationTaskMoved      = "task_moved"
+
+	NotificationMentionedInTask    = "mentioned_in_task"
+	NotificationMentionedInComment = "mentioned_in_comment"
+
+	NotificationBackupFailed = "backup_failed"
+)
+
+// NotificationService decides who hears about a change and stores their notifications.
+// Root users receive every notification, normal users only the ones about tasks they
+// created or are assigned to. Nobody is notified about their own actions.
+type NotificationService struct {
+	notificationRepository *repository.NotificationRepository
+	userRepository         *repository.UserRepository
+	settingsRepository     *repository.SettingsRepository
+	db                     *database.Database
+}
+
+func NewNotificationService(db *database.Database) *NotificationService {
+	return &NotificationService{
+		notificationRepository: repository.NewNotificationRepository(db),
+		userRepository:         repository.NewUserRepository(db),
+		settingsRepository:     repository.NewSettingsRepository(db),
+		db:                     db,
+	}
+}
+
+// NotifyTaskAssignment tells the new and the previous assignee about an assignment change
+func (ns *NotificationService) NotifyTaskAssignment(task *repository.Task, oldAssigneeID *int, actorID int) {
+	oldID := idOrZero(oldAssigneeID)
+	newID := idOrZero(task.AssignedTo)
+	if oldID == newID {
+		return
+	}
+
+	actor := ns.displayName(actorID)
+
+	if newID > 0 {
+		ns.notify(ns.recipients(actorID, newID), actorID, NotificationTaskAssigned,
+			"Task assigned",
+			func(recipientID int) string {
+				if recipientID == newID {
+					return fmt.Sprintf("%s assigned you to '%s'", actor, task.Title)
+				}
+				return fmt.Sprintf("%s assigned %s to '%s'", actor, ns.displayName(newID), task.Title)
+			},
+			&task.ID, nil, map[string]interface{}{"assigned_to": newID})
+	}
+
+	if oldID > 0 {
+		ns.notify(ns.recipients(actorID, oldID), actorID, NotificationTaskUnassigned,
+			"Task unassigned",
+			func(recipientID int) string {
+				if recipientID == oldID {
+					return fmt.Sprintf("%s removed you from '%s'", actor, task.Title)
+				}
+				return fmt.Sprintf("%s removed %s from '%s'", actor, ns.displayName(oldID), task.Title)
+			},
+			&task.ID, nil, map[string]interface{}{"unassigned": oldID})
+	}
+}
+
+// NotifyTaskComment tells the task's creator and assignee about a new comment
+func (ns *NotificationService) NotifyTaskComment(task *repository.Task, commentID, actorID int) {
+	actor := ns.displayName(actorID)
+
+	ns.notify(ns.participants(task, actorID), actorID, NotificationTaskCommented,
+		"New comment",
+		func(int) string {
+			return fmt.Sprintf("%s commented on '%s'", actor, task.Title)
+		},
+		&task.ID, &commentID, nil)
+}
+
+// NotifyTaskMoved tells the task's creator and assignee that it changed column
+func (ns *NotificationService) NotifyTaskMoved(task *repository.Task, fromColumn, toColumn string, actorID int) {
+	actor := ns.displayName(actorID)
+
+	ns.notify(ns.participants(task, actorID), actorID, NotificationTaskMoved,
+		"Task moved",
+		func(int) string {
+			return fmt.Sprintf("%s moved '%s' from %s to %s", actor, task.Title, fromColumn, toColumn)
+		},
+		&task.ID, nil, map[string]interface{}{"from_column": fromColumn, "to_column": toColumn})
+}
+
+// NotifyMention tells a user they were mentioned in a task description or comment
+func (ns *NotificationService) NotifyMention(task *repository.Task, mentionedUserID int, commentID *int, actorID int) {
+	actor := ns.displayName(actorID)
+	mentioned := ns.displayName(mentionedUserID)
+
+	notificationType := NotificationMentionedInTask
+	where := "the description of"
+	if commentID != nil {
+		notificationType = NotificationMentionedInComment
+		where = "a comment on"
+	}
+
+	ns.notify(ns.recipients(actorID, mentionedUserID), actorID, notificationType,
+		"New mention",
+		func(recipientID int) string {
+			if recipientID == mentionedUserID {
+				return fmt.Sprintf("%s mentioned you in %s '%s'", actor, where, task.Title)
+			}
+			return fmt.Sprintf("%s mentioned %s in %s '%s'", actor, mentioned, where, task.Title)
+		},
+		&task.ID, commentID, map[string]interface{}{"mentioned_user_id": mentionedUserID})
+}
+
+// NotifyBackupFailed tells every root user that a scheduled backup failed
+func (ns *NotificationService) NotifyBackupFailed(runID int, reason string) {
+	if !ns.enabled() {
+		return
+	}
+
+	rootIDs, err := ns.userRepository.GetRootUserIDs()
+	if err != nil {
+		fmt.Printf("Failed to load root users for notifications: %v\n", err)
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{"backup_run_id": runID})
+	dataJSON := string(data)
+
+	for _, recipientID := range rootIDs {
+		notification, err := ns.notificationRepository.Create(recipientID, nil, NotificationBackupFailed,
+			"Scheduled backup failed", reason, nil, nil, &dataJSON, true)
+		if err != nil {
+			fmt.Printf("Failed to create %s notification for user %d: %v\n", NotificationBackupFailed, recipientID, err)
+			continue
+		}
+
+		recipient := recipientID
+		ns.db.AfterCommit(func() {
+			events.Publish(events.Event{
+				Type:        events.NotificationCreated,
+				RecipientID: &recipient,
+				Data:        notification,
+			})
+		})
+	}
+}
+
+// notify stores one notification per recipient and pushes it to their open streams
+func (ns *NotificationService) notify(recipients []int, senderID int, notificationType, title string,
+	message func(recipientID int) string, taskID, commentID *int, data map[string]interface{}) {
+
+	if len(recipients) == 0 || !ns.enabled() {
+		return
+	}
+
+	var dataJSON *string
+	if data != nil {
+		if encoded, err := json.Marshal(data); err == nil {
+			value := string(encoded)
+			dataJSON = &value
+		}
+	}
+
+	for _, recipientID := range recipients {
+		notification, err := ns.notificationRepository.Create(recipientID, &senderID, notificationType, title,
+			message(recipientID), taskID, commentID, dataJSON, false)
+		if err != nil {
+			fmt.Printf("Failed to create %s notification for user %d: %v\n", notificationType, recipientID, err)
+			continue
+		}
+
+		recipient := recipientID
+		ns.db.AfterCommit(func() {
+			events.Publish(events.Event{
+				Type:        events.NotificationCreated,
+				TaskID:      taskID,
+				UserID:      senderID,
+				RecipientID: &recipient,
+				Data:        notification,
+			})
+		})
+	}
+}
+
+// participants returns the task's creator and assignee plus every root user, without the actor
+func (ns *NotificationService) participants(task *repository.Task, actorID int) []int {
+	return ns.recipients(actorID, task.CreatedBy, idOrZero(task.AssignedTo))
+}
+
+// recipients adds the root users to the given users and drops the actor and duplicates
+func (ns *NotificationService) recipients(actorID int, userIDs ...int) []int {
+	rootIDs, err := ns.userRepository.GetRootUserIDs()
+	if err != nil {
+		fmt.Printf("Failed to load root users for notifications: %v\n", err)
+	}
+
+	seen := map[int]bool{actorID: true}
+	var recipients []int
+	for _, id := range append(userIDs, rootIDs...) {
+		if id <= 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		recipients = append(recipients, id)
+	}
+
+	return recipients
+}
+
+// enabled reports whether notifications are switched on in the app settings
+func (ns *NotificationService) enabled() bool {
+	settings, err := ns.settingsRepository.GetSettings()
+	if err != nil {
+		return true
+	}
+	return settings.EnableNotifications
+}
+
+// displayName returns the user's name, falling back to the username
+func (ns *NotificationService) displayName(userID int) string {
+	user, err := ns.userRepository.FindByID(userID)
+	if err != nil {
+		return "Someone"
+	}
+	if user.Name != nil && *user.Name != "" {
+		return *user.Name
+	}
+	return user.UserName
+}
+
+func idOrZero(id *int) int {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
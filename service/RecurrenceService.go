@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/recurrence"
+	"github.com/dev-parvej/offline_kanban/repository"
+)
+
+// A rule never generates more than this many missed instances in one run
+const maxCatchUpInstances = 31
+
+// RecurrenceService keeps recurrence rules and clones their template tasks when they are due.
+// Runs missed while the app was closed are made up on the next run, either one instance per
+// missed occurrence or only the latest one, depending on the rule's catch_up flag.
+type RecurrenceService struct {
+	recurrenceRepository *repository.TaskRecurrenceRepository
+	taskRepository       *repository.TaskRepository
+	activityRepository   *repository.ActivityRepository
+	taskService          *TaskService
+}
+
+func NewRecurrenceService(db *database.Database) *RecurrenceService {
+	return &RecurrenceService{
+		recurrenceRepository: repository.NewTaskRecurrenceRepository(db),
+		taskRepository:       repository.NewTaskRepository(db),
+		activityRepository:   repository.NewActivityRepository(db),
+		taskService:          NewTaskService(db),
+	}
+}
+
+// SetRecurrence creates or replaces the rule of a template task and schedules its first run
+func (rs *RecurrenceService) SetRecurrence(taskID, columnID int, frequency string, interval int, cronExpr *string,
+	catchUp bool, startsAt *time.Time, userID int) (*repository.TaskRecurrence, error) {
+
+	now := time.Now()
+	start := now
+	if startsAt != nil {
+		start = *startsAt
+	}
+
+	expr := ""
+	if cronExpr != nil {
+		expr = *cronExpr
+	}
+
+	rule, err := recurrence.New(frequency, interval, expr, start)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first run is the first occurrence from now on, earlier ones are not made up
+	after := now
+	if start.After(now) {
+		after = start.Add(-time.Nanosecond)
+	}
+	nextRunAt := optionalTime(rule.Next(after))
+
+	if frequency != recurrence.Cron {
+		cronExpr = nil
+	}
+
+	return rs.recurrenceRepository.Save(taskID, columnID, frequency, rule.Interval, cronExpr, catchUp,
+		nextRunAt != nil, start, nextRunAt, userID)
+}
+
+// Start runs due rules in the background, the first run catches up on anything missed while offline
+func (rs *RecurrenceService) Start(ctx context.Context, every time.Duration) {
+	runEvery(ctx, every, func() {
+		rs.RunDue(time.Now())
+	})
+}
+
+// RunDue generates the instances of every rule whose next run is not after now
+func (rs *RecurrenceService) RunDue(now time.Time) {
+	recurrences, err := rs.recurrenceRepository.GetActive()
+	if err != nil {
+		fmt.Printf("Failed to load task recurrences: %v\n", err)
+		return
+	}
+
+	for _, taskRecurrence := range recurrences {
+		if taskRecurrence.NextRunAt == nil || taskRecurrence.NextRunAt.After(now) {
+			continue
+		}
+		rs.run(taskRecurrence, now)
+	}
+}
+
+// run generates the missed instances of one rule and moves it to its next occurrence
+func (rs *RecurrenceService) run(taskRecurrence *repository.TaskRecurrence, now time.Time) {
+	template, err := rs.taskRepository.FindByID(taskRecurrence.TaskID)
+	if err != nil {
+		// The template is gone, nothing left to repeat
+		rs.markRun(taskRecurrence, now, time.Time{})
+		return
+	}
+
+	expr := ""
+	if taskRecurrence.CronExpr != nil {
+		expr = *taskRecurrence.CronExpr
+	}
+
+	rule, err := recurrence.New(taskRecurrence.Frequency, taskRecurrence.Interval, expr, taskRecurrence.StartsAt)
+	if err != nil {
+		fmt.Printf("Invalid recurrence rule %d: %v\n", taskRecurrence.ID, err)
+		rs.markRun(taskRecurrence, now, time.Time{})
+		return
+	}
+
+	var occurrences []time.Time
+	occurrence := *taskRecurrence.NextRunAt
+	for !occurrence.IsZero() && !occurrence.After(now) {
+		occurrences = append(occurrences, occurrence)
+		occurrence = rule.Next(occurrence)
+	}
+
+	if !taskRecurrence.CatchUp {
+		occurrences = occurrences[len(occurrences)-1:]
+	} else if len(occurrences) > maxCatchUpInstances {
+		occurrences = occurrences[len(occurrences)-maxCatchUpInstances:]
+	}
+
+	for _, occurredAt := range occurrences {
+		if err := rs.generate(template, taskRecurrence, occurredAt); err != nil {
+			fmt.Printf("Failed to generate instance of recurring task %d: %v\n", template.ID, err)
+		}
+	}
+
+	rs.markRun(taskRecurrence, now, occurrence)
+}
+
+// generate clones the template into the rule's column for one occurrence
+func (rs *RecurrenceService) generate(template *repository.Task, taskRecurrence *repository.TaskRecurrence, occurredAt time.Time) error {
+	description := ""
+	if template.Description != nil {
+		description = *template.Description
+	}
+
+	priority := ""
+	if template.Priority != nil {
+		priority = *template.Priority
+	}
+
+	// Keep the template's lead time between creation and due date
+	var dueDate *time.Time
+	if template.DueDate != nil && template.DueDate.After(template.CreatedAt) {
+		due := occurredAt.Add(template.DueDate.Sub(template.CreatedAt))
+		dueDate = &due
+	}
+
+	// Scheduled chores are created even when the column is at its WIP limit
+	instance, err := rs.taskService.CreateTask(template.Title, description, taskRecurrence.ColumnID,
+		taskRecurrence.CreatedBy, template.AssignedTo, dueDate, priority, true)
+	if err != nil {
+		return err
+	}
+
+	if err := rs.taskRepository.LoadLabels(template); err == nil && len(template.Labels) > 0 {
+		labelIDs := make([]int, len(template.Labels))
+		for i, label := range template.Labels {
+			labelIDs[i] = label.ID
+		}
+		if _, err := rs.taskService.SetTaskLabels(instance.ID, labelIDs, taskRecurrence.CreatedBy); err != nil {
+			fmt.Printf("Failed to copy labels to recurring task instance %d: %v\n", instance.ID, err)
+		}
+	}
+
+	occurrence := occurredAt.In(time.Local).Format("2006-01-02 15:04")
+
+	err = rs.activityRepository.RecordTaskRecurred(template.ID, taskRecurrence.CreatedBy,
+		fmt.Sprintf("%s (%s)", taskName(instance), occurrence))
+	if err != nil {
+		fmt.Printf("Failed to record task recurred activity: %v\n", err)
+	}
+
+	err = rs.activityRepository.RecordTaskGenerated(instance.ID, taskRecurrence.CreatedBy,
+		fmt.Sprintf("%s (%s)", taskName(template), occurrence))
+	if err != nil {
+		fmt.Printf("Failed to record task generated activity: %v\n", err)
+	}
+
+	return nil
+}
+
+// markRun stores the run, a zero next time deactivates the rule
+func (rs *RecurrenceService) markRun(taskRecurrence *repository.TaskRecurrence, now, next time.Time) {
+	err := rs.recurrenceRepository.MarkRun(taskRecurrence.ID, now, optionalTime(next))
+	if err != nil {
+		fmt.Printf("Failed to update task recurrence %d: %v\n", taskRecurrence.ID, err)
+	}
+}
+
+// runEvery calls fn in a goroutine right away and then on every tick until the context is done,
+// the schedulers of the app share it
+func runEvery(ctx context.Context, every time.Duration, fn func()) {
+	go func() {
+		fn()
+
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+}
+
+// optionalTime turns the zero time into nil
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,556 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/repository"
+)
+
+// SnapshotVersion is the version of the snapshot document written by Export, Import only reads this version
+const SnapshotVersion = 1
+
+const (
+	ImportModeRestore = "restore" // Into an empty database, every row keeps its ID
+	ImportModeMerge   = "merge"   // Next to the existing data, rows get new IDs
+)
+
+// Snapshot is the whole board data as one versioned document
+type Snapshot struct {
+	Version      int                              `json:"version"`
+	ExportedAt   time.Time                        `json:"exported_at"`
+	Settings     *repository.AppSettings          `json:"settings"`
+	Users        []*repository.SnapshotUser       `json:"users"`
+	Boards       []*repository.SnapshotBoard      `json:"boards"`
+	Columns      []*repository.SnapshotColumn     `json:"columns"`
+	Labels       []*repository.SnapshotLabel      `json:"labels"`
+	Tasks        []*repository.SnapshotTask       `json:"tasks"`
+	Dependencies []*repository.SnapshotDependency `json:"dependencies"`
+	Checklists   []*repository.SnapshotChecklist  `json:"checklists"`
+	Comments     []*repository.SnapshotComment    `json:"comments"`
+	Activities   []*repository.SnapshotActivity   `json:"activities"`
+}
+
+// ImportResult counts the rows an import created, users and labels that already existed are counted as matched
+type ImportResult struct {
+	Mode          string `json:"mode"`
+	Users         int    `json:"users"`
+	MatchedUsers  int    `json:"matched_users"`
+	Boards        int    `json:"boards"`
+	Columns       int    `json:"columns"`
+	Labels        int    `json:"labels"`
+	MatchedLabels int    `json:"matched_labels"`
+	Tasks         int    `json:"tasks"`
+	Dependencies  int    `json:"dependencies"`
+	Checklists    int    `json:"checklists"`
+	Comments      int    `json:"comments"`
+	Activities    int    `json:"activities"`
+}
+
+var ErrDatabaseNotEmpty = errors.New("the database already has board data, restore only works on an empty database, use merge instead")
+
+// SnapshotError is a snapshot document that can't be imported
+type SnapshotError struct {
+	Message string
+}
+
+func (e *SnapshotError) Error() string {
+	return e.Message
+}
+
+// SnapshotService exports the board data as a snapshot and imports snapshots back
+type SnapshotService struct {
+	db *database.Database
+}
+
+func NewSnapshotService(db *database.Database) *SnapshotService {
+	return &SnapshotService{
+		db: db,
+	}
+}
+
+// Export reads everything into a snapshot inside one transaction, so the tables are consistent with each other
+func (ss *SnapshotService) Export(includePasswords bool) (*Snapshot, error) {
+	snapshot := &Snapshot{Version: SnapshotVersion, ExportedAt: time.Now().UTC()}
+
+	err := ss.db.Transaction(func(tx *database.Database) error {
+		snapshotRepository := repository.NewSnapshotRepository(tx)
+
+		var err error
+		if snapshot.Settings, err = repository.NewSettingsRepository(tx).GetSettings(); err != nil {
+			return err
+		}
+		if snapshot.Users, err = snapshotRepository.GetUsers(includePasswords); err != nil {
+			return err
+		}
+		if snapshot.Boards, err = snapshotRepository.GetBoards(); err != nil {
+			return err
+		}
+		if snapshot.Columns, err = snapshotRepository.GetColumns(); err != nil {
+			return err
+		}
+		if snapshot.Labels, err = snapshotRepository.GetLabels(); err != nil {
+			return err
+		}
+		if snapshot.Tasks, err = snapshotRepository.GetTasks(); err != nil {
+			return err
+		}
+		if snapshot.Dependencies, err = snapshotRepository.GetDependencies(); err != nil {
+			return err
+		}
+		if snapshot.Checklists, err = snapshotRepository.GetChecklists(); err != nil {
+			return err
+		}
+		if snapshot.Comments, err = snapshotRepository.GetComments(); err != nil {
+			return err
+		}
+		snapshot.Activities, err = snapshotRepository.GetActivities()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// Import writes a snapshot in one transaction. Users are matched by username and created when missing,
+// without a password hash they can't log in until a root user sets a password. References to users
+// missing from the snapshot fall back to the importing user where one is required
+func (ss *SnapshotService) Import(snapshot *Snapshot, mode string, userID int) (*ImportResult, error) {
+	if err := validateSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Mode: mode}
+	err := ss.db.Transaction(func(tx *database.Database) error {
+		importer := &snapshotImport{
+			snapshot:           snapshot,
+			result:             result,
+			repository:         repository.NewSnapshotRepository(tx),
+			settingsRepository: repository.NewSettingsRepository(tx),
+			keepIDs:            mode == ImportModeRestore,
+			userID:             userID,
+			users:              make(map[int]int),
+			boards:             make(map[int]int),
+			columns:            make(map[int]int),
+			labels:             make(map[int]int),
+			tasks:              make(map[int]int),
+		}
+		return importer.run()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// validateSnapshot checks the version and that every reference between the rows resolves inside the document
+func validateSnapshot(snapshot *Snapshot) error {
+	if snapshot.Version != SnapshotVersion {
+		return &SnapshotError{Message: fmt.Sprintf("unsupported snapshot version %d, expected %d", snapshot.Version, SnapshotVersion)}
+	}
+
+	usernames := make(map[string]bool)
+	for _, user := range snapshot.Users {
+		if user.UserName == "" {
+			return &SnapshotError{Message: fmt.Sprintf("user %d has no username", user.ID)}
+		}
+		if usernames[user.UserName] {
+			return &SnapshotError{Message: fmt.Sprintf("username %q appears more than once", user.UserName)}
+		}
+		usernames[user.UserName] = true
+	}
+
+	boards := make(map[int]bool)
+	for _, board := range snapshot.Boards {
+		if boards[board.ID] {
+			return &SnapshotError{Message: fmt.Sprintf("board %d appears more than once", board.ID)}
+		}
+		boards[board.ID] = true
+	}
+
+	columns := make(map[int]bool)
+	for _, column := range snapshot.Columns {
+		if columns[column.ID] {
+			return &SnapshotError{Message: fmt.Sprintf("column %d appears more than once", column.ID)}
+		}
+		if column.BoardID == nil || !boards[*column.BoardID] {
+			return &SnapshotError{Message: fmt.Sprintf("column %d belongs to a board that isn't in the snapshot", column.ID)}
+		}
+		columns[column.ID] = true
+	}
+
+	labels := make(map[int]bool)
+	for _, label := range snapshot.Labels {
+		if labels[label.ID] {
+			return &SnapshotError{Message: fmt.Sprintf("label %d appears more than once", label.ID)}
+		}
+		labels[label.ID] = true
+	}
+
+	tasks := make(map[int]bool)
+	for _, task := range snapshot.Tasks {
+		if tasks[task.ID] {
+			return &SnapshotError{Message: fmt.Sprintf("task %d appears more than once", task.ID)}
+		}
+		if !columns[task.ColumnID] {
+			return &SnapshotError{Message: fmt.Sprintf("task %d is in a column that isn't in the snapshot", task.ID)}
+		}
+		for _, labelID := range task.LabelIDs {
+			if !labels[labelID] {
+				return &SnapshotError{Message: fmt.Sprintf("task %d has a label that isn't in the snapshot", task.ID)}
+			}
+		}
+		tasks[task.ID] = true
+	}
+
+	for _, dependency := range snapshot.Dependencies {
+		if !tasks[dependency.TaskID] || !tasks[dependency.BlockedByTaskID] {
+			return &SnapshotError{Message: fmt.Sprintf("dependency of task %d refers to a task that isn't in the snapshot", dependency.TaskID)}
+		}
+	}
+
+	checklists := make(map[int]bool)
+	for _, checklist := range snapshot.Checklists {
+		if checklists[checklist.ID] {
+			return &SnapshotError{Message: fmt.Sprintf("checklist item %d appears more than once", checklist.ID)}
+		}
+		if !tasks[checklist.TaskID] {
+			return &SnapshotError{Message: fmt.Sprintf("checklist item %d belongs to a task that isn't in the snapshot", checklist.ID)}
+		}
+		checklists[checklist.ID] = true
+	}
+
+	comments := make(map[int]bool)
+	for _, comment := range snapshot.Comments {
+		if comments[comment.ID] {
+			return &SnapshotError{Message: fmt.Sprintf("comment %d appears more than once", comment.ID)}
+		}
+		if !tasks[comment.TaskID] {
+			return &SnapshotError{Message: fmt.Sprintf("comment %d belongs to a task that isn't in the snapshot", comment.ID)}
+		}
+		comments[comment.ID] = true
+	}
+
+	activities := make(map[int]bool)
+	for _, activity := range snapshot.Activities {
+		if activities[activity.ID] {
+			return &SnapshotError{Message: fmt.Sprintf("activity %d appears more than once", activity.ID)}
+		}
+		activities[activity.ID] = true
+	}
+
+	return nil
+}
+
+// snapshotImport holds the state of one import, the maps go from snapshot IDs to IDs in the database
+type snapshotImport struct {
+	snapshot           *Snapshot
+	result             *ImportResult
+	repository         *repository.SnapshotRepository
+	settingsRepository *repository.SettingsRepository
+	keepIDs            bool
+	userID             int
+
+	users   map[int]int
+	boards  map[int]int
+	columns map[int]int
+	labels  map[int]int
+	tasks   map[int]int
+}
+
+func (si *snapshotImport) run() error {
+	if si.keepIDs {
+		empty, err := si.repository.IsEmpty()
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return ErrDatabaseNotEmpty
+		}
+
+		// The default board of a fresh install would hold the ID of an imported board
+		if err := si.repository.DeleteEmptyBoards(); err != nil {
+			return err
+		}
+	}
+
+	steps := []func() error{
+		si.importUsers, si.importBoards, si.importColumns, si.importLabels, si.importTasks,
+		si.importDependencies, si.importChecklists, si.importComments, si.importActivities, si.importSettings,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (si *snapshotImport) importUsers() error {
+	for _, user := range si.snapshot.Users {
+		id, found, err := si.repository.FindUserID(user.UserName)
+		if err != nil {
+			return err
+		}
+		if found {
+			si.users[user.ID] = id
+			si.result.MatchedUsers++
+			continue
+		}
+
+		// An empty hash never matches a password, the user needs a new one first
+		password := ""
+		if user.Password != nil {
+			password = *user.Password
+		}
+
+		if id, err = si.repository.InsertUser(user, password); err != nil {
+			return fmt.Errorf("failed to import user %q: %w", user.UserName, err)
+		}
+		si.users[user.ID] = id
+		si.result.Users++
+	}
+
+	return nil
+}
+
+func (si *snapshotImport) importBoards() error {
+	// Merged boards go after the existing ones
+	offset := 0
+	if !si.keepIDs {
+		var err error
+		if offset, err = si.repository.GetMaxBoardPosition(); err != nil {
+			return err
+		}
+	}
+
+	for _, board := range si.snapshot.Boards {
+		id, err := si.repository.InsertBoard(board, si.optionalUser(board.CreatedBy), board.Position+offset, si.keepIDs)
+		if err != nil {
+			return fmt.Errorf("failed to import board %d: %w", board.ID, err)
+		}
+		si.boards[board.ID] = id
+		si.result.Boards++
+	}
+
+	return nil
+}
+
+func (si *snapshotImport) importColumns() error {
+	for _, column := range si.snapshot.Columns {
+		id, err := si.repository.InsertColumn(column, si.boards[*column.BoardID], si.requiredUser(column.CreatedBy), si.keepIDs)
+		if err != nil {
+			return fmt.Errorf("failed to import column %d: %w", column.ID, err)
+		}
+		si.columns[column.ID] = id
+		si.result.Columns++
+	}
+
+	return nil
+}
+
+func (si *snapshotImport) importLabels() error {
+	for _, label := range si.snapshot.Labels {
+		// Label names are unique, a merge reuses the existing label of the same name
+		if !si.keepIDs {
+			id, found, err := si.repository.FindLabelID(label.Name)
+			if err != nil {
+				return err
+			}
+			if found {
+				si.labels[label.ID] = id
+				si.result.MatchedLabels++
+				continue
+			}
+		}
+
+		id, err := si.repository.InsertLabel(label, si.optionalUser(label.CreatedBy), si.keepIDs)
+		if err != nil {
+			return fmt.Errorf("failed to import label %q: %w", label.Name, err)
+		}
+		si.labels[label.ID] = id
+		si.result.Labels++
+	}
+
+	return nil
+}
+
+// importTasks adds parents before their subtasks, so parent IDs can be mapped as the tasks go in
+func (si *snapshotImport) importTasks() error {
+	inSnapshot := make(map[int]bool)
+	for _, task := range si.snapshot.Tasks {
+		inSnapshot[task.ID] = true
+	}
+
+	pending := si.snapshot.Tasks
+	for len(pending) > 0 {
+		var waiting []*repository.SnapshotTask
+		for _, task := range pending {
+			if task.ParentID != nil && inSnapshot[*task.ParentID] {
+				if _, ok := si.tasks[*task.ParentID]; !ok {
+					waiting = append(waiting, task)
+					continue
+				}
+			}
+			if err := si.importTask(task); err != nil {
+				return err
+			}
+		}
+
+		if len(waiting) == len(pending) {
+			return &SnapshotError{Message: fmt.Sprintf("task %d is part of a subtask cycle", waiting[0].ID)}
+		}
+		pending = waiting
+	}
+
+	return nil
+}
+
+func (si *snapshotImport) importTask(task *repository.SnapshotTask) error {
+	var parentID *int
+	if task.ParentID != nil {
+		if id, ok := si.tasks[*task.ParentID]; ok {
+			parentID = &id
+		}
+	}
+
+	id, err := si.repository.InsertTask(task, si.columns[task.ColumnID], si.optionalUser(task.AssignedTo),
+		si.requiredUser(task.CreatedBy), parentID, si.optionalUser(task.DeletedBy), si.keepIDs)
+	if err != nil {
+		return fmt.Errorf("failed to import task %d: %w", task.ID, err)
+	}
+	si.tasks[task.ID] = id
+	si.result.Tasks++
+
+	for _, labelID := range task.LabelIDs {
+		if err := si.repository.InsertTaskLabel(id, si.labels[labelID]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (si *snapshotImport) importDependencies() error {
+	for _, dependency := range si.snapshot.Dependencies {
+		err := si.repository.InsertDependency(si.tasks[dependency.TaskID], si.tasks[dependency.BlockedByTaskID],
+			si.optionalUser(dependency.CreatedBy), dependency.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to import dependency of task %d: %w", dependency.TaskID, err)
+		}
+		si.result.Dependencies++
+	}
+
+	return nil
+}
+
+func (si *snapshotImport) importChecklists() error {
+	for _, checklist := range si.snapshot.Checklists {
+		_, err := si.repository.InsertChecklist(checklist, si.tasks[checklist.TaskID], si.requiredUser(checklist.CreatedBy),
+			si.optionalUser(checklist.CompletedBy), si.keepIDs)
+		if err != nil {
+			return fmt.Errorf("failed to import checklist item %d: %w", checklist.ID, err)
+		}
+		si.result.Checklists++
+	}
+
+	return nil
+}
+
+func (si *snapshotImport) importComments() error {
+	for _, comment := range si.snapshot.Comments {
+		_, err := si.repository.InsertComment(comment, si.tasks[comment.TaskID], si.requiredUser(comment.CreatedBy), si.keepIDs)
+		if err != nil {
+			return fmt.Errorf("failed to import comment %d: %w", comment.ID, err)
+		}
+		si.result.Comments++
+	}
+
+	return nil
+}
+
+// importActivities maps the task each activity is about, and the columns or users behind its values.
+// A merge drops the history of tasks that aren't in the snapshot, a restore keeps it as it was
+func (si *snapshotImport) importActivities() error {
+	for _, activity := range si.snapshot.Activities {
+		entityID, ok := activity.EntityID, si.keepIDs
+		if activity.EntityType == "task" {
+			if id, found := si.tasks[activity.EntityID]; found {
+				entityID, ok = id, true
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		oldRef, newRef := activity.OldRef, activity.NewRef
+		if activity.FieldName != nil {
+			switch *activity.FieldName {
+			case "column":
+				oldRef, newRef = mapRef(si.columns, oldRef, si.keepIDs), mapRef(si.columns, newRef, si.keepIDs)
+			case "assignee":
+				oldRef, newRef = mapRef(si.users, oldRef, false), mapRef(si.users, newRef, false)
+			}
+		}
+
+		_, err := si.repository.InsertActivity(activity, entityID, oldRef, newRef, si.requiredUser(activity.UserID), si.keepIDs)
+		if err != nil {
+			return fmt.Errorf("failed to import activity %d: %w", activity.ID, err)
+		}
+		si.result.Activities++
+	}
+
+	return nil
+}
+
+// importSettings applies the snapshot's settings on a restore, a merge keeps the current ones
+func (si *snapshotImport) importSettings() error {
+	settings := si.snapshot.Settings
+	if !si.keepIDs || settings == nil {
+		return nil
+	}
+
+	_, err := si.settingsRepository.UpdateSettings(settings.AppName, settings.AppDescription, settings.DefaultTheme,
+		settings.EnableNotifications, &settings.EnforceDependencies, &settings.TrashRetentionDays, &settings.AutoArchiveDays)
+	return err
+}
+
+// requiredUser maps a user reference that can't be empty, falling back to the importing user
+func (si *snapshotImport) requiredUser(id int) int {
+	if mapped, ok := si.users[id]; ok {
+		return mapped
+	}
+	return si.userID
+}
+
+// optionalUser maps a nullable user reference, dropping it when the user isn't in the snapshot
+func (si *snapshotImport) optionalUser(id *int) *int {
+	if id == nil {
+		return nil
+	}
+	if mapped, ok := si.users[*id]; ok {
+		return &mapped
+	}
+	return nil
+}
+
+// mapRef maps an activity reference, 0 stands for no value and stays as it is. Unknown references
+// are kept when keep is set, for rows deleted before the export whose IDs a restore preserved
+func mapRef(ids map[int]int, ref *int, keep bool) *int {
+	if ref == nil || *ref == 0 {
+		return ref
+	}
+	if mapped, ok := ids[*ref]; ok {
+		return &mapped
+	}
+	if keep {
+		return ref
+	}
+	return nil
+}
@@ -0,0 +1,948 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/repository"
+)
+
+// SyncBatchSize is how many changed rows go into one pull or push request
+const SyncBatchSize = 500
+
+// SyncTokenHeader carries the token of the instance being read from or written to
+const SyncTokenHeader = "X-Sync-Token"
+
+// Kinds of sync conflicts
+const (
+	SyncConflictConcurrentEdit   = "concurrent_edit"   // Both instances changed the same field, or row, since they last synced
+	SyncConflictDeletedRemotely  = "deleted_remotely"  // Edited here, deleted on the other instance
+	SyncConflictDeletedLocally   = "deleted_locally"   // Deleted here, edited on the other instance
+	SyncConflictMissingReference = "missing_reference" // Points to a board, column or task this instance doesn't have
+	SyncConflictUnknownUser      = "unknown_user"      // Points to a username this instance doesn't have
+	SyncConflictUnknownEntity    = "unknown_entity"    // Sent by a newer version that syncs more than this one
+)
+
+// How a conflict was settled
+const (
+	SyncResolutionTookRemote = "took_remote"
+	SyncResolutionKeptLocal  = "kept_local"
+	SyncResolutionCleared    = "cleared"
+	SyncResolutionSkipped    = "skipped"
+)
+
+var ErrSyncSameInstance = errors.New("the peer is this instance")
+
+// SyncVersion orders the changes of a row or field across instances, a higher clock wins and the
+// origin instance ID breaks ties
+type SyncVersion struct {
+	Clock    int64  `json:"clock"`
+	Origin   string `json:"origin"`
+	LocalSeq int64  `json:"local_seq,omitempty"` // Last edit on the instance sending it, 0 when it only changed elsewhere
+}
+
+func (v SyncVersion) same(other SyncVersion) bool {
+	return v.Clock == other.Clock && v.Origin == other.Origin
+}
+
+func (v SyncVersion) newerThan(other SyncVersion) bool {
+	if v.Clock != other.Clock {
+		return v.Clock > other.Clock
+	}
+	return v.Origin > other.Origin
+}
+
+// SyncChange is the current state of one changed row. References hold global IDs, users their username
+type SyncChange struct {
+	Entity    string                 `json:"entity"`
+	GlobalID  string                 `json:"global_id"`
+	Seq       int64                  `json:"seq"`
+	Version   SyncVersion            `json:"version"`
+	Deleted   bool                   `json:"deleted"`
+	CreatedAt *time.Time             `json:"created_at,omitempty"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+	Versions  map[string]SyncVersion `json:"versions,omitempty"` // Per field, for entities merged field by field
+}
+
+// SyncBatch is a page of changes read from an instance
+type SyncBatch struct {
+	InstanceID string        `json:"instance_id"`
+	Changes    []*SyncChange `json:"changes"`
+	Next       int64         `json:"next"` // Read from here for the following page
+	More       bool          `json:"more"`
+}
+
+// SyncPush is a page of changes written to an instance
+type SyncPush struct {
+	InstanceID string        `json:"instance_id"`
+	Seen       int64         `json:"seen"`  // Last change of the receiving instance the sender has applied
+	Since      int64         `json:"since"` // Last change of the sender the receiving instance has applied
+	Changes    []*SyncChange `json:"changes"`
+}
+
+// SyncApplyResult counts what applying changes did
+type SyncApplyResult struct {
+	Applied   int                        `json:"applied"`
+	Unchanged int                        `json:"unchanged"`
+	Conflicts []*repository.SyncConflict `json:"conflicts"`
+}
+
+func (r *SyncApplyResult) add(other *SyncApplyResult) {
+	r.Applied += other.Applied
+	r.Unchanged += other.Unchanged
+	r.Conflicts = append(r.Conflicts, other.Conflicts...)
+}
+
+// SyncResult is the outcome of syncing with a peer in both directions
+type SyncResult struct {
+	Peer   *repository.SyncPeer `json:"peer"`
+	Pulled *SyncApplyResult     `json:"pulled"`
+	Pushed *SyncApplyResult     `json:"pushed"`
+}
+
+// SyncError is a peer that could not be synced with
+type SyncError struct {
+	Message string
+}
+
+func (e *SyncError) Error() string {
+	return e.Message
+}
+
+// SyncService exchanges changed boards, columns, tasks, comments and checklists with other instances.
+// Rows are last-writer-wins, tasks field by field, and a deleted row stays deleted. Whatever could
+// not be merged cleanly is recorded as a conflict for root users to review
+type SyncService struct {
+	db                 *database.Database
+	syncRepository     *repository.SyncRepository
+	syncPeerRepository *repository.SyncPeerRepository
+	client             *http.Client
+}
+
+func NewSyncService(db *database.Database) *SyncService {
+	return &SyncService{
+		db:                 db,
+		syncRepository:     repository.NewSyncRepository(db),
+		syncPeerRepository: repository.NewSyncPeerRepository(db),
+		client:             &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// SyncStatus is this instance's identity for syncing, Token is only set right after it was generated
+type SyncStatus struct {
+	InstanceID string  `json:"instance_id"`
+	Clock      int64   `json:"clock"`
+	Seq        int64   `json:"seq"`
+	Enabled    bool    `json:"enabled"` // Whether other instances can sync with this one
+	Token      *string `json:"token,omitempty"`
+}
+
+func (ss *SyncService) Status() (*SyncStatus, error) {
+	instance, err := ss.syncRepository.GetInstance()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SyncStatus{
+		InstanceID: instance.InstanceID,
+		Clock:      instance.Clock,
+		Seq:        instance.Seq,
+		Enabled:    instance.Token != nil,
+	}, nil
+}
+
+// GenerateToken replaces the token other instances sync with, the old one stops working
+func (ss *SyncService) GenerateToken() (*SyncStatus, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(random)
+
+	if err := ss.syncRepository.SetToken(&token); err != nil {
+		return nil, err
+	}
+
+	status, err := ss.Status()
+	if err != nil {
+		return nil, err
+	}
+	status.Token = &token
+	return status, nil
+}
+
+// DisableToken stops other instances from syncing with this one, syncing with peers still works
+func (ss *SyncService) DisableToken() (*SyncStatus, error) {
+	if err := ss.syncRepository.SetToken(nil); err != nil {
+		return nil, err
+	}
+	return ss.Status()
+}
+
+// Changes reads the rows changed after since, in one transaction so references between them line up
+func (ss *SyncService) Changes(since int64, limit int) (*SyncBatch, error) {
+	batch := &SyncBatch{Changes: []*SyncChange{}, Next: since}
+
+	err := ss.db.Transaction(func(tx *database.Database) error {
+		syncRepository := repository.NewSyncRepository(tx)
+
+		instance, err := syncRepository.GetInstance()
+		if err != nil {
+			return err
+		}
+		batch.InstanceID = instance.InstanceID
+
+		rows, err := syncRepository.GetChangedRows(since, limit+1)
+		if err != nil {
+			return err
+		}
+		if len(rows) > limit {
+			rows = rows[:limit]
+			batch.More = true
+		}
+
+		for _, row := range rows {
+			change, err := exportSyncChange(syncRepository, row)
+			if err != nil {
+				return err
+			}
+			batch.Changes = append(batch.Changes, change)
+			batch.Next = row.Seq
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+// exportSyncChange reads the current state of a changed row
+func exportSyncChange(syncRepository *repository.SyncRepository, row *repository.SyncRow) (*SyncChange, error) {
+	change := &SyncChange{
+		Entity:   row.Entity,
+		GlobalID: row.GlobalID,
+		Seq:      row.Seq,
+		Version:  SyncVersion{Clock: row.Clock, Origin: row.Origin, LocalSeq: row.LocalSeq},
+		Deleted:  row.Deleted,
+	}
+
+	entity, ok := database.FindSyncEntity(row.Entity)
+	if !ok || row.Deleted {
+		return change, nil
+	}
+
+	fields, createdAt, found, err := syncRepository.ReadFields(entity, row.LocalID)
+	if err != nil {
+		return nil, err
+	}
+	// Rows removed along with their task don't have tombstones of their own
+	if !found {
+		change.Deleted = true
+		return change, nil
+	}
+
+	change.CreatedAt = &createdAt
+	change.Fields = map[string]interface{}{}
+	for _, field := range entity.Fields {
+		value, err := exportSyncValue(syncRepository, field, fields[field.Name])
+		if err != nil {
+			return nil, err
+		}
+		change.Fields[field.Name] = value
+	}
+
+	if entity.FieldMerge {
+		versions, err := syncRepository.GetFieldVersions(entity.Name, row.GlobalID)
+		if err != nil {
+			return nil, err
+		}
+
+		change.Versions = map[string]SyncVersion{}
+		for _, field := range entity.Fields {
+			if version, ok := versions[field.Name]; ok {
+				change.Versions[field.Name] = SyncVersion{Clock: version.Clock, Origin: version.Origin, LocalSeq: version.LocalSeq}
+			} else {
+				change.Versions[field.Name] = change.Version
+			}
+		}
+	}
+
+	return change, nil
+}
+
+// exportSyncValue turns a column value into its form in a change, IDs become global IDs or usernames
+func exportSyncValue(syncRepository *repository.SyncRepository, field database.SyncField, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case time.Time:
+		return v.UTC().Format("2006-01-02 15:04:05"), nil
+	case []byte:
+		value = string(v)
+	}
+
+	if field.Ref == "" {
+		return value, nil
+	}
+
+	id, ok := value.(int64)
+	if !ok {
+		return nil, nil
+	}
+
+	if field.Ref == "user" {
+		username, found, err := syncRepository.FindUsername(id)
+		if err != nil || !found {
+			return nil, err
+		}
+		return username, nil
+	}
+
+	row, err := syncRepository.FindRowByLocalID(field.Ref, int(id))
+	if err != nil || row == nil {
+		return nil, err
+	}
+	return row.GlobalID, nil
+}
+
+// Apply writes changes read from another instance. seen is the last change of this instance the
+// other one has applied, anything changed here after it is unknown over there, and since the last
+// change of the other instance applied here before these
+func (ss *SyncService) Apply(remoteInstanceID string, seen, since int64, changes []*SyncChange) (*SyncApplyResult, error) {
+	result := &SyncApplyResult{Conflicts: []*repository.SyncConflict{}}
+
+	err := ss.db.Transaction(func(tx *database.Database) error {
+		syncRepository := repository.NewSyncRepository(tx)
+
+		instance, err := syncRepository.GetInstance()
+		if err != nil {
+			return err
+		}
+		if remoteInstanceID == instance.InstanceID {
+			return ErrSyncSameInstance
+		}
+
+		// The triggers would record the writes below as local edits
+		if err := syncRepository.SetApplying(true); err != nil {
+			return err
+		}
+
+		apply := &syncApply{
+			tx:                 tx,
+			syncRepository:     syncRepository,
+			syncPeerRepository: repository.NewSyncPeerRepository(tx),
+			remoteInstanceID:   remoteInstanceID,
+			seen:               seen,
+			since:              since,
+			result:             result,
+		}
+		for _, change := range changes {
+			if err := apply.change(change); err != nil {
+				return fmt.Errorf("applying %s %s: %w", change.Entity, change.GlobalID, err)
+			}
+		}
+
+		return syncRepository.SetApplying(false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// syncApply holds what applying one batch of changes needs
+type syncApply struct {
+	tx                 *database.Database
+	syncRepository     *repository.SyncRepository
+	syncPeerRepository *repository.SyncPeerRepository
+	remoteInstanceID   string
+	seen               int64
+	since              int64
+	result             *SyncApplyResult
+	rootUserID         int
+}
+
+func (a *syncApply) change(change *SyncChange) error {
+	entity, ok := database.FindSyncEntity(change.Entity)
+	if !ok {
+		return a.conflict(change, nil, nil, SyncConflictUnknownEntity,
+			fmt.Sprintf("This version can't sync %q rows", change.Entity), nil, change.Fields, SyncResolutionSkipped)
+	}
+
+	// Later local edits have to win over everything seen from the other instance
+	clock := change.Version.Clock
+	for _, version := range change.Versions {
+		clock = max(clock, version.Clock)
+	}
+	if err := a.syncRepository.ObserveClock(clock); err != nil {
+		return err
+	}
+
+	local, err := a.syncRepository.FindRow(entity.Name, change.GlobalID)
+	if err != nil {
+		return err
+	}
+
+	var localChange *SyncChange
+	if local != nil {
+		if localChange, err = exportSyncChange(a.syncRepository, local); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case local == nil && change.Deleted:
+		// Deleted before this instance ever saw it
+		a.result.Unchanged++
+		return nil
+	case local == nil:
+		return a.insert(entity, change)
+	case localChange.Deleted:
+		return a.keepDeleted(entity, change, local)
+	case change.Deleted:
+		return a.delete(entity, change, local, localChange)
+	case entity.FieldMerge:
+		return a.mergeFields(entity, change, local, localChange)
+	default:
+		return a.mergeRow(entity, change, local, localChange)
+	}
+}
+
+// changedHere reports whether the row or field was edited here since the other instance last synced
+func (a *syncApply) changedHere(localSeq int64) bool {
+	return localSeq > a.seen
+}
+
+// changedThere reports whether the row or field was edited on the other instance since this one last
+// synced, or reached it from elsewhere in a version this instance doesn't have
+func (a *syncApply) changedThere(remote, local SyncVersion) bool {
+	return remote.LocalSeq > a.since || remote.newerThan(local)
+}
+
+func (a *syncApply) insert(entity *database.SyncEntity, change *SyncChange) error {
+	values := map[string]interface{}{}
+	for _, field := range entity.Fields {
+		value, ok, err := a.localValue(entity, change, field, nil)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		values[field.Column] = value
+	}
+
+	createdAt := time.Now()
+	if change.CreatedAt != nil {
+		createdAt = *change.CreatedAt
+	}
+
+	localID, err := a.syncRepository.InsertFields(entity, values, createdAt)
+	if err != nil {
+		return err
+	}
+
+	if err := a.saveRow(entity, change, localID, change.Version, 0, false); err != nil {
+		return err
+	}
+
+	if entity.FieldMerge {
+		for _, field := range entity.Fields {
+			version, ok := change.Versions[field.Name]
+			if !ok {
+				version = change.Version
+			}
+			if err := a.saveFieldVersion(entity, change, field, version); err != nil {
+				return err
+			}
+		}
+	}
+
+	a.result.Applied++
+	return nil
+}
+
+// keepDeleted handles a change to a row deleted here, deleted rows are never brought back
+func (a *syncApply) keepDeleted(entity *database.SyncEntity, change *SyncChange, local *repository.SyncRow) error {
+	if !change.Deleted && a.changedHere(local.LocalSeq) && change.Version.LocalSeq > a.since {
+		return a.conflict(change, &local.LocalID, nil, SyncConflictDeletedLocally,
+			"Edited on the other instance but deleted here, it stays deleted", nil, change.Fields, SyncResolutionKeptLocal)
+	}
+
+	a.result.Unchanged++
+	return nil
+}
+
+// delete removes a row deleted on the other instance, even when it was edited here meanwhile
+func (a *syncApply) delete(entity *database.SyncEntity, change *SyncChange, local *repository.SyncRow, localChange *SyncChange) error {
+	if a.changedHere(local.LocalSeq) {
+		err := a.conflict(change, &local.LocalID, nil, SyncConflictDeletedRemotely,
+			"Edited here but deleted on the other instance, it was deleted", localChange.Fields, nil, SyncResolutionTookRemote)
+		if err != nil {
+			return err
+		}
+	}
+
+	if entity.Name == "task" {
+		if err := a.reportDependents(entity, change, local.LocalID); err != nil {
+			return err
+		}
+
+		// Takes the task's comments, checklists and labels with it
+		if err := repository.NewTaskRepository(a.tx).Delete(local.LocalID); err != nil {
+			return err
+		}
+	} else if err := a.syncRepository.DeleteRow(entity, local.LocalID); err != nil {
+		return err
+	}
+
+	version := change.Version
+	if !version.newerThan(localChange.Version) {
+		version = localChange.Version
+	}
+	if err := a.saveRow(entity, change, local.LocalID, version, 0, true); err != nil {
+		return err
+	}
+
+	a.result.Applied++
+	return nil
+}
+
+// reportDependents records a conflict for every row edited here that goes away with a row deleted on
+// the other instance
+func (a *syncApply) reportDependents(entity *database.SyncEntity, change *SyncChange, localID int) error {
+	for i := range database.SyncEntities {
+		dependent := &database.SyncEntities[i]
+		for _, field := range dependent.Fields {
+			if field.Ref != entity.Name || !field.Required {
+				continue
+			}
+
+			rows, err := a.syncRepository.GetReferencingRows(dependent, field.Column, localID)
+			if err != nil {
+				return err
+			}
+
+			for _, row := range rows {
+				if row.Deleted || !a.changedHere(row.LocalSeq) {
+					continue
+				}
+
+				dependentChange, err := exportSyncChange(a.syncRepository, row)
+				if err != nil {
+					return err
+				}
+				err = a.conflict(dependentChange, &row.LocalID, nil, SyncConflictDeletedRemotely,
+					fmt.Sprintf("Edited here but its %s was deleted on the other instance, it was deleted", entity.Name),
+					dependentChange.Fields, nil, SyncResolutionTookRemote)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// mergeRow keeps the newer of the two versions of a row
+func (a *syncApply) mergeRow(entity *database.SyncEntity, change *SyncChange, local *repository.SyncRow, localChange *SyncChange) error {
+	if change.Version.same(localChange.Version) {
+		a.result.Unchanged++
+		return nil
+	}
+
+	remoteNewer := change.Version.newerThan(localChange.Version)
+
+	if a.changedHere(local.LocalSeq) && a.changedThere(change.Version, localChange.Version) &&
+		!sameSyncValue(localChange.Fields, change.Fields) {
+		resolution := SyncResolutionKeptLocal
+		if remoteNewer {
+			resolution = SyncResolutionTookRemote
+		}
+		err := a.conflict(change, &local.LocalID, nil, SyncConflictConcurrentEdit,
+			"Changed on both instances since they last synced", localChange.Fields, change.Fields, resolution)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !remoteNewer {
+		a.result.Unchanged++
+		return nil
+	}
+
+	values := map[string]interface{}{}
+	for _, field := range entity.Fields {
+		value, ok, err := a.localValue(entity, change, field, &local.LocalID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		values[field.Column] = value
+	}
+
+	if err := a.syncRepository.UpdateFields(entity, local.LocalID, values); err != nil {
+		return err
+	}
+	if err := a.saveRow(entity, change, local.LocalID, change.Version, 0, false); err != nil {
+		return err
+	}
+
+	a.result.Applied++
+	return nil
+}
+
+// mergeFields keeps the newer version of every field on its own, so edits to different fields of
+// the same task on both instances are all kept
+func (a *syncApply) mergeFields(entity *database.SyncEntity, change *SyncChange, local *repository.SyncRow, localChange *SyncChange) error {
+	localVersions, err := a.syncRepository.GetFieldVersions(entity.Name, change.GlobalID)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{}
+	for _, field := range entity.Fields {
+		remoteVersion, ok := change.Versions[field.Name]
+		if !ok {
+			continue
+		}
+
+		localVersion := SyncVersion{Clock: local.Clock, Origin: local.Origin}
+		var localSeq int64
+		if version, ok := localVersions[field.Name]; ok {
+			localVersion = SyncVersion{Clock: version.Clock, Origin: version.Origin}
+			localSeq = version.LocalSeq
+		}
+		if remoteVersion.same(localVersion) {
+			continue
+		}
+
+		remoteNewer := remoteVersion.newerThan(localVersion)
+
+		if a.changedHere(localSeq) && a.changedThere(remoteVersion, localVersion) &&
+			!sameSyncValue(localChange.Fields[field.Name], change.Fields[field.Name]) {
+			resolution := SyncResolutionKeptLocal
+			if remoteNewer {
+				resolution = SyncResolutionTookRemote
+			}
+			name := field.Name
+			err := a.conflict(change, &local.LocalID, &name, SyncConflictConcurrentEdit,
+				fmt.Sprintf("%s changed on both instances since they last synced", field.Name),
+				localChange.Fields[field.Name], change.Fields[field.Name], resolution)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !remoteNewer {
+			continue
+		}
+
+		value, ok, err := a.localValue(entity, change, field, &local.LocalID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		values[field.Column] = value
+		if err := a.saveFieldVersion(entity, change, field, remoteVersion); err != nil {
+			return err
+		}
+	}
+
+	if len(values) == 0 {
+		a.result.Unchanged++
+		return nil
+	}
+
+	if err := a.syncRepository.UpdateFields(entity, local.LocalID, values); err != nil {
+		return err
+	}
+
+	// Fields edited here and not yet sent keep the row counted as changed here
+	version := localChange.Version
+	if change.Version.newerThan(version) {
+		version = change.Version
+	}
+	if err := a.saveRow(entity, change, local.LocalID, version, local.LocalSeq, false); err != nil {
+		return err
+	}
+
+	a.result.Applied++
+	return nil
+}
+
+// localValue turns a value from a change into the column value, resolving global IDs and usernames.
+// ok is false when a required reference is missing, which is recorded as a conflict
+func (a *syncApply) localValue(entity *database.SyncEntity, change *SyncChange, field database.SyncField, localID *int) (interface{}, bool, error) {
+	value := change.Fields[field.Name]
+	if value == nil || field.Ref == "" {
+		return value, true, nil
+	}
+
+	name := field.Name
+	reference, _ := value.(string)
+
+	if field.Ref == "user" {
+		userID, found, err := a.syncRepository.FindUserID(reference)
+		if err != nil || found {
+			return userID, err == nil, err
+		}
+
+		// Rows keep their content when their creator has no account here
+		if field.Required {
+			rootUserID, err := a.rootUser()
+			return rootUserID, err == nil, err
+		}
+
+		err = a.conflict(change, localID, &name, SyncConflictUnknownUser,
+			fmt.Sprintf("No user named %q here, %s was cleared", reference, field.Name), nil, value, SyncResolutionCleared)
+		return nil, err == nil, err
+	}
+
+	row, err := a.syncRepository.FindRow(field.Ref, reference)
+	if err != nil {
+		return nil, false, err
+	}
+	if row != nil && !row.Deleted {
+		return row.LocalID, true, nil
+	}
+
+	if field.Required {
+		err := a.conflict(change, localID, &name, SyncConflictMissingReference,
+			fmt.Sprintf("The %s this %s belongs to doesn't exist here", field.Ref, entity.Name), nil, change.Fields, SyncResolutionSkipped)
+		return nil, false, err
+	}
+
+	err = a.conflict(change, localID, &name, SyncConflictMissingReference,
+		fmt.Sprintf("The %s doesn't exist here, %s was cleared", field.Ref, field.Name), nil, value, SyncResolutionCleared)
+	return nil, err == nil, err
+}
+
+func (a *syncApply) rootUser() (int, error) {
+	if a.rootUserID == 0 {
+		id, err := a.syncRepository.FirstRootUserID()
+		if err != nil {
+			return 0, err
+		}
+		a.rootUserID = id
+	}
+	return a.rootUserID, nil
+}
+
+// saveRow records the new version of a row, with a new sequence number so it is passed on to other instances
+func (a *syncApply) saveRow(entity *database.SyncEntity, change *SyncChange, localID int, version SyncVersion, localSeq int64, deleted bool) error {
+	seq, err := a.syncRepository.NextSeq()
+	if err != nil {
+		return err
+	}
+
+	return a.syncRepository.SaveRow(&repository.SyncRow{
+		Entity:   entity.Name,
+		LocalID:  localID,
+		GlobalID: change.GlobalID,
+		Clock:    version.Clock,
+		Origin:   version.Origin,
+		Seq:      seq,
+		LocalSeq: localSeq,
+		Deleted:  deleted,
+	})
+}
+
+func (a *syncApply) saveFieldVersion(entity *database.SyncEntity, change *SyncChange, field database.SyncField, version SyncVersion) error {
+	return a.syncRepository.SaveFieldVersion(entity.Name, change.GlobalID, field.Name,
+		&repository.SyncFieldVersion{Clock: version.Clock, Origin: version.Origin})
+}
+
+func (a *syncApply) conflict(change *SyncChange, localID *int, field *string, kind, message string, localValue, remoteValue interface{}, resolution string) error {
+	conflict, err := a.syncPeerRepository.CreateConflict(&repository.SyncConflict{
+		RemoteInstanceID: a.remoteInstanceID,
+		Entity:           change.Entity,
+		GlobalID:         change.GlobalID,
+		LocalID:          localID,
+		Field:            field,
+		Kind:             kind,
+		Message:          message,
+		LocalValue:       syncJSON(localValue),
+		RemoteValue:      syncJSON(remoteValue),
+		Resolution:       resolution,
+	})
+	if err != nil {
+		return err
+	}
+
+	a.result.Conflicts = append(a.result.Conflicts, conflict)
+	return nil
+}
+
+func syncJSON(value interface{}) *string {
+	if value == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	text := string(encoded)
+	return &text
+}
+
+// sameSyncValue compares values the way they are sent, so 3 read here equals 3 decoded from JSON
+func sameSyncValue(a, b interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+// SyncPeer pulls the peer's changes and then pushes this instance's changes to it
+func (ss *SyncService) SyncPeer(peerID int) (*SyncResult, error) {
+	peer, err := ss.syncPeerRepository.FindByID(peerID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{
+		Pulled: &SyncApplyResult{Conflicts: []*repository.SyncConflict{}},
+		Pushed: &SyncApplyResult{Conflicts: []*repository.SyncConflict{}},
+	}
+
+	if err := ss.syncPeer(peer, result); err != nil {
+		message := err.Error()
+		if resultErr := ss.syncPeerRepository.SetResult(peer.ID, &message); resultErr != nil {
+			return nil, resultErr
+		}
+		return nil, err
+	}
+
+	if err := ss.syncPeerRepository.SetResult(peer.ID, nil); err != nil {
+		return nil, err
+	}
+
+	if result.Peer, err = ss.syncPeerRepository.FindByID(peer.ID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (ss *SyncService) syncPeer(peer *repository.SyncPeer, result *SyncResult) error {
+	instance, err := ss.syncRepository.GetInstance()
+	if err != nil {
+		return err
+	}
+
+	for {
+		batch := &SyncBatch{}
+		query := url.Values{"since": {strconv.FormatInt(peer.PulledSeq, 10)}, "limit": {strconv.Itoa(SyncBatchSize)}}
+		if err := ss.request(peer, http.MethodGet, "/sync/changes?"+query.Encode(), nil, batch); err != nil {
+			return err
+		}
+
+		if batch.InstanceID == instance.InstanceID {
+			return &SyncError{Message: ErrSyncSameInstance.Error()}
+		}
+		if peer.RemoteInstanceID == nil {
+			if err := ss.syncPeerRepository.SetRemoteInstance(peer.ID, batch.InstanceID); err != nil {
+				return err
+			}
+			peer.RemoteInstanceID = &batch.InstanceID
+		} else if *peer.RemoteInstanceID != batch.InstanceID {
+			return &SyncError{Message: "A different instance answers at this address now, add it as a new peer to sync with it"}
+		}
+
+		applied, err := ss.Apply(batch.InstanceID, peer.PushedSeq, peer.PulledSeq, batch.Changes)
+		if err != nil {
+			return err
+		}
+		result.Pulled.add(applied)
+
+		if err := ss.syncPeerRepository.SetPulledSeq(peer.ID, batch.Next); err != nil {
+			return err
+		}
+		peer.PulledSeq = batch.Next
+
+		if !batch.More {
+			break
+		}
+	}
+
+	for {
+		batch, err := ss.Changes(peer.PushedSeq, SyncBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch.Changes) == 0 {
+			break
+		}
+
+		applied := &SyncApplyResult{}
+		push := &SyncPush{InstanceID: instance.InstanceID, Seen: peer.PulledSeq, Since: peer.PushedSeq, Changes: batch.Changes}
+		if err := ss.request(peer, http.MethodPost, "/sync/changes", push, applied); err != nil {
+			return err
+		}
+		result.Pushed.add(applied)
+
+		if err := ss.syncPeerRepository.SetPushedSeq(peer.ID, batch.Next); err != nil {
+			return err
+		}
+		peer.PushedSeq = batch.Next
+
+		if !batch.More {
+			break
+		}
+	}
+
+	return nil
+}
+
+// request calls the peer's sync API and decodes the answer into target
+func (ss *SyncService) request(peer *repository.SyncPeer, method, path string, body, target interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimRight(peer.URL, "/")+path, reader)
+	if err != nil {
+		return &SyncError{Message: "Invalid peer address: " + err.Error()}
+	}
+	req.Header.Set(SyncTokenHeader, peer.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := ss.client.Do(req)
+	if err != nil {
+		return &SyncError{Message: "Can't reach the peer: " + err.Error()}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return &SyncError{Message: fmt.Sprintf("The peer answered %d: %s", res.StatusCode, strings.TrimSpace(string(message)))}
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(target); err != nil {
+		return &SyncError{Message: "Invalid answer from the peer: " + err.Error()}
+	}
+	return nil
+}
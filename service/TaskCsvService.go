@@ -0,0 +1,485 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/repository"
+)
+
+// TaskCsvHeader is the header of a task export, an export imports back as new tasks
+var TaskCsvHeader = []string{
+	"id", "title", "description", "column_id", "column", "assignee", "priority", "due_date",
+	"labels", "parent_id", "created_by", "created_at", "updated_at", "archived_at",
+}
+
+// MaxCsvImportRows caps the rows of one import, all of them are validated before anything is created
+const MaxCsvImportRows = 5000
+
+// csvImportFields maps the header names an import understands to the task field they fill
+var csvImportFields = map[string]string{
+	"title":       "title",
+	"description": "description",
+	"column_id":   "column_id",
+	"column":      "column",
+	"assignee":    "assignee",
+	"assigned_to": "assignee",
+	"priority":    "priority",
+	"due_date":    "due_date",
+	"due":         "due_date",
+}
+
+var csvPriorities = []string{"low", "medium", "high", "urgent"}
+
+// CsvImportError is a CSV file that can't be read as a task import at all
+type CsvImportError struct {
+	Message string
+}
+
+func (e *CsvImportError) Error() string {
+	return e.Message
+}
+
+type CsvImportOptions struct {
+	DryRun           bool
+	ColumnID         *int // Column of the rows that don't name one
+	BoardID          *int // Board the column titles are looked up on, all boards otherwise
+	OverrideWipLimit bool
+	UserID           int
+}
+
+// CsvRowError is a problem with one row, Row is the line in the file with the header on line 1
+type CsvRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type CsvImportResult struct {
+	DryRun         bool          `json:"dry_run"`
+	Rows           int           `json:"rows"`
+	Valid          int           `json:"valid"`
+	Created        int           `json:"created"`
+	TaskIDs        []int         `json:"task_ids"`
+	IgnoredColumns []string      `json:"ignored_columns"`
+	Errors         []CsvRowError `json:"errors"`
+}
+
+// csvTask is a validated row, ready to be created
+type csvTask struct {
+	row         int
+	title       string
+	description string
+	columnID    int
+	assignedTo  *int
+	dueDate     *time.Time
+	priority    string
+}
+
+type TaskCsvService struct {
+	db               *database.Database
+	taskRepository   *repository.TaskRepository
+	columnRepository *repository.ColumnRepository
+	userRepository   *repository.UserRepository
+}
+
+func NewTaskCsvService(db *database.Database) *TaskCsvService {
+	return &TaskCsvService{
+		db:               db,
+		taskRepository:   repository.NewTaskRepository(db),
+		columnRepository: repository.NewColumnRepository(db),
+		userRepository:   repository.NewUserRepository(db),
+	}
+}
+
+// Export writes the tasks matching the filters to w as CSV while they are read, flush is called
+// after every batch of rows so the client receives them as they come
+func (cs *TaskCsvService) Export(w io.Writer, filters repository.TaskFilters, flush func()) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(TaskCsvHeader); err != nil {
+		return err
+	}
+
+	rows := 0
+	err := cs.taskRepository.EachWithRelations(filters, func(task *repository.Task) error {
+		if err := writer.Write(taskCsvRecord(task)); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%100 == 0 {
+			writer.Flush()
+			flush()
+		}
+		return writer.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	flush()
+	return writer.Error()
+}
+
+func taskCsvRecord(task *repository.Task) []string {
+	labels := make([]string, len(task.Labels))
+	for i, label := range task.Labels {
+		labels[i] = label.Name
+	}
+
+	record := []string{
+		strconv.Itoa(task.ID),
+		task.Title,
+		"",
+		strconv.Itoa(task.ColumnID),
+		"",
+		"",
+		"",
+		csvTime(task.DueDate),
+		strings.Join(labels, ", "),
+		"",
+		"",
+		task.CreatedAt.UTC().Format(time.RFC3339),
+		task.UpdatedAt.UTC().Format(time.RFC3339),
+		csvTime(task.ArchivedAt),
+	}
+
+	if task.Description != nil {
+		record[2] = *task.Description
+	}
+	if task.ColumnTitle != nil {
+		record[4] = *task.ColumnTitle
+	}
+	if task.AssignedUser != nil {
+		record[5] = task.AssignedUser.UserName
+	}
+	if task.Priority != nil {
+		record[6] = *task.Priority
+	}
+	if task.ParentID != nil {
+		record[9] = strconv.Itoa(*task.ParentID)
+	}
+	if task.CreatedByUser != nil {
+		record[10] = task.CreatedByUser.UserName
+	}
+
+	return record
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Import creates a task for every row of a CSV file, mapping the columns by header name. Every row is
+// validated first, with any row error nothing is created. A dry run only validates
+func (cs *TaskCsvService) Import(r io.Reader, options CsvImportOptions) (*CsvImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, &CsvImportError{Message: "the file is empty"}
+	}
+	if err != nil {
+		return nil, &CsvImportError{Message: "invalid CSV: " + err.Error()}
+	}
+
+	result := &CsvImportResult{
+		DryRun:         options.DryRun,
+		TaskIDs:        make([]int, 0),
+		IgnoredColumns: make([]string, 0),
+		Errors:         make([]CsvRowError, 0),
+	}
+
+	// Field name to the index of its column in the file
+	fields := make(map[string]int)
+	for i, name := range header {
+		// Spreadsheet apps put a byte order mark in front of the first header
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.ReplaceAll(name, " ", "_")
+
+		field, ok := csvImportFields[name]
+		if !ok {
+			result.IgnoredColumns = append(result.IgnoredColumns, header[i])
+			continue
+		}
+		if _, seen := fields[field]; !seen {
+			fields[field] = i
+		}
+	}
+
+	if _, ok := fields["title"]; !ok {
+		return nil, &CsvImportError{Message: "the header has no title column"}
+	}
+
+	resolver := &csvResolver{service: cs, options: options,
+		columns: make(map[string]csvLookup), users: make(map[string]csvLookup)}
+
+	var rows []*csvTask
+	incoming := make(map[int][]int) // Column ID to the rows going into it
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &CsvImportError{Message: "invalid CSV: " + err.Error()}
+		}
+
+		// Quoted values can span lines, the row is the line it starts on
+		line, _ := reader.FieldPos(0)
+
+		if isBlankRecord(record) {
+			continue
+		}
+
+		result.Rows++
+		if result.Rows > MaxCsvImportRows {
+			return nil, &CsvImportError{Message: fmt.Sprintf("the file has more than %d rows", MaxCsvImportRows)}
+		}
+
+		value := func(field string) string {
+			if i, ok := fields[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		task, rowErrors := resolver.resolve(line, value)
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			continue
+		}
+
+		rows = append(rows, task)
+		incoming[task.columnID] = append(incoming[task.columnID], task.row)
+	}
+
+	// The rows of a column count against its WIP limit together
+	if !options.OverrideWipLimit {
+		for columnID, columnRows := range incoming {
+			if err := cs.columnRepository.CheckWipLimit(columnID, len(columnRows)); err != nil {
+				var wipErr *repository.WipLimitError
+				if !errors.As(err, &wipErr) {
+					return nil, err
+				}
+				result.Errors = append(result.Errors, CsvRowError{Row: columnRows[0], Field: "column",
+					Message: fmt.Sprintf("%s, the file adds %d more", wipErr.Message, len(columnRows))})
+			}
+		}
+	}
+
+	slices.SortStableFunc(result.Errors, func(a, b CsvRowError) int {
+		return a.Row - b.Row
+	})
+
+	result.Valid = len(rows)
+	if len(result.Errors) > 0 || options.DryRun {
+		return result, nil
+	}
+
+	err = cs.db.Transaction(func(tx *database.Database) error {
+		txService := NewTaskService(tx)
+
+		for _, row := range rows {
+			// WIP limits were checked for all rows at once above
+			task, err := txService.CreateTask(row.title, row.description, row.columnID, options.UserID,
+				row.assignedTo, row.dueDate, row.priority, true)
+			if err != nil {
+				return fmt.Errorf("failed to create the task of row %d: %w", row.row, err)
+			}
+			result.TaskIDs = append(result.TaskIDs, task.ID)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Created = len(result.TaskIDs)
+	return result, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// csvLookup is a cached column or user lookup, error is the row error for values that don't resolve
+type csvLookup struct {
+	id    int
+	error string
+}
+
+// csvResolver turns rows into tasks, looking each column and assignee up once per file
+type csvResolver struct {
+	service *TaskCsvService
+	options CsvImportOptions
+	columns map[string]csvLookup
+	users   map[string]csvLookup
+}
+
+func (cr *csvResolver) resolve(line int, value func(field string) string) (*csvTask, []CsvRowError) {
+	task := &csvTask{row: line, title: value("title"), description: value("description")}
+	var rowErrors []CsvRowError
+
+	fail := func(field, message string) {
+		rowErrors = append(rowErrors, CsvRowError{Row: line, Field: field, Message: message})
+	}
+
+	// Same limits as creating a task through the API
+	switch length := utf8.RuneCountInString(task.title); {
+	case length == 0:
+		fail("title", "title is required")
+	case length < 3 || length > 255:
+		fail("title", "title must be between 3 and 255 characters")
+	}
+
+	if utf8.RuneCountInString(task.description) > 10000 {
+		fail("description", "description must be at most 10000 characters")
+	}
+
+	// Column by ID, then by title, then the default of the import
+	if columnID, column := value("column_id"), value("column"); columnID != "" {
+		if lookup := cr.columnByID(columnID); lookup.error != "" {
+			fail("column_id", lookup.error)
+		} else {
+			task.columnID = lookup.id
+		}
+	} else if column != "" {
+		lookup := cr.columnByTitle(column)
+		if _, err := strconv.Atoi(column); lookup.error != "" && err == nil {
+			// Numbers no column is named after are IDs
+			if byID := cr.columnByID(column); byID.error == "" {
+				lookup = byID
+			}
+		}
+		if lookup.error != "" {
+			fail("column", lookup.error)
+		} else {
+			task.columnID = lookup.id
+		}
+	} else if cr.options.ColumnID != nil {
+		task.columnID = *cr.options.ColumnID
+	} else {
+		fail("column", "column is required")
+	}
+
+	if assignee := value("assignee"); assignee != "" {
+		if lookup := cr.user(assignee); lookup.error != "" {
+			fail("assignee", lookup.error)
+		} else {
+			task.assignedTo = &lookup.id
+		}
+	}
+
+	task.priority = strings.ToLower(value("priority"))
+	if task.priority == "" {
+		task.priority = "medium"
+	} else if !slices.Contains(csvPriorities, task.priority) {
+		fail("priority", "priority must be one of low, medium, high or urgent")
+	}
+
+	if dueDate := value("due_date"); dueDate != "" {
+		parsed, err := parseCsvDate(dueDate)
+		if err != nil {
+			fail("due_date", "due date must be a date like 2024-01-15 or an ISO 8601 time")
+		} else {
+			task.dueDate = &parsed
+		}
+	}
+
+	if len(rowErrors) > 0 {
+		return nil, rowErrors
+	}
+	return task, nil
+}
+
+func (cr *csvResolver) columnByID(value string) csvLookup {
+	key := "id:" + value
+	if lookup, ok := cr.columns[key]; ok {
+		return lookup
+	}
+
+	lookup := csvLookup{error: fmt.Sprintf("column %s not found", value)}
+	if id, err := strconv.Atoi(value); err == nil && id > 0 {
+		column, err := cr.service.columnRepository.FindByID(id)
+		if err == nil && column.DeletedAt.Valid {
+			lookup.error = fmt.Sprintf("column %d is archived", id)
+		} else if err == nil {
+			lookup = csvLookup{id: column.ID}
+		}
+	}
+
+	cr.columns[key] = lookup
+	return lookup
+}
+
+func (cr *csvResolver) columnByTitle(title string) csvLookup {
+	key := "title:" + strings.ToLower(title)
+	if lookup, ok := cr.columns[key]; ok {
+		return lookup
+	}
+
+	var lookup csvLookup
+	columns, err := cr.service.columnRepository.FindByTitle(title, cr.options.BoardID)
+	switch {
+	case err != nil:
+		lookup.error = err.Error()
+	case len(columns) == 0:
+		lookup.error = fmt.Sprintf("column %q not found", title)
+	case len(columns) > 1:
+		lookup.error = fmt.Sprintf("column %q is on more than one board, use column_id or import into one board", title)
+	default:
+		lookup.id = columns[0].ID
+	}
+
+	cr.columns[key] = lookup
+	return lookup
+}
+
+// user finds an active user by username, or by ID for numeric values no username matches
+func (cr *csvResolver) user(value string) csvLookup {
+	if lookup, ok := cr.users[value]; ok {
+		return lookup
+	}
+
+	lookup := csvLookup{error: fmt.Sprintf("user %q not found", value)}
+	if user, err := cr.service.userRepository.FindByUsername(value); err == nil {
+		lookup = csvLookup{id: user.ID}
+	} else if id, err := strconv.Atoi(value); err == nil && id > 0 {
+		if user, err := cr.service.userRepository.FindByID(id); err == nil && user.IsActive {
+			lookup = csvLookup{id: user.ID}
+		}
+	}
+
+	cr.users[value] = lookup
+	return lookup
+}
+
+// parseCsvDate reads the dates spreadsheets write, a plain date is the start of that day in UTC
+func parseCsvDate(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
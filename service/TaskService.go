@@ -1,31 +1,58 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/events"
 	"github.com/dev-parvej/offline_kanban/repository"
 )
 
 type TaskService struct {
-	taskRepository     *repository.TaskRepository
-	userRepository     *repository.UserRepository
-	columnRepository   *repository.ColumnRepository
-	activityRepository *repository.ActivityRepository
+	taskRepository       *repository.TaskRepository
+	userRepository       *repository.UserRepository
+	columnRepository     *repository.ColumnRepository
+	activityRepository   *repository.ActivityRepository
+	checklistRepository  *repository.ChecklistRepository
+	labelRepository      *repository.LabelRepository
+	templateRepository   *repository.TaskTemplateRepository
+	dependencyRepository *repository.TaskDependencyRepository
+	settingsRepository   *repository.SettingsRepository
+	notificationService  *NotificationService
+	mentionService       *MentionService
+	db                   *database.Database
 }
 
 func NewTaskService(db *database.Database) *TaskService {
 	return &TaskService{
-		taskRepository:     repository.NewTaskRepository(db),
-		userRepository:     repository.NewUserRepository(db),
-		columnRepository:   repository.NewColumnRepository(db),
-		activityRepository: repository.NewActivityRepository(db),
+		taskRepository:       repository.NewTaskRepository(db),
+		userRepository:       repository.NewUserRepository(db),
+		columnRepository:     repository.NewColumnRepository(db),
+		activityRepository:   repository.NewActivityRepository(db),
+		checklistRepository:  repository.NewChecklistRepository(db),
+		labelRepository:      repository.NewLabelRepository(db),
+		templateRepository:   repository.NewTaskTemplateRepository(db),
+		dependencyRepository: repository.NewTaskDependencyRepository(db),
+		settingsRepository:   repository.NewSettingsRepository(db),
+		notificationService:  NewNotificationService(db),
+		mentionService:       NewMentionService(db),
+		db:                   db,
 	}
 }
 
-// CreateTask creates a new task and records the activity
-func (ts *TaskService) CreateTask(title, description string, columnID, userID int, assignedTo *int, dueDate *time.Time, priority string) (*repository.Task, error) {
+// CreateTask creates a new task and records the activity, refusing it when the column is at its WIP limit
+func (ts *TaskService) CreateTask(title, description string, columnID, userID int, assignedTo *int, dueDate *time.Time, priority string, overrideWipLimit bool) (*repository.Task, error) {
+	if !overrideWipLimit {
+		if err := ts.columnRepository.CheckWipLimit(columnID, 1); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create the task
 	task, err := ts.taskRepository.Create(title, &description, columnID, userID, assignedTo, dueDate, &priority)
 	if err != nil {
@@ -39,11 +66,57 @@ func (ts *TaskService) CreateTask(title, description string, columnID, userID in
 		fmt.Printf("Failed to record task creation activity: %v\n", err)
 	}
 
+	ts.notificationService.NotifyTaskAssignment(task, nil, userID)
+	ts.mentionService.ProcessDescription(task, nil, userID)
+
+	ts.publish(events.TaskCreated, task.ID, userID, task)
+
+	return task, nil
+}
+
+// CreateTaskFromTemplate creates a task with the template's checklist and labels in one go and records the
+// activity, refusing it when the column is at its WIP limit. Values fill the custom placeholders of the title
+func (ts *TaskService) CreateTaskFromTemplate(template *repository.TaskTemplate, values map[string]string, columnID, userID int,
+	assignedTo *int, dueDate *time.Time, overrideWipLimit bool) (*repository.Task, error) {
+
+	if !overrideWipLimit {
+		if err := ts.columnRepository.CheckWipLimit(columnID, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	title := renderTemplateTitle(template.TitlePattern, template.UseCount+1, values)
+
+	task, err := ts.taskRepository.CreateWithChecklist(title, template.Description, columnID, userID, assignedTo,
+		dueDate, template.Priority, template.ChecklistItems, template.LabelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ts.templateRepository.IncrementUseCount(template.ID); err != nil {
+		fmt.Printf("Failed to count use of task template %d: %v\n", template.ID, err)
+	}
+
+	err = ts.activityRepository.RecordTaskCreated(task.ID, userID, task.Title)
+	if err != nil {
+		fmt.Printf("Failed to record task creation activity: %v\n", err)
+	}
+
+	err = ts.activityRepository.RecordTaskGenerated(task.ID, userID, template.Name)
+	if err != nil {
+		fmt.Printf("Failed to record task generated activity: %v\n", err)
+	}
+
+	ts.notificationService.NotifyTaskAssignment(task, nil, userID)
+	ts.mentionService.ProcessDescription(task, nil, userID)
+
+	ts.publish(events.TaskCreated, task.ID, userID, task)
+
 	return task, nil
 }
 
 // UpdateTask updates a task and records field changes
-func (ts *TaskService) UpdateTask(taskID, userID int, title, description *string, assignedTo *int, dueDate *time.Time, priority *string, columnID *int) (*repository.Task, error) {
+func (ts *TaskService) UpdateTask(taskID, userID int, title, description *string, assignedTo *int, dueDate *time.Time, priority *string, columnID *int, overrideWipLimit bool) (*repository.Task, error) {
 	// Get existing task for comparison
 	existingTask, err := ts.taskRepository.FindByID(taskID)
 	if err != nil {
@@ -52,7 +125,7 @@ func (ts *TaskService) UpdateTask(taskID, userID int, title, description *string
 
 	// Handle column update if provided
 	if columnID != nil && existingTask.ColumnID != *columnID {
-		err = ts.moveTaskToColumn(taskID, *columnID, userID)
+		err = ts.moveTaskToColumn(taskID, *columnID, userID, overrideWipLimit)
 		if err != nil {
 			return nil, err
 		}
@@ -68,17 +141,52 @@ func (ts *TaskService) UpdateTask(taskID, userID int, title, description *string
 	}
 
 	// Record field change activities
-	for _, change := range fieldChanges {
-		err = ts.activityRepository.RecordTaskUpdate(taskID, userID, change.field, change.oldValue, change.newValue)
+	ts.recordFieldChanges(taskID, userID, fieldChanges)
+
+	ts.notificationService.NotifyTaskAssignment(task, existingTask.AssignedTo, userID)
+	ts.mentionService.ProcessDescription(task, existingTask.Description, userID)
+
+	ts.publish(events.TaskUpdated, taskID, userID, task)
+
+	return task, nil
+}
+
+// SetTaskLabels replaces the labels of a task and records the change
+func (ts *TaskService) SetTaskLabels(taskID int, labelIDs []int, userID int) (*repository.Task, error) {
+	task, err := ts.taskRepository.FindByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	oldLabels, err := ts.labelRepository.GetByTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ts.labelRepository.SetTaskLabels(taskID, labelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ts.taskRepository.LoadLabels(task); err != nil {
+		return nil, err
+	}
+
+	oldNames := labelNames(oldLabels)
+	newNames := labelNames(task.Labels)
+	if oldNames != newNames {
+		err = ts.activityRepository.RecordTaskUpdate(taskID, userID, "labels", oldNames, newNames)
 		if err != nil {
-			fmt.Printf("Failed to record task update activity for %s: %v\n", change.field, err)
+			fmt.Printf("Failed to record task update activity for labels: %v\n", err)
 		}
+
+		ts.publish(events.TaskUpdated, taskID, userID, task)
 	}
 
 	return task, nil
 }
 
-// DeleteTask deletes a task and records the activity
+// DeleteTask moves a task and its subtasks to the trash and records the activity
 func (ts *TaskService) DeleteTask(taskID, userID int) error {
 	// Get task before deletion for activity tracking
 	task, err := ts.taskRepository.FindByID(taskID)
@@ -86,8 +194,11 @@ func (ts *TaskService) DeleteTask(taskID, userID int) error {
 		return err
 	}
 
-	// Delete the task
-	err = ts.taskRepository.Delete(taskID)
+	// Resolve the board while the task still exists
+	boardID := ts.boardIDOf(taskID)
+
+	// Move the task to the trash
+	err = ts.taskRepository.Trash(taskID, userID)
 	if err != nil {
 		return err
 	}
@@ -98,11 +209,421 @@ func (ts *TaskService) DeleteTask(taskID, userID int) error {
 		fmt.Printf("Failed to record task deletion activity: %v\n", err)
 	}
 
+	ts.db.AfterCommit(func() {
+		events.Publish(events.Event{
+			Type:    events.TaskDeleted,
+			BoardID: boardID,
+			TaskID:  &taskID,
+			UserID:  userID,
+			Data:    task,
+		})
+	})
+
+	return nil
+}
+
+// RestoreTask takes a task and the subtasks trashed with it out of the trash and records the activity.
+// A subtask whose parent is still in the trash can't be restored on its own
+func (ts *TaskService) RestoreTask(taskID int, columnID *int, userID int) (*repository.Task, error) {
+	trashedTask, err := ts.taskRepository.FindTrashedByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if trashedTask.ParentID != nil {
+		if _, err := ts.taskRepository.FindTrashedByID(*trashedTask.ParentID); err == nil {
+			return nil, fmt.Errorf("parent task #%d is in the trash, restore it first", *trashedTask.ParentID)
+		}
+	}
+
+	// Columns are deleted once only trashed tasks are left in them
+	trashedIDs, err := ts.taskRepository.GetTrashedWith(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	withoutColumn, err := ts.taskRepository.GetWithoutActiveColumn(trashedIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(withoutColumn) == 0 {
+		columnID = nil
+	} else if columnID == nil {
+		return nil, ErrRestoreColumnGone
+	} else if column, err := ts.columnRepository.FindByID(*columnID); err != nil || column.DeletedAt.Valid {
+		return nil, errors.New("column not found")
+	}
+
+	err = ts.taskRepository.Restore(taskID, columnID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ts.activityRepository.RecordTaskRestored(taskID, userID, trashedTask.Title)
+	if err != nil {
+		fmt.Printf("Failed to record task restore activity: %v\n", err)
+	}
+
+	task, err := ts.taskRepository.FindByIDWithRelation(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.publish(events.TaskRestored, taskID, userID, task)
+
+	return task, nil
+}
+
+// ErrRestoreColumnGone is returned when a trashed task is restored without a column to replace its deleted one
+var ErrRestoreColumnGone = errors.New("the task's column was deleted or archived, choose a column to restore it to")
+
+// PurgeTask permanently deletes a trashed task, along with the subtasks trashed with it, and records the activity
+func (ts *TaskService) PurgeTask(taskID, userID int) error {
+	task, err := ts.taskRepository.FindTrashedByID(taskID)
+	if err != nil {
+		return err
+	}
+
+	taskIDs, err := ts.taskRepository.GetTrashedWith(taskID)
+	if err != nil {
+		return err
+	}
+
+	if err := ts.purge(taskIDs); err != nil {
+		return err
+	}
+
+	err = ts.activityRepository.RecordTaskPurged(taskID, userID, task.Title)
+	if err != nil {
+		fmt.Printf("Failed to record task purge activity: %v\n", err)
+	}
+
 	return nil
 }
 
-// moveTaskToColumn handles moving a task to a different column
-func (ts *TaskService) moveTaskToColumn(taskID, newColumnID, userID int) error {
+// PurgeTrash permanently deletes every task in the trash, or only the ones trashed more than olderThanDays
+// days ago when it is positive, and returns how many were deleted
+func (ts *TaskService) PurgeTrash(olderThanDays int) (int, error) {
+	taskIDs, err := ts.taskRepository.GetTrashedBefore(olderThanDays)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := ts.purge(taskIDs); err != nil {
+		return 0, err
+	}
+
+	return len(taskIDs), nil
+}
+
+// purge deletes trashed tasks for good in one transaction
+func (ts *TaskService) purge(taskIDs []int) error {
+	return ts.db.Transaction(func(tx *database.Database) error {
+		taskRepository := repository.NewTaskRepository(tx)
+		for _, taskID := range taskIDs {
+			if err := taskRepository.Delete(taskID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ArchiveTask takes a task off the board and records the activity
+func (ts *TaskService) ArchiveTask(taskID, userID int) (*repository.Task, error) {
+	if err := ts.taskRepository.Archive(taskID); err != nil {
+		return nil, err
+	}
+
+	task, err := ts.taskRepository.FindByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ts.activityRepository.RecordTaskArchived(taskID, userID, task.Title)
+	if err != nil {
+		fmt.Printf("Failed to record task archive activity: %v\n", err)
+	}
+
+	ts.publish(events.TaskArchived, taskID, userID, task)
+
+	return task, nil
+}
+
+// UnarchiveTask puts an archived task back at the end of its column and records the activity,
+// respecting the column's WIP limit unless overridden
+func (ts *TaskService) UnarchiveTask(taskID, userID int, overrideWipLimit bool) (*repository.Task, error) {
+	task, err := ts.taskRepository.FindByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.ArchivedAt == nil {
+		return nil, errors.New("task is not archived")
+	}
+
+	if !overrideWipLimit {
+		if err := ts.columnRepository.CheckWipLimit(task.ColumnID, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ts.taskRepository.Unarchive(taskID); err != nil {
+		return nil, err
+	}
+
+	err = ts.activityRepository.RecordTaskUnarchived(taskID, userID, task.Title)
+	if err != nil {
+		fmt.Printf("Failed to record task unarchive activity: %v\n", err)
+	}
+
+	task, err = ts.taskRepository.FindByIDWithRelation(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.publish(events.TaskUnarchived, taskID, userID, task)
+
+	return task, nil
+}
+
+// AutoArchive archives the tasks that have been done for longer than their column's threshold,
+// or defaultDays, and returns how many it archived. There is no user behind it to record
+func (ts *TaskService) AutoArchive(defaultDays int) (int, error) {
+	taskIDs, err := ts.taskRepository.GetArchivable(defaultDays)
+	if err != nil {
+		return 0, err
+	}
+
+	archived := 0
+	for _, taskID := range taskIDs {
+		if err := ts.taskRepository.Archive(taskID); err != nil {
+			fmt.Printf("Failed to archive task %d: %v\n", taskID, err)
+			continue
+		}
+		archived++
+
+		if task, err := ts.taskRepository.FindByID(taskID); err == nil {
+			ts.publish(events.TaskArchived, taskID, 0, task)
+		}
+	}
+
+	return archived, nil
+}
+
+// Task fields whose changes can be reverted from the activity log
+var revertibleFields = []string{"title", "description", "priority", "assignee", "column"}
+
+// ErrNothingToUndo is returned when a user has no change left that can be undone
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// RevertConflictError is returned when the field an activity changed no longer holds the value it set
+type RevertConflictError struct {
+	Message    string `json:"message"`
+	ActivityID int    `json:"activity_id"`
+	ChangedBy  *int   `json:"changed_by_activity_id,omitempty"` // The later change, when the activity log has it
+}
+
+func (e *RevertConflictError) Error() string {
+	return e.Message
+}
+
+// RevertActivity applies the inverse of a task change from the activity log and returns the reverted
+// activity it records. It refuses when the field has changed again since
+func (ts *TaskService) RevertActivity(activityID, userID int) (*repository.Activity, error) {
+	activity, err := ts.activityRepository.FindByID(activityID)
+	if err != nil {
+		return nil, errors.New("activity not found")
+	}
+
+	task, err := ts.revertibleTask(activity)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.revert(task, activity, userID)
+}
+
+// UndoLastChange reverts the latest change the user made to a task that nothing has changed since,
+// so undoing repeatedly walks back through the user's changes. It returns the undone activity and the revert
+func (ts *TaskService) UndoLastChange(userID int) (*repository.Activity, *repository.Activity, error) {
+	changes, err := ts.activityRepository.GetTaskChangesByUser(userID, []string{"updated", "moved"}, revertibleFields, 50)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, activity := range changes {
+		task, err := ts.revertibleTask(activity)
+		if err != nil {
+			continue
+		}
+
+		revert, err := ts.revert(task, activity, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return activity, revert, nil
+	}
+
+	return nil, nil, ErrNothingToUndo
+}
+
+// revertibleTask checks that an activity is a task change that can still be reverted and returns the task
+func (ts *TaskService) revertibleTask(activity *repository.Activity) (*repository.Task, error) {
+	if activity.EntityType != "task" || activity.FieldName == nil || activity.NewValue == nil ||
+		(activity.Action != "updated" && activity.Action != "moved" && activity.Action != "reverted") {
+		return nil, errors.New("this activity can't be reverted")
+	}
+
+	field := *activity.FieldName
+	if !slices.Contains(revertibleFields, field) {
+		return nil, fmt.Errorf("changes to the %s can't be reverted", field)
+	}
+
+	// Older activities only hold names for these
+	if (field == "assignee" || field == "column") && (activity.OldRef == nil || activity.NewRef == nil) {
+		return nil, errors.New("this change was recorded before it could be reverted")
+	}
+
+	task, err := ts.taskRepository.FindByID(activity.EntityID)
+	if err != nil {
+		return nil, err
+	}
+
+	laterChange, err := ts.activityRepository.FindLaterChange(activity)
+	if err != nil {
+		return nil, err
+	}
+	if laterChange != nil {
+		return nil, &RevertConflictError{
+			Message:    fmt.Sprintf("The %s has changed again since", field),
+			ActivityID: activity.ID,
+			ChangedBy:  &laterChange.ID,
+		}
+	}
+
+	// Changes that skipped the activity log
+	if !holdsValue(task, field, *activity.NewValue, activity.NewRef) {
+		return nil, &RevertConflictError{
+			Message:    fmt.Sprintf("The %s no longer has the value this change set", field),
+			ActivityID: activity.ID,
+		}
+	}
+
+	return task, nil
+}
+
+// holdsValue reports whether a task field still has the value an activity recorded
+func holdsValue(task *repository.Task, field, value string, ref *int) bool {
+	switch field {
+	case "title":
+		return task.Title == value
+	case "description":
+		if value == "(empty)" {
+			value = ""
+		}
+		return (task.Description == nil && value == "") || (task.Description != nil && *task.Description == value)
+	case "priority":
+		return (task.Priority == nil && value == "") || (task.Priority != nil && *task.Priority == value)
+	case "assignee":
+		return (task.AssignedTo == nil && *ref == 0) || (task.AssignedTo != nil && *task.AssignedTo == *ref)
+	case "column":
+		return task.ColumnID == *ref
+	}
+	return false
+}
+
+// revert sets a task field back to the old value of an activity and returns the reverted activity recorded for it
+func (ts *TaskService) revert(task *repository.Task, activity *repository.Activity, userID int) (*repository.Activity, error) {
+	field := *activity.FieldName
+	oldValue := ""
+	if activity.OldValue != nil {
+		oldValue = *activity.OldValue
+	}
+
+	if field == "column" {
+		// The WIP limit and blockers of the column apply as for any move
+		if err := ts.move(task.ID, *activity.OldRef, nil, userID, false, true); err != nil {
+			return nil, err
+		}
+		return ts.revertOf(activity)
+	}
+
+	var title, description, priority *string
+	var assignee *int
+	assignedTo := task.AssignedTo
+
+	switch field {
+	case "title":
+		title = &oldValue
+	case "description":
+		if oldValue == "(empty)" {
+			oldValue = ""
+		}
+		description = &oldValue
+	case "priority":
+		if oldValue == "" {
+			return nil, errors.New("the task had no priority before this change")
+		}
+		priority = &oldValue
+	case "assignee":
+		oldAssigneeID := *activity.OldRef
+		assignee = &oldAssigneeID
+		assignedTo = nil
+		if oldAssigneeID > 0 {
+			if _, err := ts.userRepository.FindByID(oldAssigneeID); err != nil {
+				return nil, errors.New("the previous assignee no longer exists")
+			}
+			assignedTo = &oldAssigneeID
+		}
+	}
+
+	changes := ts.trackFieldChanges(task, title, description, priority, assignee)
+
+	updatedTask, err := ts.taskRepository.Update(task.ID, title, description, assignedTo, task.DueDate, priority)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		err = ts.activityRepository.RecordTaskReverted(task.ID, userID, change.field, change.oldValue, change.newValue,
+			change.oldRef, change.newRef)
+		if err != nil {
+			fmt.Printf("Failed to record task revert activity for %s: %v\n", change.field, err)
+		}
+	}
+
+	ts.notificationService.NotifyTaskAssignment(updatedTask, task.AssignedTo, userID)
+	ts.mentionService.ProcessDescription(updatedTask, task.Description, userID)
+
+	ts.publish(events.TaskUpdated, task.ID, userID, updatedTask)
+
+	return ts.revertOf(activity)
+}
+
+// revertOf finds the reverted activity recorded right after undoing an activity
+func (ts *TaskService) revertOf(activity *repository.Activity) (*repository.Activity, error) {
+	revert, err := ts.activityRepository.FindLaterChange(activity)
+	if err == nil && revert == nil {
+		err = errors.New("the change was reverted but could not be recorded")
+	}
+	return revert, err
+}
+
+// moveTaskToColumn handles moving a task to the end of a different column
+func (ts *TaskService) moveTaskToColumn(taskID, newColumnID, userID int, overrideWipLimit bool) error {
+	return ts.MoveTask(taskID, newColumnID, nil, userID, overrideWipLimit)
+}
+
+// MoveTask moves a task to the given position, or the end of the column when none is given,
+// and records the activity. Moving into another column respects its WIP limit unless overridden
+func (ts *TaskService) MoveTask(taskID, newColumnID int, newPosition *int, userID int, overrideWipLimit bool) error {
+	return ts.move(taskID, newColumnID, newPosition, userID, overrideWipLimit, false)
+}
+
+// move moves a task like MoveTask, recording a reverted activity instead of a move when undoing one
+func (ts *TaskService) move(taskID, newColumnID int, newPosition *int, userID int, overrideWipLimit, revert bool) error {
 	// Get existing task
 	existingTask, err := ts.taskRepository.FindByID(taskID)
 	if err != nil {
@@ -111,28 +632,440 @@ func (ts *TaskService) moveTaskToColumn(taskID, newColumnID, userID int) error {
 
 	// Get old and new column names for activity tracking
 	oldColumn, _ := ts.columnRepository.FindByID(existingTask.ColumnID)
-	newColumn, _ := ts.columnRepository.FindByID(newColumnID)
+	newColumn, err := ts.columnRepository.FindByID(newColumnID)
+	if err != nil {
+		return err
+	}
+
+	// Tasks stay on their board
+	if oldColumn != nil && oldColumn.BoardID != newColumn.BoardID {
+		return errors.New("task cannot be moved to a column of another board")
+	}
+
+	// Reordering inside a column never changes its size
+	if !overrideWipLimit && existingTask.ColumnID != newColumnID {
+		if err := ts.columnRepository.CheckWipLimit(newColumnID, 1); err != nil {
+			return err
+		}
+	}
+
+	if newColumn.IsDone && existingTask.ColumnID != newColumnID {
+		if err := ts.checkBlockers(existingTask, newColumn); err != nil {
+			return err
+		}
+	}
 
 	// Get task count for the target column to position at the end
-	col, _ := ts.columnRepository.GetTaskCount(newColumnID)
+	position, _ := ts.columnRepository.GetTaskCount(newColumnID)
+	if newPosition != nil {
+		position = *newPosition
+	}
 
 	// Move task to new column
-	err = ts.taskRepository.MoveToColumn(taskID, newColumnID, col)
+	err = ts.taskRepository.MoveToColumn(taskID, newColumnID, position)
 	if err != nil {
 		return err
 	}
 
 	// Record column move activity
-	if oldColumn != nil && newColumn != nil {
-		err = ts.activityRepository.RecordTaskMoved(taskID, userID, oldColumn.Title, newColumn.Title)
+	if oldColumn != nil && existingTask.ColumnID != newColumnID {
+		if revert {
+			err = ts.activityRepository.RecordTaskReverted(taskID, userID, "column", oldColumn.Title,
+				newColumn.Title, &oldColumn.ID, &newColumn.ID)
+		} else {
+			err = ts.activityRepository.RecordTaskMoved(taskID, userID, oldColumn.ID, newColumn.ID, oldColumn.Title, newColumn.Title)
+		}
 		if err != nil {
 			fmt.Printf("Failed to record task move activity: %v\n", err)
 		}
+
+		ts.notificationService.NotifyTaskMoved(existingTask, oldColumn.Title, newColumn.Title, userID)
+	}
+
+	ts.publish(events.TaskMoved, taskID, userID, map[string]int{
+		"from_column_id": existingTask.ColumnID,
+		"to_column_id":   newColumnID,
+		"position":       position,
+	})
+
+	return nil
+}
+
+// AddDependency marks a task as blocked by another task and records the change
+func (ts *TaskService) AddDependency(taskID, blockedByTaskID, userID int) (*repository.Task, error) {
+	blocker, err := ts.taskRepository.FindByID(blockedByTaskID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ts.dependencyRepository.Create(taskID, blockedByTaskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ts.activityRepository.RecordTaskUpdate(taskID, userID, "blocked_by", "", taskName(blocker))
+	if err != nil {
+		fmt.Printf("Failed to record task update activity for blocked_by: %v\n", err)
+	}
+
+	return ts.publishDependencyChange(taskID, blockedByTaskID, userID)
+}
+
+// RemoveDependency unblocks a task from another task and records the change
+func (ts *TaskService) RemoveDependency(taskID, blockedByTaskID, userID int) (*repository.Task, error) {
+	err := ts.dependencyRepository.Delete(taskID, blockedByTaskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if blocker, err := ts.taskRepository.FindByID(blockedByTaskID); err == nil {
+		err = ts.activityRepository.RecordTaskUpdate(taskID, userID, "blocked_by", taskName(blocker), "")
+		if err != nil {
+			fmt.Printf("Failed to record task update activity for blocked_by: %v\n", err)
+		}
+	}
+
+	return ts.publishDependencyChange(taskID, blockedByTaskID, userID)
+}
+
+// SetParent attaches a task to a parent task, or detaches it when parentID is nil, and records the change
+func (ts *TaskService) SetParent(taskID int, parentID *int, userID int) (*repository.Task, error) {
+	existingTask, err := ts.taskRepository.FindByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := ts.taskRepository.SetParent(taskID, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	oldParent := ts.parentName(existingTask.ParentID)
+	newParent := ts.parentName(parentID)
+	if oldParent != newParent {
+		err = ts.activityRepository.RecordTaskUpdate(taskID, userID, "parent", oldParent, newParent)
+		if err != nil {
+			fmt.Printf("Failed to record task update activity for parent: %v\n", err)
+		}
+	}
+
+	if err := ts.taskRepository.LoadSubtaskProgress(task); err != nil {
+		return nil, err
+	}
+
+	ts.publish(events.TaskUpdated, taskID, userID, task)
+
+	// Both parents show a different roll-up now
+	for _, id := range []*int{existingTask.ParentID, parentID} {
+		if id == nil {
+			continue
+		}
+		if parent, err := ts.taskRepository.FindByID(*id); err == nil {
+			if err := ts.taskRepository.LoadSubtaskProgress(parent); err == nil {
+				ts.publish(events.TaskUpdated, parent.ID, userID, parent)
+			}
+		}
+	}
+
+	return task, nil
+}
+
+// checkBlockers refuses a move into a done column while the task still waits on unfinished tasks,
+// when the app settings ask for dependencies to be enforced
+func (ts *TaskService) checkBlockers(task *repository.Task, doneColumn *repository.Column) error {
+	settings, err := ts.settingsRepository.GetSettings()
+	if err != nil || !settings.EnforceDependencies {
+		return nil
+	}
+
+	openBlockers, err := ts.dependencyRepository.GetOpenBlockers(task.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(openBlockers) == 0 {
+		return nil
+	}
+
+	return &repository.BlockedTaskError{
+		Message:   fmt.Sprintf("Task '%s' is blocked by %d unfinished task(s) and cannot be moved to '%s'", task.Title, len(openBlockers), doneColumn.Title),
+		TaskID:    task.ID,
+		ColumnID:  doneColumn.ID,
+		BlockedBy: openBlockers,
+	}
+}
+
+// CheckColumnMoveBlockers refuses moving every task of a column into a done column while one of them
+// waits on an unfinished task that is not moved along with it, when dependencies are enforced
+func (ts *TaskService) CheckColumnMoveBlockers(fromColumnID int, doneColumn *repository.Column) error {
+	if !doneColumn.IsDone {
+		return nil
+	}
+
+	settings, err := ts.settingsRepository.GetSettings()
+	if err != nil || !settings.EnforceDependencies {
+		return nil
+	}
+
+	tasks, err := ts.taskRepository.GetByColumn(fromColumnID)
+	if err != nil {
+		return err
+	}
+
+	moved := make(map[int]bool, len(tasks))
+	taskIDs := make([]int, 0, len(tasks))
+	for _, task := range tasks {
+		moved[task.ID] = true
+		taskIDs = append(taskIDs, task.ID)
+	}
+
+	blockedBy, err := ts.dependencyRepository.GetBlockedBy(taskIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		var openBlockers []*repository.TaskDependency
+		for _, blocker := range blockedBy[task.ID] {
+			if !blocker.IsDone && !moved[blocker.TaskID] {
+				openBlockers = append(openBlockers, blocker)
+			}
+		}
+
+		if len(openBlockers) > 0 {
+			return &repository.BlockedTaskError{
+				Message:   fmt.Sprintf("Task '%s' is blocked by %d unfinished task(s) and cannot be moved to '%s'", task.Title, len(openBlockers), doneColumn.Title),
+				TaskID:    task.ID,
+				ColumnID:  doneColumn.ID,
+				BlockedBy: openBlockers,
+			}
+		}
+	}
+
+	return nil
+}
+
+// publishDependencyChange reloads the blocked task and tells both boards about the change
+func (ts *TaskService) publishDependencyChange(taskID, blockedByTaskID, userID int) (*repository.Task, error) {
+	task, err := ts.taskRepository.FindByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ts.taskRepository.LoadDependencies(task); err != nil {
+		return nil, err
+	}
+
+	ts.publish(events.TaskUpdated, taskID, userID, task)
+	if blocker, err := ts.taskRepository.FindByID(blockedByTaskID); err == nil {
+		if err := ts.taskRepository.LoadDependencies(blocker); err == nil {
+			ts.publish(events.TaskUpdated, blockedByTaskID, userID, blocker)
+		}
+	}
+
+	return task, nil
+}
+
+// CreateChecklist adds a checklist item to a task
+func (ts *TaskService) CreateChecklist(title string, taskID, userID int) (*repository.Checklist, error) {
+	checklist, err := ts.checklistRepository.Create(title, taskID, userID)
+	if err != nil {
+		return nil, err
 	}
 
+	ts.publish(events.ChecklistCreated, taskID, userID, checklist)
+
+	return checklist, nil
+}
+
+// UpdateChecklist renames a checklist item
+func (ts *TaskService) UpdateChecklist(checklistID int, title string, userID int) (*repository.Checklist, error) {
+	checklist, err := ts.checklistRepository.Update(checklistID, title)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.publish(events.ChecklistUpdated, checklist.TaskID, userID, checklist)
+
+	return checklist, nil
+}
+
+// ToggleChecklist marks a checklist item as completed or open
+func (ts *TaskService) ToggleChecklist(checklistID, userID int, completed bool) (*repository.Checklist, error) {
+	checklist, err := ts.checklistRepository.ToggleComplete(checklistID, userID, completed)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.publish(events.ChecklistToggled, checklist.TaskID, userID, checklist)
+
+	return checklist, nil
+}
+
+// DeleteChecklist removes a checklist item from a task
+func (ts *TaskService) DeleteChecklist(checklistID, taskID, userID int) error {
+	err := ts.checklistRepository.Delete(checklistID)
+	if err != nil {
+		return err
+	}
+
+	ts.publish(events.ChecklistDeleted, taskID, userID, map[string]int{
+		"id": checklistID,
+	})
+
 	return nil
 }
 
+// Operations of a bulk task request
+const (
+	BulkMove        = "move"
+	BulkAssign      = "assign"
+	BulkPriority    = "priority"
+	BulkAddLabel    = "add_label"
+	BulkRemoveLabel = "remove_label"
+	BulkDelete      = "delete"
+)
+
+// BulkOperation is the change a bulk request makes to each of its tasks
+type BulkOperation struct {
+	Type             string
+	ColumnID         int    // move
+	AssignedTo       *int   // assign, nil unassigns
+	Priority         string // priority
+	LabelID          int    // add_label, remove_label
+	OverrideWipLimit bool   // move, root only
+}
+
+// BulkUpdate applies one operation to each task in a single transaction and returns the error of every
+// task that failed. Each task runs in its own savepoint, so a failing task is left untouched while the
+// others are still changed. Non-root users can only change the tasks they created
+func (ts *TaskService) BulkUpdate(taskIDs []int, operation BulkOperation, userID int, isRoot bool) (map[int]error, error) {
+	failures := make(map[int]error)
+
+	err := ts.db.Transaction(func(tx *database.Database) error {
+		for _, taskID := range taskIDs {
+			// A failed task drops its changes and queued events with its savepoint
+			err := tx.Transaction(func(savepoint *database.Database) error {
+				return NewTaskService(savepoint).applyBulkOperation(taskID, operation, userID, isRoot)
+			})
+			if err != nil {
+				failures[taskID] = err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return failures, nil
+}
+
+// applyBulkOperation changes one task of a bulk request through the regular task operations, so
+// activities, notifications and events are the same as for a single change
+func (ts *TaskService) applyBulkOperation(taskID int, operation BulkOperation, userID int, isRoot bool) error {
+	task, err := ts.taskRepository.FindByID(taskID)
+	if err != nil {
+		return err
+	}
+
+	if !isRoot && task.CreatedBy != userID {
+		return errors.New("you can only edit your own tasks")
+	}
+
+	switch operation.Type {
+	case BulkMove:
+		if task.ColumnID == operation.ColumnID {
+			return nil
+		}
+		return ts.MoveTask(taskID, operation.ColumnID, nil, userID, operation.OverrideWipLimit)
+
+	case BulkAssign:
+		if sameAssignee(task.AssignedTo, operation.AssignedTo) {
+			return nil
+		}
+		if _, err := ts.UpdateTask(taskID, userID, nil, nil, operation.AssignedTo, task.DueDate, nil, nil, false); err != nil {
+			return err
+		}
+
+		// UpdateTask only tracks new assignees, record the unassignment here
+		if operation.AssignedTo == nil {
+			unassigned := 0
+			ts.recordFieldChanges(taskID, userID, ts.trackFieldChanges(task, nil, nil, nil, &unassigned))
+		}
+		return nil
+
+	case BulkPriority:
+		if task.Priority != nil && *task.Priority == operation.Priority {
+			return nil
+		}
+		// Assignee and due date are written as given, pass the current ones along
+		_, err := ts.UpdateTask(taskID, userID, nil, nil, task.AssignedTo, task.DueDate, &operation.Priority, nil, false)
+		return err
+
+	case BulkAddLabel, BulkRemoveLabel:
+		labels, err := ts.labelRepository.GetByTask(taskID)
+		if err != nil {
+			return err
+		}
+
+		labelIDs := make([]int, 0, len(labels)+1)
+		hasLabel := false
+		for _, label := range labels {
+			if label.ID == operation.LabelID {
+				hasLabel = true
+				if operation.Type == BulkRemoveLabel {
+					continue
+				}
+			}
+			labelIDs = append(labelIDs, label.ID)
+		}
+
+		if hasLabel == (operation.Type == BulkAddLabel) {
+			return nil
+		}
+		if operation.Type == BulkAddLabel {
+			labelIDs = append(labelIDs, operation.LabelID)
+		}
+
+		_, err = ts.SetTaskLabels(taskID, labelIDs, userID)
+		return err
+
+	case BulkDelete:
+		if !isRoot {
+			return errors.New("only root users can delete tasks")
+		}
+		return ts.DeleteTask(taskID, userID)
+	}
+
+	return fmt.Errorf("unknown bulk operation %q", operation.Type)
+}
+
+// sameAssignee reports whether two optional assignees are the same user, or both unassigned
+func sameAssignee(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// publish pushes a task-related event to the task's board
+func (ts *TaskService) publish(eventType string, taskID, userID int, data interface{}) {
+	event := events.Event{
+		Type:    eventType,
+		BoardID: ts.boardIDOf(taskID),
+		TaskID:  &taskID,
+		UserID:  userID,
+		Data:    data,
+	}
+
+	ts.db.AfterCommit(func() {
+		events.Publish(event)
+	})
+}
+
 // trackFieldChanges compares existing task with updates and returns changes
 func (ts *TaskService) trackFieldChanges(existing *repository.Task, title, description, priority *string, assignedTo *int) []fieldChange {
 	var changes []fieldChange
@@ -147,7 +1080,7 @@ func (ts *TaskService) trackFieldChanges(existing *repository.Task, title, descr
 	}
 
 	// Track description changes
-	if description != nil && *description != *existing.Description {
+	if description != nil && (existing.Description == nil || *description != *existing.Description) {
 		var oldDesc string
 		if existing.Description == nil || *existing.Description == "" {
 			oldDesc = "(empty)"
@@ -162,10 +1095,14 @@ func (ts *TaskService) trackFieldChanges(existing *repository.Task, title, descr
 	}
 
 	// Track priority changes
-	if priority != nil && *priority != *existing.Priority {
+	if priority != nil && (existing.Priority == nil || *priority != *existing.Priority) {
+		oldPriority := ""
+		if existing.Priority != nil {
+			oldPriority = *existing.Priority
+		}
 		changes = append(changes, fieldChange{
 			field:    "priority",
-			oldValue: *existing.Priority,
+			oldValue: oldPriority,
 			newValue: *priority,
 		})
 	}
@@ -181,21 +1118,20 @@ func (ts *TaskService) trackFieldChanges(existing *repository.Task, title, descr
 			newAssignee := "Unassigned"
 
 			if oldAssigneeID > 0 {
-				if user, err := ts.userRepository.FindByID(oldAssigneeID); err == nil {
-					oldAssignee = *user.Name
-				}
+				oldAssignee = ts.notificationService.displayName(oldAssigneeID)
 			}
 
 			if *assignedTo > 0 {
-				if user, err := ts.userRepository.FindByID(*assignedTo); err == nil {
-					newAssignee = *user.Name
-				}
+				newAssignee = ts.notificationService.displayName(*assignedTo)
 			}
 
+			newAssigneeID := *assignedTo
 			changes = append(changes, fieldChange{
 				field:    "assignee",
 				oldValue: oldAssignee,
 				newValue: newAssignee,
+				oldRef:   &oldAssigneeID,
+				newRef:   &newAssigneeID,
 			})
 		}
 	}
@@ -203,9 +1139,76 @@ func (ts *TaskService) trackFieldChanges(existing *repository.Task, title, descr
 	return changes
 }
 
+// recordFieldChanges records an update activity for each changed field
+func (ts *TaskService) recordFieldChanges(taskID, userID int, changes []fieldChange) {
+	for _, change := range changes {
+		err := ts.activityRepository.RecordTaskRefUpdate(taskID, userID, change.field, change.oldValue, change.newValue,
+			change.oldRef, change.newRef)
+		if err != nil {
+			fmt.Printf("Failed to record task update activity for %s: %v\n", change.field, err)
+		}
+	}
+}
+
+// labelNames joins label names for the activity log
+func labelNames(labels []*repository.Label) string {
+	if len(labels) == 0 {
+		return "(none)"
+	}
+
+	names := make([]string, len(labels))
+	for i, label := range labels {
+		names[i] = label.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// renderTemplateTitle fills {date}, {n} and the given custom placeholders of a template title
+func renderTemplateTitle(pattern string, n int, values map[string]string) string {
+	replacements := []string{
+		"{date}", time.Now().Format("2006-01-02"),
+		"{n}", strconv.Itoa(n),
+	}
+	for key, value := range values {
+		replacements = append(replacements, "{"+key+"}", value)
+	}
+
+	return strings.TrimSpace(strings.NewReplacer(replacements...).Replace(pattern))
+}
+
+// taskName describes a task in activities, e.g. "#12 Write docs"
+func taskName(task *repository.Task) string {
+	return fmt.Sprintf("#%d %s", task.ID, task.Title)
+}
+
+// parentName describes a task's parent in activities, "(none)" for top level tasks
+func (ts *TaskService) parentName(parentID *int) string {
+	if parentID == nil {
+		return "(none)"
+	}
+
+	parent, err := ts.taskRepository.FindByID(*parentID)
+	if err != nil {
+		return fmt.Sprintf("#%d", *parentID)
+	}
+	return taskName(parent)
+}
+
 // fieldChange represents a field change for activity tracking
 type fieldChange struct {
 	field    string
 	oldValue string
 	newValue string
+	oldRef   *int // IDs behind the values of the assignee, 0 for unassigned
+	newRef   *int
+}
+
+// boardIDOf returns the task's board, or nil to reach every board when it can't be resolved
+func (ts *TaskService) boardIDOf(taskID int) *int {
+	boardID, err := ts.taskRepository.GetBoardID(taskID)
+	if err != nil {
+		fmt.Printf("Failed to resolve board for task %d: %v\n", taskID, err)
+		return nil
+	}
+	return &boardID
 }
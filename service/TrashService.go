@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/repository"
+)
+
+// TrashService empties the task trash of everything older than the retention set in the app settings
+type TrashService struct {
+	settingsRepository *repository.SettingsRepository
+	taskService        *TaskService
+}
+
+func NewTrashService(db *database.Database) *TrashService {
+	return &TrashService{
+		settingsRepository: repository.NewSettingsRepository(db),
+		taskService:        NewTaskService(db),
+	}
+}
+
+// Start purges expired tasks in the background, the first run covers the time the app was closed
+func (ts *TrashService) Start(ctx context.Context, every time.Duration) {
+	runEvery(ctx, every, ts.PurgeExpired)
+}
+
+// PurgeExpired permanently deletes the tasks trashed longer ago than the retention, a retention of 0 keeps them
+func (ts *TrashService) PurgeExpired() {
+	settings, err := ts.settingsRepository.GetSettings()
+	if err != nil {
+		fmt.Printf("Failed to load trash retention: %v\n", err)
+		return
+	}
+
+	if settings.TrashRetentionDays <= 0 {
+		return
+	}
+
+	purged, err := ts.taskService.PurgeTrash(settings.TrashRetentionDays)
+	if err != nil {
+		fmt.Printf("Failed to purge expired tasks from the trash: %v\n", err)
+		return
+	}
+
+	if purged > 0 {
+		fmt.Printf("Purged %d expired tasks from the trash\n", purged)
+	}
+}
@@ -0,0 +1,471 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dev-parvej/offline_kanban/pkg/database"
+	"github.com/dev-parvej/offline_kanban/pkg/dto"
+	"github.com/dev-parvej/offline_kanban/repository"
+)
+
+// TrelloBoard is the part of a Trello board JSON export the import reads
+type TrelloBoard struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Desc       string            `json:"desc"`
+	Lists      []TrelloList      `json:"lists"`
+	Cards      []TrelloCard      `json:"cards"`
+	Checklists []TrelloChecklist `json:"checklists"`
+	Actions    []TrelloAction    `json:"actions"`
+	Members    []TrelloMember    `json:"members"`
+	Labels     []TrelloLabel     `json:"labels"`
+}
+
+type TrelloList struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Closed bool    `json:"closed"`
+	Pos    float64 `json:"pos"`
+}
+
+type TrelloCard struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Desc        string        `json:"desc"`
+	IDList      string        `json:"idList"`
+	Closed      bool          `json:"closed"`
+	Pos         float64       `json:"pos"`
+	Due         *string       `json:"due"`
+	IDMembers   []string      `json:"idMembers"`
+	IDLabels    []string      `json:"idLabels"`
+	Attachments []interface{} `json:"attachments"`
+}
+
+type TrelloChecklist struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	IDCard     string            `json:"idCard"`
+	Pos        float64           `json:"pos"`
+	CheckItems []TrelloCheckItem `json:"checkItems"`
+}
+
+type TrelloCheckItem struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	State string  `json:"state"` // complete or incomplete
+	Pos   float64 `json:"pos"`
+}
+
+type TrelloAction struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	Date            string `json:"date"`
+	IDMemberCreator string `json:"idMemberCreator"`
+	Data            struct {
+		Text string `json:"text"`
+		Card struct {
+			ID string `json:"id"`
+		} `json:"card"`
+	} `json:"data"`
+	MemberCreator *TrelloMember `json:"memberCreator"`
+}
+
+type TrelloMember struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	FullName string `json:"fullName"`
+}
+
+type TrelloLabel struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+var ErrNotTrelloExport = errors.New("not a Trello board export, the board name or lists are missing")
+
+// TrelloSkipped is something of the export that wasn't imported, or not as it was
+type TrelloSkipped struct {
+	Type     string `json:"type"` // list, card, member, label, attachment, checklist, comment
+	TrelloID string `json:"trello_id"`
+	Name     string `json:"name,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+type TrelloImportResult struct {
+	BoardID        int             `json:"board_id"`
+	BoardTitle     string          `json:"board_title"`
+	Columns        int             `json:"columns"`
+	Tasks          int             `json:"tasks"`
+	ArchivedTasks  int             `json:"archived_tasks"`
+	ChecklistItems int             `json:"checklist_items"`
+	Comments       int             `json:"comments"`
+	MatchedMembers map[string]int  `json:"matched_members"` // Trello username to user ID
+	Skipped        []TrelloSkipped `json:"skipped"`
+}
+
+// TrelloImportService creates a board from a Trello board export
+type TrelloImportService struct {
+	db *database.Database
+}
+
+func NewTrelloImportService(db *database.Database) *TrelloImportService {
+	return &TrelloImportService{
+		db: db,
+	}
+}
+
+// Import creates a new board in one transaction: columns from the open lists in list order, tasks from
+// their cards, with archived cards as archived tasks, checklist items from the card checklists and
+// comments from the comment actions. Members are matched to active users by username, ignoring case
+func (ts *TrelloImportService) Import(board *TrelloBoard, userID int) (*TrelloImportResult, error) {
+	if strings.TrimSpace(board.Name) == "" || board.Lists == nil {
+		return nil, ErrNotTrelloExport
+	}
+
+	result := &TrelloImportResult{
+		MatchedMembers: make(map[string]int),
+		Skipped:        make([]TrelloSkipped, 0),
+	}
+
+	err := ts.db.Transaction(func(tx *database.Database) error {
+		importer := &trelloImport{
+			board:               board,
+			result:              result,
+			userID:              userID,
+			boardRepository:     repository.NewBoardRepository(tx),
+			columnRepository:    repository.NewColumnRepository(tx),
+			checklistRepository: repository.NewChecklistRepository(tx),
+			snapshotRepository:  repository.NewSnapshotRepository(tx),
+			userRepository:      repository.NewUserRepository(tx),
+			taskService:         NewTaskService(tx),
+			members:             make(map[string]int),
+			columns:             make(map[string]int),
+			tasks:               make(map[string]int),
+		}
+		return importer.run()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// trelloImport holds the state of one import, the maps go from Trello IDs to IDs here
+type trelloImport struct {
+	board  *TrelloBoard
+	result *TrelloImportResult
+	userID int
+
+	boardRepository     *repository.BoardRepository
+	columnRepository    *repository.ColumnRepository
+	checklistRepository *repository.ChecklistRepository
+	snapshotRepository  *repository.SnapshotRepository
+	userRepository      *repository.UserRepository
+	taskService         *TaskService
+
+	members map[string]int
+	columns map[string]int
+	tasks   map[string]int
+}
+
+func (ti *trelloImport) run() error {
+	steps := []func() error{
+		ti.matchMembers, ti.createBoard, ti.createColumns, ti.createTasks, ti.createChecklists, ti.createComments,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+
+	// Labels have no counterpart in the import
+	for _, label := range ti.board.Labels {
+		ti.skip("label", label.ID, label.Name, "labels are not imported")
+	}
+
+	return nil
+}
+
+func (ti *trelloImport) skip(kind, trelloID, name, reason string) {
+	ti.result.Skipped = append(ti.result.Skipped, TrelloSkipped{Type: kind, TrelloID: trelloID, Name: name, Reason: reason})
+}
+
+func (ti *trelloImport) matchMembers() error {
+	users, err := ti.userRepository.GetAllUsers()
+	if err != nil {
+		return err
+	}
+
+	byUsername := make(map[string]int)
+	for _, user := range users {
+		if user.IsActive {
+			byUsername[strings.ToLower(user.UserName)] = user.ID
+		}
+	}
+
+	for _, member := range ti.board.Members {
+		if id, ok := byUsername[strings.ToLower(member.Username)]; ok {
+			ti.members[member.ID] = id
+			ti.result.MatchedMembers[member.Username] = id
+			continue
+		}
+		ti.skip("member", member.ID, member.Username, "no active user with this username")
+	}
+
+	return nil
+}
+
+func (ti *trelloImport) createBoard() error {
+	title, err := ti.uniqueBoardTitle(truncate(strings.TrimSpace(ti.board.Name), 100))
+	if err != nil {
+		return err
+	}
+
+	var description *string
+	if ti.board.Desc != "" {
+		desc := truncate(ti.board.Desc, 500)
+		description = &desc
+	}
+
+	board, err := ti.boardRepository.Create(title, description, ti.userID)
+	if err != nil {
+		return err
+	}
+
+	ti.result.BoardID = board.ID
+	ti.result.BoardTitle = board.Title
+	return nil
+}
+
+// uniqueBoardTitle numbers the title when a board already has it
+func (ti *trelloImport) uniqueBoardTitle(title string) (string, error) {
+	candidate := title
+	for n := 2; ; n++ {
+		exists, err := ti.boardRepository.TitleExists(candidate, nil)
+		if err != nil || !exists {
+			return candidate, err
+		}
+		candidate = fmt.Sprintf("%s (%d)", title, n)
+	}
+}
+
+func (ti *trelloImport) createColumns() error {
+	lists := append([]TrelloList(nil), ti.board.Lists...)
+	sort.SliceStable(lists, func(i, j int) bool { return lists[i].Pos < lists[j].Pos })
+
+	var orders []dto.ColumnsOrder
+	for _, list := range lists {
+		if list.Closed {
+			ti.skip("list", list.ID, list.Name, "archived list, its cards are skipped too")
+			continue
+		}
+
+		title, err := ti.uniqueColumnTitle(truncate(strings.TrimSpace(list.Name), 100))
+		if err != nil {
+			return err
+		}
+
+		column, err := ti.columnRepository.Create(title, ti.result.BoardID, ti.userID, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create a column for list %q: %w", list.Name, err)
+		}
+
+		ti.columns[list.ID] = column.ID
+		orders = append(orders, dto.ColumnsOrder{ID: column.ID, Position: len(orders) + 1})
+		ti.result.Columns++
+	}
+
+	if len(orders) == 0 {
+		return nil
+	}
+	return ti.columnRepository.Reorder(ti.result.BoardID, orders)
+}
+
+// uniqueColumnTitle numbers the titles of lists that share a name, column titles are unique on a board
+func (ti *trelloImport) uniqueColumnTitle(title string) (string, error) {
+	if title == "" {
+		title = "Untitled"
+	}
+
+	candidate := title
+	for n := 2; ; n++ {
+		exists, err := ti.columnRepository.TitleExists(ti.result.BoardID, candidate, nil)
+		if err != nil || !exists {
+			return candidate, err
+		}
+		candidate = fmt.Sprintf("%s (%d)", title, n)
+	}
+}
+
+// createTasks adds the cards of each list in card order, so the task positions follow the cards
+func (ti *trelloImport) createTasks() error {
+	cards := append([]TrelloCard(nil), ti.board.Cards...)
+	sort.SliceStable(cards, func(i, j int) bool { return cards[i].Pos < cards[j].Pos })
+
+	for _, card := range cards {
+		columnID, ok := ti.columns[card.IDList]
+		if !ok {
+			ti.skip("card", card.ID, card.Name, "its list is archived or missing")
+			continue
+		}
+
+		if err := ti.createTask(card, columnID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ti *trelloImport) createTask(card TrelloCard, columnID int) error {
+	title := strings.TrimSpace(card.Name)
+	if utf8.RuneCountInString(title) > 255 {
+		title = truncate(title, 255)
+		ti.skip("card", card.ID, title, "title cut to 255 characters")
+	}
+	if title == "" {
+		title = "Untitled"
+	}
+
+	var dueDate *time.Time
+	if card.Due != nil && *card.Due != "" {
+		parsed, err := time.Parse(time.RFC3339, *card.Due)
+		if err != nil {
+			ti.skip("card", card.ID, card.Name, "unreadable due date "+*card.Due)
+		} else {
+			dueDate = &parsed
+		}
+	}
+
+	// Tasks have one assignee, the first member of the card that matches a user
+	var assignedTo *int
+	for _, memberID := range card.IDMembers {
+		id, ok := ti.members[memberID]
+		if !ok {
+			continue
+		}
+		if assignedTo == nil {
+			assignedTo = &id
+			continue
+		}
+		ti.skip("member", memberID, card.Name, "card has more than one member, only the first is assigned")
+	}
+
+	task, err := ti.taskService.CreateTask(title, card.Desc, columnID, ti.userID, assignedTo, dueDate, "medium", true)
+	if err != nil {
+		return fmt.Errorf("failed to create a task for card %q: %w", card.Name, err)
+	}
+	ti.tasks[card.ID] = task.ID
+	ti.result.Tasks++
+
+	if card.Closed {
+		if _, err := ti.taskService.ArchiveTask(task.ID, ti.userID); err != nil {
+			return fmt.Errorf("failed to archive the task of card %q: %w", card.Name, err)
+		}
+		ti.result.ArchivedTasks++
+	}
+
+	if len(card.Attachments) > 0 {
+		ti.skip("attachment", card.ID, card.Name, fmt.Sprintf("%d attachments are not imported", len(card.Attachments)))
+	}
+
+	return nil
+}
+
+// createChecklists adds the items of every checklist of a card, prefixed with the checklist name
+// when the card has more than one
+func (ti *trelloImport) createChecklists() error {
+	checklists := append([]TrelloChecklist(nil), ti.board.Checklists...)
+	sort.SliceStable(checklists, func(i, j int) bool { return checklists[i].Pos < checklists[j].Pos })
+
+	perCard := make(map[string]int)
+	for _, checklist := range checklists {
+		perCard[checklist.IDCard]++
+	}
+
+	for _, checklist := range checklists {
+		taskID, ok := ti.tasks[checklist.IDCard]
+		if !ok {
+			ti.skip("checklist", checklist.ID, checklist.Name, "its card wasn't imported")
+			continue
+		}
+
+		items := append([]TrelloCheckItem(nil), checklist.CheckItems...)
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+
+		for _, item := range items {
+			title := item.Name
+			if perCard[checklist.IDCard] > 1 {
+				title = checklist.Name + ": " + title
+			}
+
+			created, err := ti.checklistRepository.Create(truncate(title, 255), taskID, ti.userID)
+			if err != nil {
+				return fmt.Errorf("failed to create checklist item %q: %w", item.Name, err)
+			}
+
+			if item.State == "complete" {
+				if _, err := ti.checklistRepository.ToggleComplete(created.ID, ti.userID, true); err != nil {
+					return err
+				}
+			}
+			ti.result.ChecklistItems++
+		}
+	}
+
+	return nil
+}
+
+// createComments adds the comment actions with their original dates, by the importing user when
+// the author isn't matched
+func (ti *trelloImport) createComments() error {
+	for _, action := range ti.board.Actions {
+		if action.Type != "commentCard" {
+			continue
+		}
+
+		taskID, ok := ti.tasks[action.Data.Card.ID]
+		if !ok {
+			ti.skip("comment", action.ID, "", "its card wasn't imported")
+			continue
+		}
+
+		createdBy, ok := ti.members[action.IDMemberCreator]
+		if !ok {
+			createdBy = ti.userID
+			author := action.IDMemberCreator
+			if action.MemberCreator != nil {
+				author = action.MemberCreator.Username
+			}
+			ti.skip("comment", action.ID, author, "author isn't a user here, imported as written by you")
+		}
+
+		createdAt, err := time.Parse(time.RFC3339, action.Date)
+		if err != nil {
+			createdAt = time.Now().UTC()
+		}
+
+		comment := &repository.SnapshotComment{Content: action.Data.Text, CreatedAt: createdAt, UpdatedAt: createdAt}
+		if _, err := ti.snapshotRepository.InsertComment(comment, taskID, createdBy, false); err != nil {
+			return fmt.Errorf("failed to create comment %s: %w", action.ID, err)
+		}
+		ti.result.Comments++
+	}
+
+	return nil
+}
+
+// truncate cuts s to at most n characters
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}